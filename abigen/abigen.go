@@ -0,0 +1,474 @@
+// Package abigen generates Go code from a contract ABI. For each struct in the ABI a typed struct is created, each
+// action gets a New<Action> constructor returning a *fio.Action, and each table gets a typed row query helper.
+//
+// Optional fields (type?) become pointers, arrays (type[]) become slices, binary extensions (type$) are tagged so
+// they are only serialized when present, and variants become sum-type interfaces wrapped in a <Name>Variant struct
+// which handles the JSON and binary serialization.
+//
+// It is normally used via the fio-abigen command and a go:generate comment, for example:
+//
+//	//go:generate go run github.com/fioprotocol/fio-go/cmd/fio-abigen -u https://testnet.fioprotocol.io -a fio.address -p address -o address_gen.go
+package abigen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+
+	"github.com/fioprotocol/fio-go/eos"
+)
+
+// Options controls the generated code
+type Options struct {
+	// Package is the name of the generated package
+	Package string
+	// Contract is the account the contract is deployed to, ie "fio.address"
+	Contract eos.AccountName
+}
+
+// builtIns maps the ABI built-in types to the Go types the eos package knows how to serialize.
+var builtIns = map[string]string{
+	"bool":                 "bool",
+	"int8":                 "int8",
+	"uint8":                "uint8",
+	"int16":                "int16",
+	"uint16":               "uint16",
+	"int32":                "int32",
+	"uint32":               "uint32",
+	"int64":                "eos.Int64",
+	"uint64":               "eos.Uint64",
+	"int128":               "eos.Int128",
+	"uint128":              "eos.Uint128",
+	"varint32":             "eos.Varint32",
+	"varuint32":            "eos.Varuint32",
+	"float32":              "float32",
+	"float64":              "float64",
+	"float128":             "eos.Float128",
+	"time_point":           "eos.TimePoint",
+	"time_point_sec":       "eos.TimePointSec",
+	"block_timestamp_type": "eos.BlockTimestamp",
+	"name":                 "eos.Name",
+	"bytes":                "eos.HexBytes",
+	"string":               "string",
+	"checksum160":          "eos.Checksum160",
+	"checksum256":          "eos.Checksum256",
+	"checksum512":          "eos.Checksum512",
+	"public_key":           "ecc.PublicKey",
+	"signature":            "ecc.Signature",
+	"symbol":               "eos.Symbol",
+	"symbol_code":          "eos.SymbolCode",
+	"asset":                "eos.Asset",
+	"extended_asset":       "eos.ExtendedAsset",
+}
+
+// knownAliases are common type aliases that have a matching named type in the eos package
+var knownAliases = map[string]string{
+	"account_name":    "eos.AccountName",
+	"permission_name": "eos.PermissionName",
+	"action_name":     "eos.ActionName",
+	"table_name":      "eos.TableName",
+	"scope_name":      "eos.ScopeName",
+}
+
+type generator struct {
+	abi     *eos.ABI
+	opts    Options
+	buf     *bytes.Buffer
+	imports map[string]bool
+	// members tracks which variants a struct belongs to, so it can implement the variant interface
+	members map[string][]string
+}
+
+// Generate returns the gofmt'd source for the ABI
+func Generate(abi *eos.ABI, opts Options) ([]byte, error) {
+	if abi == nil {
+		return nil, errors.New("abi is nil")
+	}
+	if opts.Package == "" {
+		return nil, errors.New("package name is required")
+	}
+	if opts.Contract == "" {
+		return nil, errors.New("contract account is required")
+	}
+	g := &generator{
+		abi:     abi,
+		opts:    opts,
+		buf:     bytes.NewBuffer(nil),
+		imports: map[string]bool{"github.com/fioprotocol/fio-go/eos": true},
+		members: make(map[string][]string),
+	}
+	if err := g.generate(); err != nil {
+		return nil, err
+	}
+
+	out := bytes.NewBuffer(nil)
+	fmt.Fprintf(out, "// Code generated by fio-abigen from the %s ABI. DO NOT EDIT.\n\n", opts.Contract)
+	fmt.Fprintf(out, "package %s\n\n", opts.Package)
+	imports := make([]string, 0)
+	for i := range g.imports {
+		imports = append(imports, i)
+	}
+	sort.Strings(imports)
+	out.WriteString("import (\n")
+	for _, i := range imports {
+		if i == "github.com/fioprotocol/fio-go" {
+			fmt.Fprintf(out, "\tfio %q\n", i)
+			continue
+		}
+		fmt.Fprintf(out, "\t%q\n", i)
+	}
+	out.WriteString(")\n\n")
+	out.Write(g.buf.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %s", err)
+	}
+	return src, nil
+}
+
+func (g *generator) generate() error {
+	g.printf("// Contract is the account the %s contract is deployed to\n", g.opts.Contract)
+	g.printf("const Contract eos.AccountName = %q\n\n", g.opts.Contract)
+
+	for _, v := range g.abi.Variants {
+		for _, t := range v.Types {
+			if g.isStruct(t) {
+				g.members[g.resolve(t)] = append(g.members[g.resolve(t)], v.Name)
+			}
+		}
+	}
+	for _, s := range g.abi.Structs {
+		if err := g.genStruct(s); err != nil {
+			return err
+		}
+	}
+	for _, v := range g.abi.Variants {
+		if err := g.genVariant(v); err != nil {
+			return err
+		}
+	}
+	for _, a := range g.abi.Actions {
+		if err := g.genAction(a); err != nil {
+			return err
+		}
+	}
+	for _, t := range g.abi.Tables {
+		if err := g.genTable(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) printf(format string, a ...interface{}) {
+	fmt.Fprintf(g.buf, format, a...)
+}
+
+// GoName converts an ABI identifier to an exported Go identifier, ie: "fio_address" becomes "FioAddress"
+func GoName(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	name := ""
+	for _, p := range parts {
+		name += strings.ToUpper(p[:1]) + p[1:]
+	}
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "X" + name
+	}
+	return name
+}
+
+// resolve follows ABI type aliases, stopping at known eos named types
+func (g *generator) resolve(t string) string {
+	for i := 0; i < 32; i++ {
+		if _, ok := knownAliases[t]; ok {
+			return t
+		}
+		next, isAlias := g.abi.TypeNameForNewTypeName(t)
+		if !isAlias || next == t {
+			return t
+		}
+		t = next
+	}
+	return t
+}
+
+func (g *generator) isStruct(t string) bool {
+	return g.abi.StructForName(g.resolve(t)) != nil
+}
+
+// goType maps an ABI type (without the optional or binary extension suffix) to a Go type
+func (g *generator) goType(t string) (string, error) {
+	if strings.HasSuffix(t, "[]") {
+		inner, err := g.goType(t[:len(t)-2])
+		if err != nil {
+			return "", err
+		}
+		return "[]" + inner, nil
+	}
+	if strings.HasSuffix(t, "?") || strings.HasSuffix(t, "$") {
+		return "", fmt.Errorf("type modifier is not supported here: %s", t)
+	}
+	resolved := g.resolve(t)
+	if known, ok := knownAliases[resolved]; ok {
+		return known, nil
+	}
+	if builtIn, ok := builtIns[resolved]; ok {
+		if strings.HasPrefix(builtIn, "ecc.") {
+			g.imports["github.com/fioprotocol/fio-go/eos/ecc"] = true
+		}
+		return builtIn, nil
+	}
+	if g.abi.StructForName(resolved) != nil {
+		return GoName(resolved), nil
+	}
+	if g.abi.VariantForName(resolved) != nil {
+		return GoName(resolved) + "Variant", nil
+	}
+	return "", fmt.Errorf("unknown type %s", t)
+}
+
+func (g *generator) genStruct(s eos.StructDef) error {
+	name := GoName(s.Name)
+	g.printf("// %s is the %s struct from the %s ABI\n", name, s.Name, g.opts.Contract)
+	g.printf("type %s struct {\n", name)
+	if s.Base != "" {
+		base := g.resolve(s.Base)
+		if g.abi.StructForName(base) == nil {
+			return fmt.Errorf("struct %s: base %s not found", s.Name, s.Base)
+		}
+		g.printf("\t%s\n", GoName(base))
+	}
+	for _, f := range s.Fields {
+		t := f.Type
+		var tags []string
+		jsonTag := f.Name
+		if strings.HasSuffix(t, "$") {
+			t = t[:len(t)-1]
+			tags = append(tags, "binary_extension")
+			jsonTag += ",omitempty"
+		}
+		pointer := ""
+		if strings.HasSuffix(t, "?") {
+			t = t[:len(t)-1]
+			pointer = "*"
+			tags = append(tags, "optional")
+			if !strings.HasSuffix(jsonTag, ",omitempty") {
+				jsonTag += ",omitempty"
+			}
+		}
+		if len(tags) > 1 {
+			return fmt.Errorf("struct %s: field %s: optional binary extensions are not supported", s.Name, f.Name)
+		}
+		goType, err := g.goType(t)
+		if err != nil {
+			return fmt.Errorf("struct %s: field %s: %s", s.Name, f.Name, err)
+		}
+		tag := fmt.Sprintf(`json:"%s"`, jsonTag)
+		if len(tags) > 0 {
+			tag += fmt.Sprintf(` eos:"%s"`, tags[0])
+		}
+		g.printf("\t%s %s%s `%s`\n", GoName(f.Name), pointer, goType, tag)
+	}
+	g.printf("}\n\n")
+	for _, v := range g.members[s.Name] {
+		g.printf("func (%s) is%s() {}\n\n", name, GoName(v))
+	}
+	return nil
+}
+
+// variantMember is the Go type that satisfies a variant's interface for one of its types
+type variantMember struct {
+	abiType string
+	goType  string
+	wrapper string
+}
+
+func (g *generator) genVariant(v eos.VariantDef) error {
+	name := GoName(v.Name)
+	g.imports["encoding/json"] = true
+	g.imports["fmt"] = true
+
+	members := make([]variantMember, 0)
+	for _, t := range v.Types {
+		goType, err := g.goType(t)
+		if err != nil {
+			return fmt.Errorf("variant %s: %s", v.Name, err)
+		}
+		m := variantMember{abiType: t, goType: goType}
+		if !g.isStruct(t) || strings.HasSuffix(t, "[]") {
+			m.wrapper = name + GoName(strings.Replace(t, "[]", "_array", -1))
+		}
+		members = append(members, m)
+	}
+
+	g.printf("// %s is the %s variant, it holds one of: ", name, v.Name)
+	for i, m := range members {
+		if i > 0 {
+			g.printf(", ")
+		}
+		g.printf("%s", m.typeName())
+	}
+	g.printf("\ntype %s interface {\n\tis%s()\n}\n\n", name, name)
+
+	for _, m := range members {
+		if m.wrapper == "" {
+			continue
+		}
+		g.printf("// %s is the %s type of the %s variant\n", m.wrapper, m.abiType, v.Name)
+		g.printf("type %s %s\n\n", m.wrapper, m.goType)
+		g.printf("func (%s) is%s() {}\n\n", m.wrapper, name)
+	}
+
+	g.printf("// %sVariant holds a %s, and handles the JSON ([\"type\", value]) and binary serialization\n", name, name)
+	g.printf("type %sVariant struct {\n\tValue %s\n}\n\n", name, name)
+
+	g.printf("func (v %sVariant) MarshalBinary(encoder *eos.Encoder) error {\n", name)
+	g.printf("\tswitch val := v.Value.(type) {\n")
+	for i, m := range members {
+		g.printf("\tcase %s:\n", m.typeName())
+		g.printf("\t\tif err := encoder.Encode(eos.Varuint32(%d)); err != nil {\n\t\t\treturn err\n\t\t}\n", i)
+		g.printf("\t\treturn encoder.Encode(%s)\n", m.unwrap("val"))
+	}
+	g.printf("\t}\n\treturn fmt.Errorf(\"%s: unknown variant type %%T\", v.Value)\n}\n\n", v.Name)
+
+	g.printf("func (v *%sVariant) UnmarshalBinary(decoder *eos.Decoder) error {\n", name)
+	g.printf("\tidx, err := decoder.ReadUvarint32()\n\tif err != nil {\n\t\treturn err\n\t}\n")
+	g.printf("\tswitch idx {\n")
+	for i, m := range members {
+		g.printf("\tcase %d:\n", i)
+		g.printf("\t\tvar val %s\n", m.goType)
+		g.printf("\t\tif err = decoder.Decode(&val); err != nil {\n\t\t\treturn err\n\t\t}\n")
+		g.printf("\t\tv.Value = %s\n", m.wrap("val"))
+		g.printf("\t\treturn nil\n")
+	}
+	g.printf("\t}\n\treturn fmt.Errorf(\"%s: unknown variant index %%d\", idx)\n}\n\n", v.Name)
+
+	g.printf("func (v %sVariant) MarshalJSON() ([]byte, error) {\n", name)
+	g.printf("\tswitch val := v.Value.(type) {\n")
+	for _, m := range members {
+		g.printf("\tcase %s:\n", m.typeName())
+		g.printf("\t\treturn json.Marshal([]interface{}{%q, %s})\n", m.abiType, m.unwrap("val"))
+	}
+	g.printf("\t}\n\treturn nil, fmt.Errorf(\"%s: unknown variant type %%T\", v.Value)\n}\n\n", v.Name)
+
+	g.printf("func (v *%sVariant) UnmarshalJSON(data []byte) error {\n", name)
+	g.printf("\tpair := make([]json.RawMessage, 0)\n")
+	g.printf("\tif err := json.Unmarshal(data, &pair); err != nil {\n\t\treturn err\n\t}\n")
+	g.printf("\tif len(pair) != 2 {\n\t\treturn fmt.Errorf(\"%s: expected [type, value], got %%d elements\", len(pair))\n\t}\n", v.Name)
+	g.printf("\tvar typeName string\n")
+	g.printf("\tif err := json.Unmarshal(pair[0], &typeName); err != nil {\n\t\treturn err\n\t}\n")
+	g.printf("\tswitch typeName {\n")
+	for _, m := range members {
+		g.printf("\tcase %q:\n", m.abiType)
+		g.printf("\t\tvar val %s\n", m.goType)
+		g.printf("\t\tif err := json.Unmarshal(pair[1], &val); err != nil {\n\t\t\treturn err\n\t\t}\n")
+		g.printf("\t\tv.Value = %s\n", m.wrap("val"))
+		g.printf("\t\treturn nil\n")
+	}
+	g.printf("\t}\n\treturn fmt.Errorf(\"%s: unknown variant type %%s\", typeName)\n}\n\n", v.Name)
+	return nil
+}
+
+func (m variantMember) typeName() string {
+	if m.wrapper != "" {
+		return m.wrapper
+	}
+	return m.goType
+}
+
+func (m variantMember) wrap(v string) string {
+	if m.wrapper != "" {
+		return m.wrapper + "(" + v + ")"
+	}
+	return v
+}
+
+func (m variantMember) unwrap(v string) string {
+	if m.wrapper != "" {
+		return m.goType + "(" + v + ")"
+	}
+	return v
+}
+
+func (g *generator) genAction(a eos.ActionDef) error {
+	structName := g.resolve(a.Type)
+	s := g.abi.StructForName(structName)
+	if s == nil {
+		return fmt.Errorf("action %s: struct %s not found", a.Name, a.Type)
+	}
+	g.imports["github.com/fioprotocol/fio-go"] = true
+	name := GoName(string(a.Name))
+	typeName := GoName(structName)
+
+	g.printf("// New%s builds a %s::%s action", name, g.opts.Contract, a.Name)
+	defaults := g.actionDefaults(a.Name, s)
+	if len(defaults) > 0 {
+		fields := make([]string, 0)
+		for _, d := range defaults {
+			fields = append(fields, d.field)
+		}
+		g.printf(", empty %s fields are set to their defaults", strings.Join(fields, ", "))
+	}
+	g.printf("\nfunc New%s(actor eos.AccountName, data %s) *fio.Action {\n", name, typeName)
+	for _, d := range defaults {
+		g.printf("\tif data.%s == %s {\n\t\tdata.%s = %s\n\t}\n", GoName(d.field), d.zero, GoName(d.field), d.value)
+	}
+	g.printf("\treturn fio.NewAction(Contract, %q, actor, data)\n}\n\n", a.Name)
+	return nil
+}
+
+type actionDefault struct {
+	field string
+	zero  string
+	value string
+}
+
+// actionDefaults finds the fields most FIO actions share which have sensible defaults
+func (g *generator) actionDefaults(action eos.ActionName, s *eos.StructDef) []actionDefault {
+	defaults := make([]actionDefault, 0)
+	for _, f := range s.Fields {
+		goType, err := g.goType(f.Type)
+		if err != nil {
+			continue
+		}
+		switch {
+		case f.Name == "max_fee" && (goType == "eos.Uint64" || goType == "eos.Int64"):
+			defaults = append(defaults, actionDefault{
+				field: f.Name,
+				zero:  "0",
+				value: fmt.Sprintf("%s(fio.Tokens(fio.GetMaxFeeByAction(%q)))", goType, action),
+			})
+		case f.Name == "actor" && (goType == "eos.Name" || goType == "eos.AccountName"):
+			defaults = append(defaults, actionDefault{field: f.Name, zero: `""`, value: goType + "(actor)"})
+		case f.Name == "tpid" && goType == "string":
+			defaults = append(defaults, actionDefault{field: f.Name, zero: `""`, value: "fio.CurrentTpid()"})
+		}
+	}
+	return defaults
+}
+
+func (g *generator) genTable(t eos.TableDef) error {
+	rowType, err := g.goType(t.Type)
+	if err != nil {
+		return fmt.Errorf("table %s: %s", t.Name, err)
+	}
+	g.imports["encoding/json"] = true
+	g.imports["github.com/fioprotocol/fio-go"] = true
+	name := GoName(string(t.Name))
+
+	g.printf("// Get%sRows queries the %s table. The code, table and json fields of the request are set automatically,\n", name, t.Name)
+	g.printf("// and the scope defaults to the contract account if empty.\n")
+	g.printf("func Get%sRows(api *fio.API, req eos.GetTableRowsRequest) (rows []%s, more bool, err error) {\n", name, rowType)
+	g.printf("\treq.Code = string(Contract)\n\treq.Table = %q\n\treq.JSON = true\n", t.Name)
+	g.printf("\tif req.Scope == \"\" {\n\t\treq.Scope = string(Contract)\n\t}\n")
+	g.printf("\tresp, err := api.GetTableRows(req)\n\tif err != nil {\n\t\treturn nil, false, err\n\t}\n")
+	g.printf("\trows = make([]%s, 0)\n", rowType)
+	g.printf("\terr = json.Unmarshal(resp.Rows, &rows)\n\treturn rows, resp.More, err\n}\n\n")
+	return nil
+}
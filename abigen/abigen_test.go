@@ -0,0 +1,120 @@
+package abigen
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/fioprotocol/fio-go/eos"
+)
+
+const testAbi = `{
+	"version": "eosio::abi/1.1",
+	"types": [{"new_type_name": "fio_name", "type": "string"}, {"new_type_name": "account_name", "type": "name"}],
+	"structs": [
+		{"name": "base_row", "base": "", "fields": [{"name": "id", "type": "uint64"}]},
+		{"name": "domain", "base": "base_row", "fields": [
+			{"name": "name", "type": "fio_name"},
+			{"name": "account", "type": "account_name"},
+			{"name": "public_key", "type": "public_key"},
+			{"name": "expiration", "type": "uint32"},
+			{"name": "memo", "type": "string?"},
+			{"name": "hashes", "type": "checksum256[]"},
+			{"name": "extra", "type": "choice$"}
+		]},
+		{"name": "regdomain", "base": "", "fields": [
+			{"name": "fio_domain", "type": "string"},
+			{"name": "max_fee", "type": "int64"},
+			{"name": "actor", "type": "name"},
+			{"name": "tpid", "type": "string"}
+		]},
+		{"name": "transfer", "base": "", "fields": [{"name": "to", "type": "name"}]}
+	],
+	"actions": [
+		{"name": "regdomain", "type": "regdomain", "ricardian_contract": ""},
+		{"name": "xferdomain", "type": "transfer", "ricardian_contract": ""}
+	],
+	"tables": [{"name": "domains", "index_type": "i64", "key_names": [], "key_types": [], "type": "domain"}],
+	"variants": [{"name": "choice", "types": ["uint8", "transfer", "string[]"]}]
+}`
+
+func TestGenerate(t *testing.T) {
+	abi, err := eos.NewABI(strings.NewReader(testAbi))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	src, err := Generate(abi, Options{Package: "address", Contract: "fio.address"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// type check the generated code against the real fio and eos packages
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "gen.go", src, parser.AllErrors)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err = conf.Check("address", fset, []*ast.File{file}, nil); err != nil {
+		t.Error("generated code does not compile:", err)
+		return
+	}
+
+	code := string(src)
+	for _, expect := range []string{
+		"// Code generated by fio-abigen from the fio.address ABI. DO NOT EDIT.",
+		`fio "github.com/fioprotocol/fio-go"`,
+		`"github.com/fioprotocol/fio-go/eos/ecc"`,
+		"BaseRow\n",
+		"Name       string            `json:\"name\"`",
+		"Account    eos.AccountName",
+		"Memo       *string           `json:\"memo,omitempty\" eos:\"optional\"`",
+		"Hashes     []eos.Checksum256",
+		"Extra      ChoiceVariant     `json:\"extra,omitempty\" eos:\"binary_extension\"`",
+		"func NewRegdomain(actor eos.AccountName, data Regdomain) *fio.Action {",
+		`data.MaxFee = eos.Int64(fio.Tokens(fio.GetMaxFeeByAction("regdomain")))`,
+		"data.Actor = eos.Name(actor)",
+		"data.Tpid = fio.CurrentTpid()",
+		"func NewXferdomain(actor eos.AccountName, data Transfer) *fio.Action {",
+		"type Choice interface {",
+		"type ChoiceUint8 uint8",
+		"type ChoiceStringArray []string",
+		"func (Transfer) isChoice() {}",
+		"func GetDomainsRows(api *fio.API, req eos.GetTableRowsRequest) (rows []Domain, more bool, err error) {",
+	} {
+		if !strings.Contains(code, expect) {
+			t.Errorf("generated code did not contain %q", expect)
+		}
+	}
+	if !strings.Contains(code, "data Transfer) *fio.Action {\n\treturn fio.NewAction(Contract, \"xferdomain\", actor, data)") {
+		t.Error("defaults should only be set for fields that exist")
+	}
+
+	if _, err = Generate(abi, Options{Package: "address"}); err == nil {
+		t.Error("should require a contract")
+	}
+	abi.Structs[3].Fields[0].Type = "unknown_type"
+	if _, err = Generate(abi, Options{Package: "address", Contract: "fio.address"}); err == nil {
+		t.Error("should not generate with an unknown type")
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for in, out := range map[string]string{
+		"fio_address":   "FioAddress",
+		"regaddress":    "Regaddress",
+		"fio.address":   "FioAddress",
+		"1st":           "X1st",
+		"owner_account": "OwnerAccount",
+	} {
+		if GoName(in) != out {
+			t.Errorf("expected %s for %s, got %s", out, in, GoName(in))
+		}
+	}
+}
@@ -100,9 +100,9 @@ func NewAddAddress(actor eos.AccountName, fioAddress Address, token string, chai
 	}
 	// ensure both chain and token are not empty
 	if token != "" && chain == "" {
-		token = chain
-	} else if chain != "" && token == "" {
 		chain = token
+	} else if chain != "" && token == "" {
+		token = chain
 	} else if chain == "" && token == "" {
 		return nil, false
 	}
@@ -118,20 +118,16 @@ func NewAddAddress(actor eos.AccountName, fioAddress Address, token string, chai
 	), true
 }
 
-// NewAddAddresses adds multiple public addresses at a time
+// NewAddAddresses adds multiple public addresses at a time. The public addresses are not checked, use
+// NewAddAddressesChecked to refuse invalid mappings.
 func NewAddAddresses(actor eos.AccountName, fioAddress Address, addrs []TokenPubAddr) (action *Action, ok bool) {
 	if !fioAddress.Valid() {
 		return nil, false
 	}
 	// fixup struct so both chain code and token code exist
-	for _, a := range addrs {
-		if a.TokenCode != "" && a.ChainCode == "" {
-			a.TokenCode = a.ChainCode
-		} else if a.ChainCode != "" && a.TokenCode == "" {
-			a.ChainCode = a.TokenCode
-		} else if a.ChainCode == "" && a.TokenCode == "" {
-			return nil, false
-		}
+	addrs, ok = fixupTokenPubAddrs(addrs)
+	if !ok {
+		return nil, false
 	}
 	return NewAction(
 		"fio.address", "addaddress", actor,
@@ -145,6 +141,41 @@ func NewAddAddresses(actor eos.AccountName, fioAddress Address, addrs []TokenPub
 	), true
 }
 
+// NewAddAddressesChecked is the same as NewAddAddresses, but validates each mapping with the validator registered
+// for its chain code, see RegisterPubAddressValidator. If any are invalid the error is PubAddressErrors, which
+// lists every invalid mapping.
+func NewAddAddressesChecked(actor eos.AccountName, fioAddress Address, addrs []TokenPubAddr) (*Action, error) {
+	if !fioAddress.Valid() {
+		return nil, fmt.Errorf("invalid fio address %q", fioAddress)
+	}
+	fixed, ok := fixupTokenPubAddrs(addrs)
+	if !ok {
+		return nil, errors.New("each public address requires a chain code or a token code")
+	}
+	if err := ValidatePubAddresses(fixed); err != nil {
+		return nil, err
+	}
+	action, _ := NewAddAddresses(actor, fioAddress, fixed)
+	return action, nil
+}
+
+// fixupTokenPubAddrs returns a copy of addrs where both chain code and token code are set, using one for the other
+// if missing.
+func fixupTokenPubAddrs(addrs []TokenPubAddr) ([]TokenPubAddr, bool) {
+	fixed := make([]TokenPubAddr, len(addrs))
+	for i, a := range addrs {
+		if a.TokenCode != "" && a.ChainCode == "" {
+			a.ChainCode = a.TokenCode
+		} else if a.ChainCode != "" && a.TokenCode == "" {
+			a.TokenCode = a.ChainCode
+		} else if a.ChainCode == "" && a.TokenCode == "" {
+			return nil, false
+		}
+		fixed[i] = a
+	}
+	return fixed, true
+}
+
 // RegDomain registers a FIO Domain on the FIO blockchain
 type RegDomain struct {
 	FioDomain         string           `json:"fio_domain"`
@@ -320,7 +351,7 @@ type pubAddressRequest struct {
 }
 
 // PubAddressLookup finds a public address for a user, given a currency key
-//  pubAddress, ok, err := api.PubAddressLookup(fio.Address("alice:fio", "BTC")
+//  pubAddress, ok, err := api.PubAddressLookup(fio.Address("alice@fio"), "BTC", "BTC")
 func (api API) PubAddressLookup(fioAddress Address, chain string, token string) (address PubAddress, found bool, err error) {
 	if token == "" {
 		token = chain
@@ -547,8 +578,8 @@ type DomainResp struct {
 	Account    *eos.AccountName `json:"account,omitempty"`
 }
 
-// GetDomainOwner finds the account that is the owner of a domain
-func (api *API) GetDomainOwner(domain string) (actor *eos.AccountName, err error) {
+// GetDomain reads a domain from the fio.address domains table
+func (api *API) GetDomain(domain string) (*DomainResp, error) {
 	dnh := DomainNameHash(domain)
 	resp, err := api.GetTableRows(eos.GetTableRowsRequest{
 		Code:       "fio.address",
@@ -575,7 +606,16 @@ func (api *API) GetDomainOwner(domain string) (actor *eos.AccountName, err error
 	if len(d) == 0 {
 		return nil, errors.New("not found")
 	}
-	return d[0].Account, nil
+	return &d[0], nil
+}
+
+// GetDomainOwner finds the account that is the owner of a domain
+func (api *API) GetDomainOwner(domain string) (actor *eos.AccountName, err error) {
+	d, err := api.GetDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	return d.Account, nil
 }
 
 type AvailCheckReq struct {
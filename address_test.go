@@ -66,6 +66,37 @@ func TestAddress_Valid(t *testing.T) {
 	}
 }
 
+func TestNewAddAddress_Codes(t *testing.T) {
+	for _, test := range []struct {
+		token string
+		chain string
+	}{
+		{"BTC", ""},
+		{"", "BTC"},
+	} {
+		act, ok := NewAddAddress("aloha", "alice@fio", test.token, test.chain, "1BoatSLRHtKNngkdXEeobR76b53LETtpyT")
+		if !ok {
+			t.Errorf("token %q chain %q should be valid", test.token, test.chain)
+			continue
+		}
+		if a := act.Data.(AddAddress).PublicAddresses[0]; a.TokenCode != "BTC" || a.ChainCode != "BTC" {
+			t.Errorf("token %q chain %q: both codes should be set, got %+v", test.token, test.chain, a)
+		}
+
+		act, ok = NewAddAddresses("aloha", "alice@fio", []TokenPubAddr{{TokenCode: test.token, ChainCode: test.chain, PublicAddress: "1BoatSLRHtKNngkdXEeobR76b53LETtpyT"}})
+		if !ok {
+			t.Errorf("token %q chain %q should be valid", test.token, test.chain)
+			continue
+		}
+		if a := act.Data.(AddAddress).PublicAddresses[0]; a.TokenCode != "BTC" || a.ChainCode != "BTC" {
+			t.Errorf("token %q chain %q: both codes should be set, got %+v", test.token, test.chain, a)
+		}
+	}
+	if _, ok := NewAddAddress("aloha", "alice@fio", "", "", "1BoatSLRHtKNngkdXEeobR76b53LETtpyT"); ok {
+		t.Error("a chain or token code is required")
+	}
+}
+
 func printResult(from string, result *FioNames) string {
 	if result == nil {
 		return "\n" + from + " returned a nil response"
@@ -0,0 +1,171 @@
+package fio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SufPerFio is the number of SUFs (the smallest unit of FIO) in one FIO token
+const SufPerFio = 1_000_000_000
+
+const fioPrecision = 9
+
+// FioAssetSymbol is the symbol used by fio.token for assets
+var FioAssetSymbol = eos.Symbol{Precision: fioPrecision, Symbol: "FIO"}
+
+var (
+	ErrAmountOverflow  = errors.New("fio amount overflow")
+	ErrAmountUnderflow = errors.New("fio amount cannot be negative")
+)
+
+// Amount is an exact quantity of FIO, stored as SUFs. Unlike Tokens() it does not use floating point, so it is
+// safe for accounting. It is encoded the same as an eos.Asset, "1.000000000 FIO" in JSON, and also accepts a
+// plain number of SUFs when decoding JSON, since that is how most FIO endpoints return amounts.
+type Amount uint64
+
+// ParseAmount reads an amount of FIO such as "1.5", "1.500000000 FIO" or "1000 FIO", with at most 9 decimals
+func ParseAmount(s string) (Amount, error) {
+	fields := strings.Fields(s)
+	switch {
+	case len(fields) == 2 && fields[1] == FioAssetSymbol.Symbol:
+	case len(fields) != 1:
+		return 0, fmt.Errorf("invalid fio amount %q", s)
+	}
+	integral, decimal := fields[0], ""
+	if i := strings.IndexByte(integral, '.'); i >= 0 {
+		integral, decimal = integral[:i], integral[i+1:]
+	}
+	if len(decimal) > fioPrecision {
+		return 0, fmt.Errorf("fio amount %q has more than %d decimals", s, fioPrecision)
+	}
+	if integral == "" || strings.Trim(integral+decimal, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid fio amount %q", s)
+	}
+	whole, err := strconv.ParseUint(integral, 10, 64)
+	if err != nil {
+		return 0, ErrAmountOverflow
+	}
+	var frac uint64
+	if decimal != "" {
+		frac, _ = strconv.ParseUint(decimal+strings.Repeat("0", fioPrecision-len(decimal)), 10, 64)
+	}
+	amount, err := Amount(whole).Mul(SufPerFio)
+	if err != nil {
+		return 0, err
+	}
+	return amount.Add(Amount(frac))
+}
+
+// AmountFromAsset converts a FIO eos.Asset, the asset must be positive and have the FIO symbol
+func AmountFromAsset(asset eos.Asset) (Amount, error) {
+	if asset.Symbol.Symbol != FioAssetSymbol.Symbol || asset.Symbol.Precision != fioPrecision {
+		return 0, fmt.Errorf("%s is not a fio asset", asset.Symbol.String())
+	}
+	if asset.Amount < 0 {
+		return 0, ErrAmountUnderflow
+	}
+	return Amount(asset.Amount), nil
+}
+
+// Suf is the amount as SUFs, as used for the amount and max_fee fields in actions
+func (a Amount) Suf() uint64 {
+	return uint64(a)
+}
+
+// String formats the amount with all 9 decimals and the symbol, for example "1.234567890 FIO"
+func (a Amount) String() string {
+	return fmt.Sprintf("%d.%09d %s", uint64(a)/SufPerFio, uint64(a)%SufPerFio, FioAssetSymbol.Symbol)
+}
+
+// Asset converts to an eos.Asset, which is signed so cannot hold amounts above math.MaxInt64
+func (a Amount) Asset() (eos.Asset, error) {
+	if a > math.MaxInt64 {
+		return eos.Asset{}, ErrAmountOverflow
+	}
+	return eos.Asset{Amount: eos.Int64(a), Symbol: FioAssetSymbol}, nil
+}
+
+// Add returns a + b, or ErrAmountOverflow
+func (a Amount) Add(b Amount) (Amount, error) {
+	if a > math.MaxUint64-b {
+		return 0, ErrAmountOverflow
+	}
+	return a + b, nil
+}
+
+// Sub returns a - b, or ErrAmountUnderflow if b is larger
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if b > a {
+		return 0, ErrAmountUnderflow
+	}
+	return a - b, nil
+}
+
+// Mul returns a * n, or ErrAmountOverflow
+func (a Amount) Mul(n uint64) (Amount, error) {
+	if n != 0 && uint64(a) > math.MaxUint64/n {
+		return 0, ErrAmountOverflow
+	}
+	return a * Amount(n), nil
+}
+
+// SumAmounts adds a list of amounts, or returns ErrAmountOverflow
+func SumAmounts(amounts ...Amount) (total Amount, err error) {
+	for _, a := range amounts {
+		if total, err = total.Add(a); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		suf, err := strconv.ParseUint(string(data), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid fio amount %s", string(data))
+		}
+		*a = Amount(suf)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	amount, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
+
+func (a Amount) MarshalBinary(encoder *eos.Encoder) error {
+	asset, err := a.Asset()
+	if err != nil {
+		return err
+	}
+	return encoder.Encode(asset)
+}
+
+func (a *Amount) UnmarshalBinary(decoder *eos.Decoder) error {
+	asset, err := decoder.ReadAsset()
+	if err != nil {
+		return err
+	}
+	amount, err := AmountFromAsset(asset)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
@@ -0,0 +1,139 @@
+package fio
+
+import (
+	"encoding/json"
+	"github.com/fioprotocol/fio-go/eos"
+	"math"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	for _, test := range []struct {
+		in    string
+		suf   Amount
+		valid bool
+	}{
+		{"1.234567890 FIO", 1234567890, true},
+		{"1.5", 1500000000, true},
+		{"1000 FIO", 1000 * SufPerFio, true},
+		{"0.000000001", 1, true},
+		{"18446744073.709551615", math.MaxUint64, true},
+		{"18446744073.709551616", 0, false},
+		{"1.0000000001", 0, false},
+		{"-1", 0, false},
+		{"1 EOS", 0, false},
+		{".5", 0, false},
+		{"", 0, false},
+	} {
+		a, err := ParseAmount(test.in)
+		if test.valid && (err != nil || a != test.suf) {
+			t.Errorf("%q: expected %d got %d %v", test.in, test.suf, a, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%q should be invalid", test.in)
+		}
+	}
+	if s := Amount(1234567890).String(); s != "1.234567890 FIO" {
+		t.Error("wrong string", s)
+	}
+}
+
+func TestAmount_Arithmetic(t *testing.T) {
+	a, b := Amount(SufPerFio/10), Amount(SufPerFio/5)
+	if sum, _ := a.Add(b); sum != 300000000 || sum.String() != "0.300000000 FIO" {
+		t.Error("0.1 + 0.2 should be 0.3, got", sum)
+	}
+	if _, err := Amount(math.MaxUint64).Add(1); err != ErrAmountOverflow {
+		t.Error("add should overflow")
+	}
+	if _, err := a.Sub(b); err != ErrAmountUnderflow {
+		t.Error("sub should underflow")
+	}
+	if _, err := Amount(math.MaxUint64 / 2).Mul(3); err != ErrAmountOverflow {
+		t.Error("mul should overflow")
+	}
+	if total, err := SumAmounts(a, b, a); err != nil || total != 400000000 {
+		t.Error("wrong sum", total, err)
+	}
+}
+
+func TestAmount_Marshal(t *testing.T) {
+	type row struct {
+		Quantity eos.Asset `json:"quantity"`
+		Balance  Amount    `json:"balance"`
+	}
+	asset, _ := Amount(1500000000).Asset()
+	j, err := json.Marshal(row{Quantity: asset, Balance: 1500000000})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(j) != `{"quantity":"1.500000000 FIO","balance":"1.500000000 FIO"}` {
+		t.Error("json should match eos.Asset:", string(j))
+	}
+	decoded := row{}
+	if err = json.Unmarshal([]byte(`{"quantity":"2.000000000 FIO","balance":2000000000}`), &decoded); err != nil || decoded.Balance != 2*SufPerFio {
+		t.Error("should decode SUFs", decoded.Balance, err)
+	}
+
+	// binary encoding is identical to an asset
+	assetBin, err := eos.MarshalBinary(asset)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	amountBin, err := eos.MarshalBinary(Amount(1500000000))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(assetBin) != string(amountBin) {
+		t.Errorf("binary should match eos.Asset: %x != %x", amountBin, assetBin)
+	}
+	var a Amount
+	if err = eos.UnmarshalBinary(assetBin, &a); err != nil || a != 1500000000 {
+		t.Error("could not decode asset", a, err)
+	}
+	eosBin, _ := eos.MarshalBinary(eos.NewEOSAsset(1))
+	if err = eos.UnmarshalBinary(eosBin, &a); err == nil {
+		t.Error("should not decode an EOS asset")
+	}
+	if _, err = AmountFromAsset(eos.Asset{Amount: -1, Symbol: FioAssetSymbol}); err != ErrAmountUnderflow {
+		t.Error("negative asset should be invalid")
+	}
+}
+
+func TestAmount_Fees(t *testing.T) {
+	if fee := GetMaxFeeAmount(FeeRegisterFioDomain); fee.Suf() != uint64(GetMaxFee(FeeRegisterFioDomain))*SufPerFio {
+		t.Error("wrong max fee", fee)
+	}
+	maxFeeMutex.Lock()
+	old := maxFees[FeeTransferTokensPubKey]
+	maxFees[FeeTransferTokensPubKey] = 2_300_000_007
+	maxFeeMutex.Unlock()
+	defer func() {
+		maxFeeMutex.Lock()
+		maxFees[FeeTransferTokensPubKey] = old
+		maxFeeMutex.Unlock()
+	}()
+	if fee := GetMaxFeeAmountByAction("trnsfiopubky"); fee != 2_300_000_007 {
+		t.Error("max fee should be exact", fee)
+	}
+
+	transfer := NewTransferTokensPubKeyAmount("aloha", "FIO7uRvrLVrZCbCM2DtCgUMospqUMnP3JUC1sKHA8zNoF835kJBvN", 300000000).Data.(TransferTokensPubKey)
+	if transfer.Amount != 300000000 || transfer.MaxFee != 2_300_000_007 {
+		t.Errorf("wrong transfer: %+v", transfer)
+	}
+	if _, err := NewTransferAmount("aloha", "bob", math.MaxUint64); err != ErrAmountOverflow {
+		t.Error("transfer should overflow")
+	}
+	if _, err := NewTransferLockedTokensAmount("aloha", "FIO7uRvrLVrZCbCM2DtCgUMospqUMnP3JUC1sKHA8zNoF835kJBvN", false, []LockPeriod{{Duration: 1, Amount: -1}}, math.MaxUint64); err != ErrAmountOverflow {
+		t.Error("locked transfer should overflow")
+	}
+	if stake := NewStakeFioAmount("aloha", "", 1).Data.(StakeFio); stake.Amount != 1 || stake.MaxFee != GetMaxFeeAmount(FeeStakeFioTokens).Suf() {
+		t.Errorf("wrong stake: %+v", stake)
+	}
+	vote := NewSetFeeVoteAmounts(map[string]Amount{FeeRegisterFioDomain: 1, FeeAddNft: 2}, "aloha").Data.(SetFeeVote)
+	if len(vote.FeeRatios) != 2 || vote.FeeRatios[0].EndPoint != FeeAddNft || vote.FeeRatios[0].Value != 2 {
+		t.Errorf("wrong fee vote: %+v", vote)
+	}
+}
@@ -0,0 +1,394 @@
+package fio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"github.com/fioprotocol/fio-go/eos/ecc"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Weights used to score a BpJsonReport, endpoint weights are shared between all endpoints of that type.
+const (
+	BpScoreSchema  = 30
+	BpScoreAccount = 20
+	BpScoreApi     = 30
+	BpScoreP2p     = 20
+)
+
+var bpNodeTypes = map[string]bool{"producer": true, "full": true, "query": true, "seed": true}
+
+var accountNameRe = regexp.MustCompile(`^[a-z1-5.]{1,12}$`)
+var countryRe = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// BpJsonCheck is the result of one check in a BpJsonReport
+type BpJsonCheck struct {
+	Name    string  `json:"name"`
+	Passed  bool    `json:"passed"`
+	Message string  `json:"message,omitempty"`
+	Weight  float64 `json:"weight"`
+}
+
+// BpJsonReport holds the checks for one producer, Score is the percentage of the weighted checks that passed.
+type BpJsonReport struct {
+	Producer eos.AccountName `json:"producer"`
+	Url      string          `json:"url"`
+	BpJson   *BpJson         `json:"bp_json,omitempty"`
+	Checks   []BpJsonCheck   `json:"checks"`
+	Score    float64         `json:"score"`
+	Error    string          `json:"error,omitempty"`
+}
+
+func (r *BpJsonReport) add(name string, weight float64, err error) {
+	c := BpJsonCheck{Name: name, Passed: err == nil, Weight: weight}
+	if err != nil {
+		c.Message = err.Error()
+	}
+	r.Checks = append(r.Checks, c)
+}
+
+func (r *BpJsonReport) score() {
+	var total, passed float64
+	for _, c := range r.Checks {
+		total += c.Weight
+		if c.Passed {
+			passed += c.Weight
+		}
+	}
+	if total > 0 {
+		r.Score = 100 * passed / total
+	}
+}
+
+func checkUrl(field string, s string, required bool, schemes ...string) error {
+	if s == "" {
+		if required {
+			return fmt.Errorf("%s is required", field)
+		}
+		return nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%s is not a valid url", field)
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s must use %s", field, strings.Join(schemes, " or "))
+}
+
+func checkLocation(field string, l BpJsonLocation) []error {
+	errs := make([]error, 0)
+	if l.Name == "" {
+		errs = append(errs, fmt.Errorf("%s.name is required", field))
+	}
+	if !countryRe.MatchString(l.Country) {
+		errs = append(errs, fmt.Errorf("%s.country must be an ISO 3166-1 alpha-2 code", field))
+	}
+	if l.Latitude < -90 || l.Latitude > 90 {
+		errs = append(errs, fmt.Errorf("%s.latitude is out of range", field))
+	}
+	if l.Longitude < -180 || l.Longitude > 180 {
+		errs = append(errs, fmt.Errorf("%s.longitude is out of range", field))
+	}
+	return errs
+}
+
+// ValidateBpJsonSchema checks a bp.json against the bp-info standard, returning every problem found
+func ValidateBpJsonSchema(bpj *BpJson) []error {
+	errs := make([]error, 0)
+	if bpj == nil {
+		return append(errs, errors.New("bp.json is empty"))
+	}
+	add := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if !accountNameRe.MatchString(bpj.ProducerAccountName) {
+		add(errors.New("producer_account_name is not a valid account name"))
+	}
+	if bpj.Org.CandidateName == "" {
+		add(errors.New("org.candidate_name is required"))
+	}
+	add(checkUrl("org.website", bpj.Org.Website, true, "https"))
+	add(checkUrl("org.code_of_conduct", bpj.Org.CodeOfConduct, false, "https"))
+	add(checkUrl("org.ownership_disclosure", bpj.Org.OwnershipDisclosure, false, "https"))
+	add(checkUrl("org.branding.logo_256", bpj.Org.Branding.Logo256, false, "https"))
+	add(checkUrl("org.branding.logo_1024", bpj.Org.Branding.Logo1024, false, "https"))
+	add(checkUrl("org.branding.logo_svg", bpj.Org.Branding.LogoSvg, false, "https"))
+	if !strings.Contains(bpj.Org.Email, "@") {
+		add(errors.New("org.email is required"))
+	}
+	errs = append(errs, checkLocation("org.location", bpj.Org.Location)...)
+
+	if len(bpj.Nodes) == 0 {
+		add(errors.New("at least one node is required"))
+	}
+	for i, n := range bpj.Nodes {
+		field := fmt.Sprintf("nodes[%d]", i)
+		if !bpNodeTypes[n.NodeType] {
+			add(fmt.Errorf("%s.node_type must be producer, full, query or seed", field))
+		}
+		errs = append(errs, checkLocation(field+".location", n.Location)...)
+		add(checkUrl(field+".api_endpoint", n.ApiEndpoint, false, "http"))
+		add(checkUrl(field+".ssl_endpoint", n.SslEndpoint, false, "https"))
+		if n.P2pEndpoint != "" {
+			if _, _, err := net.SplitHostPort(n.P2pEndpoint); err != nil {
+				add(fmt.Errorf("%s.p2p_endpoint must be host:port", field))
+			}
+		}
+		switch n.NodeType {
+		case "query", "full":
+			if n.ApiEndpoint == "" && n.SslEndpoint == "" {
+				add(fmt.Errorf("%s is a %s node without an api_endpoint or ssl_endpoint", field, n.NodeType))
+			}
+		case "seed":
+			if n.P2pEndpoint == "" {
+				add(fmt.Errorf("%s is a seed node without a p2p_endpoint", field))
+			}
+		}
+	}
+	return errs
+}
+
+// BpValidator checks producer bp.json files and probes their advertised endpoints. Endpoints that are IP
+// addresses or resolve to private addresses are not probed unless AllowPrivate is set.
+type BpValidator struct {
+	Api          *API
+	Info         *eos.InfoResp
+	Timeout      time.Duration
+	AllowPrivate bool
+	// Concurrency is the number of producers checked at once by Crawl
+	Concurrency int
+}
+
+// NewBpValidator gets the chain id from the node, which is compared with each probed endpoint
+func NewBpValidator(api *API) (*BpValidator, error) {
+	info, err := api.GetInfo()
+	if err != nil {
+		return nil, err
+	}
+	return &BpValidator{
+		Api:         api,
+		Info:        info,
+		Timeout:     5 * time.Second,
+		Concurrency: 8,
+	}, nil
+}
+
+func (v *BpValidator) checkHost(host string) error {
+	if v.AllowPrivate {
+		return nil
+	}
+	return checkPublicHost(host)
+}
+
+// ProbeApi calls get_info on an api_endpoint or ssl_endpoint and checks the chain id
+func (v *BpValidator) ProbeApi(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if err = v.checkHost(u.Hostname()); err != nil {
+		return err
+	}
+	probe := eos.New(strings.TrimSuffix(endpoint, "/"))
+	probe.HttpClient = &http.Client{Timeout: v.Timeout}
+	info, err := probe.GetInfo()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(info.ChainID, v.Info.ChainID) {
+		return fmt.Errorf("wrong chain id %s", info.ChainID.String())
+	}
+	return nil
+}
+
+// ProbeP2p connects to a p2p_endpoint, sends a handshake, and checks the chain id in the handshake that is
+// returned.
+func (v *BpValidator) ProbeP2p(endpoint string) error {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return err
+	}
+	if err = v.checkHost(host); err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", endpoint, v.Timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(v.Timeout))
+
+	key, _ := ecc.NewPublicKey("FIO1111111111111111111111111111111114T1Anm")
+	nodeId := make([]byte, 32)
+	copy(nodeId, "fio-go bp validator")
+	handshake := &eos.HandshakeMessage{
+		NetworkVersion:           1206,
+		ChainID:                  v.Info.ChainID,
+		NodeID:                   nodeId,
+		Key:                      key,
+		Time:                     eos.Tstamp{Time: time.Now()},
+		Token:                    make([]byte, 32),
+		Signature:                ecc.Signature{Curve: ecc.CurveK1, Content: make([]byte, 65)},
+		P2PAddress:               "fio-go",
+		LastIrreversibleBlockNum: v.Info.LastIrreversibleBlockNum,
+		LastIrreversibleBlockID:  v.Info.LastIrreversibleBlockID,
+		HeadNum:                  v.Info.HeadBlockNum,
+		HeadID:                   v.Info.HeadBlockID,
+		OS:                       runtime.GOOS,
+		Agent:                    "fio-go",
+		Generation:               1,
+	}
+	buf := bytes.NewBuffer(nil)
+	if err = eos.NewEncoder(buf).Encode(&eos.Packet{Type: handshake.GetType(), P2PMessage: handshake}); err != nil {
+		return err
+	}
+	if _, err = conn.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	// the peer may send other messages, such as a time message, before its handshake
+	for i := 0; i < 8; i++ {
+		packet, err := eos.ReadPacket(conn)
+		if err != nil {
+			return fmt.Errorf("did not receive a handshake: %s", err)
+		}
+		switch msg := packet.P2PMessage.(type) {
+		case *eos.HandshakeMessage:
+			if !bytes.Equal(msg.ChainID, v.Info.ChainID) {
+				return fmt.Errorf("wrong chain id %s", msg.ChainID.String())
+			}
+			return nil
+		case *eos.GoAwayMessage:
+			return fmt.Errorf("peer sent go away: %s", msg.Reason)
+		}
+	}
+	return errors.New("did not receive a handshake")
+}
+
+// Validate checks a bp.json for the producer's owner account, and probes every endpoint
+func (v *BpValidator) Validate(owner eos.AccountName, bpj *BpJson) *BpJsonReport {
+	report := &BpJsonReport{Producer: owner, BpJson: bpj, Checks: make([]BpJsonCheck, 0)}
+	if bpj != nil {
+		report.Url = bpj.BpJsonUrl
+	}
+
+	var schemaErr error
+	if errs := ValidateBpJsonSchema(bpj); len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i := range errs {
+			msgs[i] = errs[i].Error()
+		}
+		schemaErr = errors.New(strings.Join(msgs, "; "))
+	}
+	report.add("schema", BpScoreSchema, schemaErr)
+	if bpj == nil {
+		report.score()
+		return report
+	}
+
+	var accountErr error
+	if bpj.ProducerAccountName != string(owner) {
+		accountErr = fmt.Errorf("producer_account_name %q does not match owner %s", bpj.ProducerAccountName, owner)
+	}
+	report.add("producer_account_name", BpScoreAccount, accountErr)
+
+	apis, p2ps := make([]string, 0), make([]string, 0)
+	for _, n := range bpj.Nodes {
+		for _, e := range []string{n.ApiEndpoint, n.SslEndpoint} {
+			if e != "" {
+				apis = append(apis, e)
+			}
+		}
+		if n.P2pEndpoint != "" {
+			p2ps = append(p2ps, n.P2pEndpoint)
+		}
+	}
+	if len(apis) == 0 {
+		report.add("api", BpScoreApi, errors.New("no api endpoints"))
+	}
+	for _, e := range apis {
+		report.add("api "+e, BpScoreApi/float64(len(apis)), v.ProbeApi(e))
+	}
+	if len(p2ps) == 0 {
+		report.add("p2p", BpScoreP2p, errors.New("no p2p endpoints"))
+	}
+	for _, e := range p2ps {
+		report.add("p2p "+e, BpScoreP2p/float64(len(p2ps)), v.ProbeP2p(e))
+	}
+	report.score()
+	return report
+}
+
+// Check fetches and validates the bp.json for a producer, see GetBpJson
+func (v *BpValidator) Check(producer eos.AccountName) *BpJsonReport {
+	bpj, err := v.Api.getBpJson(producer, v.AllowPrivate)
+	if err != nil {
+		return &BpJsonReport{Producer: producer, Checks: make([]BpJsonCheck, 0), Error: err.Error()}
+	}
+	return v.Validate(producer, bpj)
+}
+
+// Crawl checks every active producer from GetFioProducers concurrently, and returns the reports sorted by score,
+// highest first.
+func (v *BpValidator) Crawl(ctx context.Context) ([]*BpJsonReport, error) {
+	producers, err := v.Api.GetFioProducers()
+	if err != nil {
+		return nil, err
+	}
+	owners := make(chan eos.AccountName)
+	reports := make([]*BpJsonReport, 0)
+	mux := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	workers := v.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for owner := range owners {
+				r := v.Check(owner)
+				mux.Lock()
+				reports = append(reports, r)
+				mux.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, p := range producers.Producers {
+		if p.IsActive == 0 {
+			continue
+		}
+		select {
+		case owners <- p.Owner:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(owners)
+	wg.Wait()
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Score == reports[j].Score {
+			return reports[i].Producer < reports[j].Producer
+		}
+		return reports[i].Score > reports[j].Score
+	})
+	return reports, ctx.Err()
+}
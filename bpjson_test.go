@@ -0,0 +1,164 @@
+package fio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testChainId = "21dcae42c0182200e93f954a074011f9048a7624c6fe81d3c9541a614a88bd1c"
+
+// fakeP2p answers a single handshake with either a handshake or a go away message
+func fakeP2p(t *testing.T, goAway bool) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		packet, err := eos.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		buf := bytes.NewBuffer(nil)
+		if goAway {
+			// the encoder does not support GoAwayReason: length, type, reason and a node id
+			buf.Write([]byte{34, 0, 0, 0, byte(eos.GoAwayMessageType), byte(eos.GoAwayWrongChain)})
+			buf.Write(make([]byte, 32))
+		} else {
+			_ = eos.NewEncoder(buf).Encode(&eos.Packet{Type: packet.Type, P2PMessage: packet.P2PMessage})
+		}
+		_, _ = conn.Write(buf.Bytes())
+	}()
+	return l.Addr().String()
+}
+
+func testBpJson(account string, api string, p2p string) *BpJson {
+	bpj := &BpJson{ProducerAccountName: account}
+	bpj.Org.CandidateName = "Test BP"
+	bpj.Org.Website = "https://example.com"
+	bpj.Org.Email = "bp@example.com"
+	bpj.Org.Location = BpJsonLocation{Name: "Cayman Islands", Country: "KY", Latitude: 19.3, Longitude: -81.3}
+	bpj.Nodes = []BpJsonNode{
+		{Location: bpj.Org.Location, NodeType: "query", ApiEndpoint: api},
+		{Location: bpj.Org.Location, NodeType: "seed", P2pEndpoint: p2p},
+	}
+	return bpj
+}
+
+func TestValidateBpJsonSchema(t *testing.T) {
+	if errs := ValidateBpJsonSchema(testBpJson("bp1", "http://api.example.com", "p2p.example.com:9876")); len(errs) != 0 {
+		t.Error("should be valid:", errs)
+	}
+	for _, test := range []struct {
+		name   string
+		modify func(bpj *BpJson)
+		want   string
+	}{
+		{"account", func(bpj *BpJson) { bpj.ProducerAccountName = "BP1" }, "producer_account_name"},
+		{"website", func(bpj *BpJson) { bpj.Org.Website = "http://example.com" }, "org.website must use https"},
+		{"email", func(bpj *BpJson) { bpj.Org.Email = "" }, "org.email"},
+		{"country", func(bpj *BpJson) { bpj.Org.Location.Country = "Cayman" }, "org.location.country"},
+		{"latitude", func(bpj *BpJson) { bpj.Nodes[0].Location.Latitude = 91 }, "nodes[0].location.latitude"},
+		{"node type", func(bpj *BpJson) { bpj.Nodes[0].NodeType = "api" }, "nodes[0].node_type"},
+		{"ssl", func(bpj *BpJson) { bpj.Nodes[0].SslEndpoint = "http://api.example.com" }, "nodes[0].ssl_endpoint"},
+		{"no api", func(bpj *BpJson) { bpj.Nodes[0].ApiEndpoint = "" }, "without an api_endpoint"},
+		{"p2p", func(bpj *BpJson) { bpj.Nodes[1].P2pEndpoint = "p2p.example.com" }, "nodes[1].p2p_endpoint"},
+		{"nodes", func(bpj *BpJson) { bpj.Nodes = nil }, "at least one node"},
+	} {
+		bpj := testBpJson("bp1", "http://api.example.com", "p2p.example.com:9876")
+		test.modify(bpj)
+		errs := ValidateBpJsonSchema(bpj)
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), test.want) {
+			t.Errorf("%s: expected one error containing %q, got %v", test.name, test.want, errs)
+		}
+	}
+}
+
+func TestBpValidator_Crawl(t *testing.T) {
+	var server *httptest.Server
+	wrongChain := strings.Repeat("00", 32)
+	p2ps := map[string]string{"bp1": fakeP2p(t, false), "bp2": fakeP2p(t, true)}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/chain/get_info":
+			chainId := testChainId
+			if strings.HasPrefix(r.Host, "localhost") {
+				chainId = wrongChain
+			}
+			_, _ = fmt.Fprintf(w, `{"chain_id":"%s","head_block_num":1000,"last_irreversible_block_num":990}`, chainId)
+		case r.URL.Path == "/v1/chain/get_producers":
+			_, _ = w.Write([]byte(`{"producers":[
+{"owner":"bp1","is_active":1,"url":"` + server.URL + `/bp1"},
+{"owner":"bp2","is_active":1,"url":"` + server.URL + `/bp2"},
+{"owner":"bp3","is_active":0,"url":"` + server.URL + `/bp3"}]}`))
+		case r.URL.Path == "/v1/chain/get_table_rows":
+			req := eos.GetTableRowsRequest{}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			_, _ = fmt.Fprintf(w, `{"rows":[{"owner":"%s","is_active":1,"url":"%s/%s"}]}`, req.LowerBound, server.URL, req.LowerBound)
+		case strings.HasSuffix(r.URL.Path, "bp.json"):
+			var bpj *BpJson
+			switch strings.Split(r.URL.Path, "/")[1] {
+			case "bp1":
+				bpj = testBpJson("bp1", server.URL, p2ps["bp1"])
+			case "bp2":
+				// wrong account, api on the wrong chain, and the p2p node rejects the handshake
+				bpj = testBpJson("bp9", strings.Replace(server.URL, "127.0.0.1", "localhost", 1), p2ps["bp2"])
+				bpj.Org.Website = "http://example.com"
+			}
+			_ = json.NewEncoder(w).Encode(bpj)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	v, err := NewBpValidator(&API{*eos.New(server.URL)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.AllowPrivate = true
+	v.Timeout = 2 * time.Second
+	reports, err := v.Crawl(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 {
+		t.Fatal("expected a report for each active producer, got", len(reports))
+	}
+	if reports[0].Producer != "bp1" || reports[0].Score != 100 {
+		t.Errorf("bp1 should pass every check: %+v", reports[0])
+	}
+	if reports[1].Producer != "bp2" || reports[1].Score != 0 {
+		t.Errorf("bp2 should fail every check: %+v", reports[1])
+	}
+	for _, c := range reports[1].Checks {
+		switch {
+		case c.Name == "producer_account_name" && !strings.Contains(c.Message, "bp9"):
+			t.Error("wrong account message:", c.Message)
+		case strings.HasPrefix(c.Name, "api") && !strings.Contains(c.Message, "wrong chain id"):
+			t.Error("wrong api message:", c.Message)
+		case strings.HasPrefix(c.Name, "p2p") && !strings.Contains(c.Message, "go away"):
+			t.Error("wrong p2p message:", c.Message)
+		}
+	}
+
+	// private endpoints are refused by default
+	v.AllowPrivate = false
+	if err = v.ProbeApi(server.URL); err == nil {
+		t.Error("should not probe an IP address")
+	}
+}
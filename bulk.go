@@ -0,0 +1,523 @@
+package fio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bulk operations, the values are the fio.address action names
+const (
+	BulkRegAddress      = "regaddress"
+	BulkRegDomain       = "regdomain"
+	BulkRenewAddress    = "renewaddress"
+	BulkRenewDomain     = "renewdomain"
+	BulkTransferAddress = "xferaddress"
+	BulkTransferDomain  = "xferdomain"
+	BulkSetDomainPublic = "setdomainpub"
+)
+
+// Status of an item in a BulkReport
+const (
+	BulkPending     = "pending"
+	BulkReady       = "ready"
+	BulkUnavailable = "unavailable"
+	BulkInvalid     = "invalid"
+	BulkFailed      = "failed"
+	BulkUnknown     = "unknown"
+	BulkDone        = "done"
+)
+
+// txDuplicateCode is the nodeos error code for a transaction that was already accepted
+const txDuplicateCode = 3040008
+
+var bulkFees = map[string]string{
+	BulkRegAddress:      FeeRegisterFioAddress,
+	BulkRegDomain:       FeeRegisterFioDomain,
+	BulkRenewAddress:    FeeRenewFioAddress,
+	BulkRenewDomain:     FeeRenewFioDomain,
+	BulkTransferAddress: FeeTransferAddress,
+	BulkTransferDomain:  FeeTransferDom,
+	BulkSetDomainPublic: FeeSetDomainPub,
+}
+
+// BulkItem is a single operation on a FIO address or domain. PubKey is the owner for a registration, or the new
+// owner for a transfer. Public is only used by BulkSetDomainPublic.
+type BulkItem struct {
+	Op     string `json:"op"`
+	Name   string `json:"name"`
+	PubKey string `json:"pub_key,omitempty"`
+	Public bool   `json:"public,omitempty"`
+}
+
+// isDomain is true if the operation is on a domain instead of an address
+func (bi BulkItem) isDomain() bool {
+	switch bi.Op {
+	case BulkRegDomain, BulkRenewDomain, BulkTransferDomain, BulkSetDomainPublic:
+		return true
+	}
+	return false
+}
+
+// BulkResult is the outcome of a BulkItem. Fee is the fee from the chain in SUF, and is set by Check. Expiration is
+// the expiration of the name before a renewal, used to tell if a renewal was applied.
+type BulkResult struct {
+	BulkItem
+	Status     string `json:"status"`
+	Fee        uint64 `json:"fee"`
+	Expiration int64  `json:"expiration,omitempty"`
+	TxId       string `json:"tx_id,omitempty"`
+	Block      uint32 `json:"block,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// BulkReport tracks every item in a bulk operation. It is safe to save a report after a partial failure and load
+// it later to resume. Pending and ready items are run again, failed and unknown items are checked against the chain
+// first, and only pushed again if their operation was not applied.
+type BulkReport struct {
+	Items []*BulkResult `json:"items"`
+}
+
+// NewBulkReport creates a report with every item pending
+func NewBulkReport(items []BulkItem) *BulkReport {
+	report := &BulkReport{Items: make([]*BulkResult, len(items))}
+	for i := range items {
+		report.Items[i] = &BulkResult{BulkItem: items[i], Status: BulkPending}
+	}
+	return report
+}
+
+// LoadBulkReport reads a report saved with BulkReport.Save
+func LoadBulkReport(r io.Reader) (*BulkReport, error) {
+	report := &BulkReport{}
+	if err := json.NewDecoder(r).Decode(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Save writes the report as JSON
+func (br *BulkReport) Save(w io.Writer) error {
+	j, err := json.MarshalIndent(br, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(j)
+	return err
+}
+
+// Count returns the number of items with a status
+func (br *BulkReport) Count(status string) int {
+	var n int
+	for _, item := range br.Items {
+		if item.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// TotalFee is the sum of fees for items that are ready, failed or unknown, which is the most running the rest of
+// the report can cost.
+func (br *BulkReport) TotalFee() uint64 {
+	var total uint64
+	for _, item := range br.Items {
+		if item.Status == BulkReady || item.Status == BulkFailed || item.Status == BulkUnknown {
+			total += item.Fee
+		}
+	}
+	return total
+}
+
+// BulkOperator runs many name operations for one actor, batching actions into transactions.
+//
+// The number of actions in a transaction is limited by MaxActions and by MaxTrxBytes (the packed size of the
+// action data). It isn't possible to know the CPU used by a transaction before it runs, so if a batch fails it is
+// split in half and retried, until the failing items are isolated. This keeps transactions under the CPU and NET
+// limits, and means one bad item does not fail its neighbours.
+type BulkOperator struct {
+	Api   *API
+	Actor eos.AccountName
+
+	// MaxActions per transaction, defaults to 5
+	MaxActions int
+	// MaxTrxBytes is the maximum size of the packed actions in a transaction, defaults to 2048
+	MaxTrxBytes int
+	// Concurrency is the number of AvailCheck requests to run at once, defaults to 4
+	Concurrency int
+	// Delay between transactions
+	Delay time.Duration
+
+	// Push sends a transaction, it defaults to Api.SignPushActions
+	Push func(actions ...*Action) (*eos.PushTransactionFullResp, error)
+	// Progress is called after each transaction, for example to save the report
+	Progress func(report *BulkReport)
+}
+
+// NewBulkOperator returns a BulkOperator with the default limits
+func NewBulkOperator(api *API, actor eos.AccountName) *BulkOperator {
+	return &BulkOperator{
+		Api:         api,
+		Actor:       actor,
+		MaxActions:  5,
+		MaxTrxBytes: 2048,
+		Concurrency: 4,
+		Push:        api.SignPushActions,
+	}
+}
+
+// action builds the action for an item
+func (bo *BulkOperator) action(item BulkItem) (*Action, error) {
+	switch item.Op {
+	case BulkRegAddress:
+		act, ok := NewRegAddress(bo.Actor, Address(item.Name), item.PubKey)
+		if !ok {
+			return nil, errors.New("invalid fio address")
+		}
+		return act, nil
+	case BulkRegDomain:
+		return NewRegDomain(bo.Actor, item.Name, item.PubKey), nil
+	case BulkRenewAddress:
+		return NewRenewAddress(bo.Actor, item.Name), nil
+	case BulkRenewDomain:
+		return NewRenewDomain(bo.Actor, item.Name), nil
+	case BulkTransferAddress:
+		return NewTransferAddress(bo.Actor, Address(item.Name), item.PubKey), nil
+	case BulkTransferDomain:
+		return NewTransferDom(bo.Actor, item.Name, item.PubKey), nil
+	case BulkSetDomainPublic:
+		return NewSetDomainPub(bo.Actor, item.Name, item.Public), nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", item.Op)
+}
+
+// validate checks the name and public key before any queries are made
+func (bi BulkItem) validate() error {
+	if _, ok := bulkFees[bi.Op]; !ok {
+		return fmt.Errorf("unknown operation %q", bi.Op)
+	}
+	if bi.isDomain() {
+		if _, err := ParseDomain(bi.Name); err != nil {
+			return err
+		}
+	} else if _, err := ParseHandle(bi.Name); err != nil {
+		return err
+	}
+	switch bi.Op {
+	case BulkRegAddress, BulkRegDomain, BulkTransferAddress, BulkTransferDomain:
+		if err := ValidateFioPubKey(bi.PubKey); err != nil {
+			return fmt.Errorf("invalid public key: %s", err)
+		}
+	}
+	return nil
+}
+
+// Check is a dry run. Pending items are validated, checked with AvailCheck concurrently, and have their fee set.
+// Registrations must be available, and other operations must be on registered names. Items that pass are marked
+// ready, use BulkReport.TotalFee for the cost.
+func (bo *BulkOperator) Check(ctx context.Context, report *BulkReport) error {
+	pending := make([]*BulkResult, 0)
+	for _, item := range report.Items {
+		if item.Status != BulkPending {
+			continue
+		}
+		if err := item.validate(); err != nil {
+			item.Status, item.Error = BulkInvalid, err.Error()
+			continue
+		}
+		pending = append(pending, item)
+	}
+
+	// fees are the same for every item with the same operation
+	fees := make(map[string]uint64)
+	for _, item := range pending {
+		if _, ok := fees[item.Op]; ok {
+			continue
+		}
+		fee, err := bo.Api.GetFee("", bulkFees[item.Op])
+		if err != nil {
+			return fmt.Errorf("could not get fee for %s: %s", item.Op, err)
+		}
+		fees[item.Op] = fee
+	}
+
+	workers := bo.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	work := make(chan *BulkResult)
+	errs := make(chan error, workers)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range work {
+				available, err := bo.Api.AvailCheck(item.Name)
+				if err != nil {
+					errs <- fmt.Errorf("avail_check %s: %s", item.Name, err)
+					return
+				}
+				if !available && (item.Op == BulkRenewAddress || item.Op == BulkRenewDomain) {
+					state, err := bo.nameState(item.BulkItem)
+					if err != nil {
+						errs <- fmt.Errorf("lookup %s: %s", item.Name, err)
+						return
+					}
+					if state != nil {
+						item.Expiration = state.expiration
+					}
+				}
+				item.Fee = fees[item.Op]
+				switch {
+				case (item.Op == BulkRegAddress || item.Op == BulkRegDomain) && !available:
+					item.Status, item.Error = BulkUnavailable, "already registered"
+				case item.Op != BulkRegAddress && item.Op != BulkRegDomain && available:
+					item.Status, item.Error = BulkInvalid, "not registered"
+				default:
+					item.Status, item.Error = BulkReady, ""
+				}
+			}
+		}()
+	}
+
+	var err error
+feed:
+	for _, item := range pending {
+		select {
+		case work <- item:
+		case err = <-errs:
+			break feed
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+	return err
+}
+
+// Run checks any pending items, rechecks failed and unknown items, then submits every ready item. It returns an
+// error if the context is cancelled or a check fails, individual item failures are recorded in the report.
+func (bo *BulkOperator) Run(ctx context.Context, report *BulkReport) error {
+	if err := bo.Check(ctx, report); err != nil {
+		return err
+	}
+	for _, item := range report.Items {
+		if item.Status != BulkFailed && item.Status != BulkUnknown {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := bo.recheck(item); err != nil {
+			return fmt.Errorf("recheck %s: %s", item.Name, err)
+		}
+	}
+	if bo.Push == nil {
+		bo.Push = bo.Api.SignPushActions
+	}
+
+	todo := make([]*BulkResult, 0)
+	for _, item := range report.Items {
+		if item.Status == BulkReady {
+			todo = append(todo, item)
+		}
+	}
+	for _, batch := range bo.batches(todo) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		bo.submit(batch)
+		if bo.Progress != nil {
+			bo.Progress(report)
+		}
+		if bo.Delay > 0 {
+			time.Sleep(bo.Delay)
+		}
+	}
+	return nil
+}
+
+// batches groups items into transactions under MaxActions and MaxTrxBytes
+func (bo *BulkOperator) batches(items []*BulkResult) [][]*BulkResult {
+	maxActions, maxBytes := bo.MaxActions, bo.MaxTrxBytes
+	if maxActions < 1 {
+		maxActions = 1
+	}
+	batches := make([][]*BulkResult, 0)
+	current := make([]*BulkResult, 0)
+	size := 0
+	for _, item := range items {
+		act, err := bo.action(item.BulkItem)
+		if err != nil {
+			item.Status, item.Error = BulkInvalid, err.Error()
+			continue
+		}
+		packed, err := eos.MarshalBinary(act.ToEos())
+		if err != nil {
+			item.Status, item.Error = BulkInvalid, err.Error()
+			continue
+		}
+		if len(current) > 0 && (len(current) >= maxActions || (maxBytes > 0 && size+len(packed) > maxBytes)) {
+			batches = append(batches, current)
+			current, size = make([]*BulkResult, 0), 0
+		}
+		current = append(current, item)
+		size += len(packed)
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+// bulkNameState is the current owner and expiration of a name, public is only set for domains
+type bulkNameState struct {
+	owner      eos.AccountName
+	expiration int64
+	public     bool
+}
+
+// nameState looks up a name in the fionames or domains table, it returns nil if the name is not registered
+func (bo *BulkOperator) nameState(item BulkItem) (*bulkNameState, error) {
+	req := eos.GetTableRowsRequest{
+		Code:    "fio.address",
+		Scope:   "fio.address",
+		Limit:   1,
+		KeyType: "i128",
+		JSON:    true,
+	}
+	if item.isDomain() {
+		req.Table, req.Index = "domains", "4"
+		req.LowerBound = DomainNameHash(strings.ToLower(item.Name))
+	} else {
+		req.Table, req.Index = "fionames", "5"
+		req.LowerBound = AddressHash(strings.ToLower(item.Name))
+	}
+	req.UpperBound = req.LowerBound
+	gtr, err := bo.Api.GetTableRows(req)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]struct {
+		OwnerAccount eos.AccountName `json:"owner_account"`
+		Account      eos.AccountName `json:"account"`
+		Expiration   int64           `json:"expiration"`
+		IsPublic     uint8           `json:"is_public"`
+	}, 0)
+	if err = json.Unmarshal(gtr.Rows, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	state := &bulkNameState{owner: rows[0].OwnerAccount, expiration: rows[0].Expiration, public: rows[0].IsPublic == 1}
+	if item.isDomain() {
+		state.owner = rows[0].Account
+	}
+	return state, nil
+}
+
+// recheck looks up a failed or unknown item on chain. If its operation was applied it is marked done, so it is not
+// pushed and charged for again, otherwise it is marked ready.
+func (bo *BulkOperator) recheck(item *BulkResult) error {
+	state, err := bo.nameState(item.BulkItem)
+	if err != nil {
+		return err
+	}
+	var applied bool
+	switch item.Op {
+	case BulkRegAddress, BulkRegDomain:
+		if state != nil {
+			owner, err := ActorFromPub(item.PubKey)
+			if err != nil {
+				return err
+			}
+			if state.owner != owner {
+				item.Status, item.Error = BulkUnavailable, "already registered"
+				return nil
+			}
+			applied = true
+		}
+	case BulkRenewAddress, BulkRenewDomain:
+		applied = state != nil && state.expiration > item.Expiration
+	case BulkTransferAddress, BulkTransferDomain:
+		newOwner, err := ActorFromPub(item.PubKey)
+		if err != nil {
+			return err
+		}
+		applied = state != nil && state.owner == newOwner
+	case BulkSetDomainPublic:
+		applied = state != nil && state.public == item.Public
+	}
+	if applied {
+		item.Status, item.Error = BulkDone, "applied by an earlier push"
+		return nil
+	}
+	if state == nil && item.Op != BulkRegAddress && item.Op != BulkRegDomain {
+		item.Status, item.Error = BulkInvalid, "not registered"
+		return nil
+	}
+	item.Status = BulkReady
+	return nil
+}
+
+// isRejection is true if the node refused a transaction, meaning it was not applied and can be pushed again. Other
+// errors, such as timeouts, are ambiguous because the transaction may have been accepted.
+func isRejection(err error) bool {
+	var apiErr eos.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorStruct.Code != txDuplicateCode
+	}
+	var apiErrPtr *eos.APIError
+	if errors.As(err, &apiErrPtr) {
+		return apiErrPtr.ErrorStruct.Code != txDuplicateCode
+	}
+	return false
+}
+
+// submit pushes a batch, splitting it in half on a rejection until each failing item is isolated. Items in a batch
+// with an ambiguous error are marked unknown, Run will recheck them before they are pushed again.
+func (bo *BulkOperator) submit(batch []*BulkResult) {
+	// batches only holds items that have a valid action
+	actions := make([]*Action, len(batch))
+	for i, item := range batch {
+		actions[i], _ = bo.action(item.BulkItem)
+	}
+	resp, err := bo.Push(actions...)
+	if err == nil && resp == nil {
+		err = errors.New("empty response from push")
+	}
+	if err == nil {
+		for _, item := range batch {
+			item.Status, item.Error, item.TxId, item.Block = BulkDone, "", resp.TransactionID, resp.BlockNum
+		}
+		return
+	}
+	if !isRejection(err) {
+		for _, item := range batch {
+			item.Status, item.Error = BulkUnknown, err.Error()
+		}
+		return
+	}
+	if len(batch) == 1 {
+		batch[0].Status, batch[0].Error = BulkFailed, err.Error()
+		return
+	}
+	bo.submit(batch[:len(batch)/2])
+	bo.submit(batch[len(batch)/2:])
+}
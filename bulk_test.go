@@ -0,0 +1,154 @@
+package fio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/fioprotocol/fio-go/eos"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// bulkRejection is an error returned by nodeos for a transaction that was not applied
+func bulkRejection(code int, what string) error {
+	apiErr := eos.APIError{Code: 500, Message: "Internal Service Error"}
+	apiErr.ErrorStruct.Code, apiErr.ErrorStruct.What = code, what
+	return apiErr
+}
+
+func TestBulkOperator(t *testing.T) {
+	owner, _ := NewRandomAccount()
+	mux := sync.Mutex{}
+	// registered names and their owners
+	registered := map[string]eos.AccountName{"taken@fio": "someoneelse1", "fio": "someoneelse1", "mine@fio": owner.Actor, "bad@fio": owner.Actor}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/v1/chain/get_table_rows":
+			req := eos.GetTableRowsRequest{}
+			_ = json.Unmarshal(body, &req)
+			for name, account := range registered {
+				if req.Table == "fionames" && AddressHash(name) == req.LowerBound {
+					_, _ = w.Write([]byte(`{"rows":[{"name":"` + name + `","owner_account":"` + string(account) + `","expiration":1700000000}]}`))
+					return
+				}
+			}
+			_, _ = w.Write([]byte(`{"rows":[]}`))
+		case "/v1/chain/avail_check":
+			req := AvailCheckReq{}
+			_ = json.Unmarshal(body, &req)
+			if registered[req.FioName] != "" {
+				_, _ = w.Write([]byte(`{"is_registered":1}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"is_registered":0}`))
+		case "/v1/chain/get_fee":
+			req := GetFeeRequest{}
+			_ = json.Unmarshal(body, &req)
+			if req.EndPoint == FeeRegisterFioDomain {
+				_, _ = w.Write([]byte(`{"fee":800000000000}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"fee":40000000000}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	items := []BulkItem{
+		{Op: BulkRegDomain, Name: "newdomain", PubKey: owner.PubKey},
+		{Op: BulkRegAddress, Name: "taken@fio", PubKey: owner.PubKey},
+		{Op: BulkRenewAddress, Name: "mine@fio"},
+		{Op: BulkRenewAddress, Name: "bad@fio"},
+		{Op: BulkTransferAddress, Name: "missing@fio", PubKey: owner.PubKey},
+		{Op: BulkRegAddress, Name: "bad:fio", PubKey: owner.PubKey},
+		{Op: BulkRegAddress, Name: "no-key@fio", PubKey: "EOS123"},
+	}
+	for i := 0; i < 10; i++ {
+		items = append(items, BulkItem{Op: BulkRegAddress, Name: "name" + string(rune('a'+i)) + "@fio", PubKey: owner.PubKey})
+	}
+
+	bo := NewBulkOperator(&API{*eos.New(server.URL)}, owner.Actor)
+	bo.MaxActions = 4
+	failNext := true
+	pushes := make([]int, 0)
+	bo.Push = func(actions ...*Action) (*eos.PushTransactionFullResp, error) {
+		pushes = append(pushes, len(actions))
+		if len(actions) > 3 {
+			return nil, bulkRejection(3080004, "tx_cpu_usage_exceeded")
+		}
+		for _, a := range actions {
+			if r, ok := a.Data.(RenewAddress); ok && r.FioAddress == "bad@fio" {
+				return nil, bulkRejection(3050003, "assertion failure")
+			}
+			if ra, ok := a.Data.(RegAddress); ok && ra.FioAddress == "namej@fio" && failNext {
+				// the transaction was accepted, but the response was lost
+				failNext = false
+				mux.Lock()
+				registered["namej@fio"] = owner.Actor
+				mux.Unlock()
+				return nil, errors.New("net/http: timeout awaiting response headers")
+			}
+		}
+		return &eos.PushTransactionFullResp{TransactionID: "abc", BlockNum: 1}, nil
+	}
+
+	report := NewBulkReport(items)
+	if err := bo.Check(context.Background(), report); err != nil {
+		t.Error(err)
+		return
+	}
+	if report.Count(BulkReady) != 13 || report.Count(BulkUnavailable) != 1 || report.Count(BulkInvalid) != 3 {
+		j, _ := json.MarshalIndent(report, "", "  ")
+		t.Error("unexpected check results", string(j))
+	}
+	if report.TotalFee() != 800000000000+12*40000000000 {
+		t.Error("wrong dry run fee total", report.TotalFee())
+	}
+	if len(pushes) != 0 {
+		t.Error("check should not push transactions")
+	}
+
+	saved := bytes.NewBuffer(nil)
+	bo.Progress = func(r *BulkReport) {
+		saved.Reset()
+		_ = r.Save(saved)
+	}
+	if err := bo.Run(context.Background(), report); err != nil {
+		t.Error(err)
+		return
+	}
+	if report.Count(BulkDone) != 11 || report.Count(BulkFailed) != 1 || report.Count(BulkUnknown) != 1 {
+		t.Error("expected 11 done, 1 failed and 1 unknown, got", report.Count(BulkDone), report.Count(BulkFailed), report.Count(BulkUnknown))
+	}
+	for _, item := range report.Items {
+		if item.Name == "bad@fio" && !strings.Contains(item.Error, "assertion failure") {
+			t.Error("failure was not isolated to the bad item:", item.Error)
+		}
+	}
+
+	// resume from the saved report, the unknown registration was applied so only the failed renewal is pushed
+	resumed, err := LoadBulkReport(saved)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pushes = pushes[:0]
+	if err = bo.Run(context.Background(), resumed); err != nil {
+		t.Error(err)
+	}
+	if resumed.Count(BulkDone) != 12 || resumed.Count(BulkFailed) != 1 || resumed.Count(BulkUnknown) != 0 {
+		t.Error("expected the unknown item to be done on resume", resumed.Count(BulkDone), resumed.Count(BulkFailed))
+	}
+	if len(pushes) != 1 || pushes[0] != 1 {
+		t.Error("resume should only have pushed the failed renewal, got", pushes)
+	}
+}
@@ -2,6 +2,7 @@ package fio
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
@@ -16,6 +17,7 @@ import (
 	"net/http/httputil"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -45,9 +47,35 @@ func (act Action) ToEos() *eos.Action {
 	}
 }
 
+// actionFromEos is the inverse of Action.ToEos, used when building actions with the eos sub-packages
+func actionFromEos(act *eos.Action) *Action {
+	return &Action{
+		Account:       act.Account,
+		Name:          act.Name,
+		Authorization: act.Authorization,
+		ActionData:    act.ActionData,
+	}
+}
+
 // TxOptions wraps eos.TxOptions
 type TxOptions struct {
 	eos.TxOptions
+	tpid string
+}
+
+// SetTpid overrides CurrentTpid for every action in transactions built with these options, see Action.WithTpid.
+// An empty tpid removes the override, anything else must be a valid FIO address.
+func (txo *TxOptions) SetTpid(tpid string) error {
+	if tpid != "" && !Address(tpid).Valid() {
+		return fmt.Errorf("invalid tpid %q", tpid)
+	}
+	txo.tpid = tpid
+	return nil
+}
+
+// Tpid is the override set with SetTpid
+func (txo TxOptions) Tpid() string {
+	return txo.tpid
 }
 
 func (txo TxOptions) toEos() *eos.TxOptions {
@@ -71,6 +99,11 @@ const (
 func NewTransaction(actions []*Action, txOpts *TxOptions) *eos.Transaction {
 	eosActions := make([]*eos.Action, 0)
 	for _, a := range actions {
+		if txOpts != nil && txOpts.tpid != "" {
+			if withTpid, ok := a.WithTpid(txOpts.tpid); ok {
+				a = withTpid
+			}
+		}
 		eosActions = append(
 			eosActions,
 			&eos.Action{
@@ -374,6 +407,66 @@ func (api *API) GetBlockByNum(num uint32) (out *eos.BlockResp, err error) {
 	return
 }
 
+// BlockActions returns the actions in a block's transactions, the action data is left as HexData. Transactions that
+// can't be unpacked, such as deferred transactions that only include an id, are skipped.
+func BlockActions(block *eos.BlockResp) []*eos.Action {
+	actions := make([]*eos.Action, 0)
+	if block == nil {
+		return actions
+	}
+	for _, receipt := range block.Transactions {
+		if receipt.Transaction.Packed == nil {
+			continue
+		}
+		trx, err := receipt.Transaction.Packed.UnpackBare()
+		if err != nil {
+			continue
+		}
+		actions = append(actions, trx.Actions...)
+	}
+	return actions
+}
+
+// FollowBlocks calls handler for each irreversible block, starting at startBlock or the current last irreversible
+// block if 0, and polls for new blocks every interval. It returns when the context is done, or when the handler or
+// a query returns an error.
+func (api *API) FollowBlocks(ctx context.Context, startBlock uint32, interval time.Duration, handler func(block *eos.BlockResp) error) error {
+	return api.followBlocks(ctx, startBlock, interval, func(block *eos.BlockResp, _ *eos.InfoResp) error {
+		return handler(block)
+	})
+}
+
+// followBlocks is FollowBlocks, also passing the handler the get_info response the block was found with
+func (api *API) followBlocks(ctx context.Context, startBlock uint32, interval time.Duration, handler func(block *eos.BlockResp, info *eos.InfoResp) error) error {
+	next := startBlock
+	for {
+		info, err := api.GetInfo()
+		if err != nil {
+			return err
+		}
+		if next == 0 {
+			next = info.LastIrreversibleBlockNum
+		}
+		for ; next <= info.LastIrreversibleBlockNum; next++ {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			block, err := api.GetBlockByNum(next)
+			if err != nil {
+				return err
+			}
+			if err = handler(block, info); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
 // BlockHeaderState holds information about reversible blocks.
 type BlockHeaderState struct {
 	BlockNum                  uint32            `json:"block_num"`
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/fioprotocol/fio-go/abigen"
+	"github.com/fioprotocol/fio-go/eos"
+)
+
+// fio-abigen generates typed Go structs, action constructors, and table helpers from a contract's ABI. The ABI is
+// either read from a file, or fetched from a node using get_abi. It is intended to be used with go generate:
+//
+//   //go:generate go run github.com/fioprotocol/fio-go/cmd/fio-abigen -a fio.address -f fio.address.abi -p address -o address_gen.go
+func main() {
+	url := flag.String("u", "", "nodeos url, used to fetch the abi if -f is not provided")
+	account := flag.String("a", "", "contract account, required")
+	file := flag.String("f", "", "abi file to read")
+	pkg := flag.String("p", "", "package name for the generated code, required")
+	out := flag.String("o", "", "output file, defaults to stdout")
+	flag.Parse()
+
+	if *account == "" || *pkg == "" || (*url == "" && *file == "") {
+		log.Fatalln("usage: fio-abigen -a <account> -p <package> [-f <file.abi> | -u <url>] [-o <output.go>]")
+	}
+
+	var abi *eos.ABI
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatalln("error opening abi:", err)
+		}
+		defer f.Close()
+		if abi, err = eos.NewABI(f); err != nil {
+			log.Fatalln(err)
+		}
+	} else {
+		resp, err := eos.New(*url).GetABI(eos.AccountName(*account))
+		if err != nil {
+			log.Fatalln("error fetching abi:", err)
+		}
+		abi = &resp.ABI
+	}
+
+	src, err := abigen.Generate(abi, abigen.Options{Package: *pkg, Contract: eos.AccountName(*account)})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if *out == "" {
+		_, _ = os.Stdout.Write(src)
+		return
+	}
+	if err = ioutil.WriteFile(*out, src, 0644); err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -0,0 +1,209 @@
+// Package ecies implements the message encryption used in the content field of FIO requests and records.
+//
+// A shared secret is derived with ECDH, and the sha512 hash of the secret is hashed again with sha512. The first 32
+// bytes of the result are the AES-256-CBC key, and the last 32 bytes are the key for a sha256 HMAC over the IV and
+// ciphertext. The plaintext is PKCS#7 padded, and the message format is:
+//
+//	IV (16 bytes) + Ciphertext (n * 16 bytes) + HMAC (32 bytes)
+//
+// See https://github.com/fioprotocol/fiojs/blob/master/docs/message_encryption.md for more information.
+package ecies
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/fioprotocol/fio-go/eos/btcsuite/btcutil"
+	"github.com/fioprotocol/fio-go/eos/ecc"
+)
+
+const (
+	IVSize  = aes.BlockSize
+	MacSize = sha256.Size
+
+	// MinMessageSize is the size of an empty plaintext, which is padded to a full block
+	MinMessageSize = IVSize + aes.BlockSize + MacSize
+)
+
+var (
+	ErrMessageTooShort  = errors.New("ecies: message is too short")
+	ErrMessageLength    = errors.New("ecies: ciphertext is not a multiple of the block size")
+	ErrInvalidMac       = errors.New("ecies: hmac is invalid")
+	ErrInvalidPadding   = errors.New("ecies: invalid pkcs#7 padding")
+	ErrInvalidIV        = errors.New("ecies: iv must be 16 bytes")
+	ErrInvalidPublicKey = errors.New("ecies: invalid public key")
+)
+
+// Keys holds the encryption and hmac keys for a pair of accounts, both sides of the conversation derive the same keys
+type Keys struct {
+	Encryption [32]byte
+	Mac        [32]byte
+}
+
+// SharedKey returns the raw ECDH shared secret (the x coordinate) for a private and public key
+func SharedKey(private *ecc.PrivateKey, public ecc.PublicKey) ([]byte, error) {
+	if private == nil {
+		return nil, errors.New("ecies: private key is nil")
+	}
+	wif, err := btcutil.DecodeWIF(private.String())
+	if err != nil {
+		return nil, err
+	}
+	btcPub, err := public.Key()
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	priv := ecies.ImportECDSA(wif.PrivKey.ToECDSA())
+	return priv.GenerateShared(ecies.ImportECDSAPublic(btcPub.ToECDSA()), 32, 0)
+}
+
+// SharedSecret returns the sha512 hash of the ECDH shared secret, this is the value shown as the shared secret in
+// the fiojs documentation.
+func SharedSecret(private *ecc.PrivateKey, public ecc.PublicKey) (hash [64]byte, err error) {
+	shared, err := SharedKey(private, public)
+	if err != nil {
+		return hash, err
+	}
+	return sha512.Sum512(shared), nil
+}
+
+// NewKeys derives the encryption and hmac keys from the hash returned by SharedSecret
+func NewKeys(secretHash [64]byte) *Keys {
+	k := sha512.Sum512(secretHash[:])
+	keys := &Keys{}
+	copy(keys.Encryption[:], k[:32])
+	copy(keys.Mac[:], k[32:])
+	return keys
+}
+
+// DeriveKeys is a convenience function for NewKeys(SharedSecret(private, public))
+func DeriveKeys(private *ecc.PrivateKey, public ecc.PublicKey) (*Keys, error) {
+	secret, err := SharedSecret(private, public)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeys(secret), nil
+}
+
+// Encrypt encrypts a message, if iv is nil a random IV is used.
+func (k *Keys) Encrypt(plainText []byte, iv []byte) ([]byte, error) {
+	iv, err := checkIV(iv)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(k.Encryption[:])
+	if err != nil {
+		return nil, err
+	}
+	padded := pad(plainText)
+	msg := make([]byte, IVSize+len(padded), IVSize+len(padded)+MacSize)
+	copy(msg, iv)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(msg[IVSize:], padded)
+	return k.sign(msg), nil
+}
+
+// Decrypt verifies and decrypts a message. The hmac is checked in constant time before anything is decrypted.
+func (k *Keys) Decrypt(message []byte) ([]byte, error) {
+	if err := CheckFormat(message); err != nil {
+		return nil, err
+	}
+	body := message[:len(message)-MacSize]
+	mac := hmac.New(sha256.New, k.Mac[:])
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), message[len(message)-MacSize:]) {
+		return nil, ErrInvalidMac
+	}
+	block, err := aes.NewCipher(k.Encryption[:])
+	if err != nil {
+		return nil, err
+	}
+	plainText := make([]byte, len(body)-IVSize)
+	cipher.NewCBCDecrypter(block, body[:IVSize]).CryptBlocks(plainText, body[IVSize:])
+	return unpad(plainText)
+}
+
+// EncryptBase64 is the same as Encrypt, but returns base64 as used in the content field
+func (k *Keys) EncryptBase64(plainText []byte, iv []byte) (string, error) {
+	msg, err := k.Encrypt(plainText, iv)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(msg), nil
+}
+
+// DecryptBase64 is the same as Decrypt, but accepts base64 as found in the content field. Padding is required and
+// the encoding is checked strictly.
+func (k *Keys) DecryptBase64(message string) ([]byte, error) {
+	msg, err := base64.StdEncoding.Strict().DecodeString(message)
+	if err != nil {
+		return nil, err
+	}
+	return k.Decrypt(msg)
+}
+
+// CheckFormat validates the length of a message without decrypting it
+func CheckFormat(message []byte) error {
+	if len(message) < MinMessageSize {
+		return ErrMessageTooShort
+	}
+	if (len(message)-IVSize-MacSize)%aes.BlockSize != 0 {
+		return ErrMessageLength
+	}
+	return nil
+}
+
+func (k *Keys) sign(msg []byte) []byte {
+	mac := hmac.New(sha256.New, k.Mac[:])
+	mac.Write(msg)
+	return mac.Sum(msg)
+}
+
+func checkIV(iv []byte) ([]byte, error) {
+	if iv == nil {
+		iv = make([]byte, IVSize)
+		_, err := rand.Read(iv)
+		return iv, err
+	}
+	if len(iv) != IVSize {
+		return nil, ErrInvalidIV
+	}
+	return iv, nil
+}
+
+func pad(b []byte) []byte {
+	padLen := aes.BlockSize - len(b)%aes.BlockSize
+	padded := make([]byte, len(b)+padLen)
+	copy(padded, b)
+	for i := len(b); i < len(padded); i++ {
+		padded[i] = byte(padLen)
+	}
+	return padded
+}
+
+// unpad checks every padding byte, without branching on the values, so that the time taken does not depend on
+// where the padding is wrong.
+func unpad(b []byte) ([]byte, error) {
+	if len(b) == 0 || len(b)%aes.BlockSize != 0 {
+		return nil, ErrInvalidPadding
+	}
+	padLen := int(b[len(b)-1])
+	good := subtle.ConstantTimeLessOrEq(1, padLen) & subtle.ConstantTimeLessOrEq(padLen, aes.BlockSize)
+	last := b[len(b)-aes.BlockSize:]
+	for i := 0; i < aes.BlockSize; i++ {
+		// only bytes within the padding length are compared
+		inPad := subtle.ConstantTimeLessOrEq(aes.BlockSize-padLen, i)
+		match := subtle.ConstantTimeByteEq(last[i], byte(padLen))
+		good &= subtle.ConstantTimeSelect(inPad, match, 1)
+	}
+	if good != 1 {
+		return nil, ErrInvalidPadding
+	}
+	return b[:len(b)-padLen], nil
+}
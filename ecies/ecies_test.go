@@ -0,0 +1,216 @@
+package ecies
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"github.com/fioprotocol/fio-go/eos/ecc"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+)
+
+// vectors from the fiojs message encryption tests
+const (
+	aliceWif     = "5J9bWm2ThenDm3tjvmUgHtWCVMUdjRR1pxnRtnJjvKA4b2ut5WK"
+	bobWif       = "5JoQtsKQuH8hC9MyvfJAqo6qmKLm8ePYNucs7tPu2YxG12trzBt"
+	sharedSecret = "a71b4ec5a9577926a1d2aa1d9d99327fd3b68f6a1ea597200a0d890bd3331df300a2d49fec0b2b3e6969ce9263c5d6cf47c191c1ef149373ecc9f0d98116b598"
+	plainText    = "0b70757273652e616c69636501310a66696f2e7265716f6274000000"
+	cipherText   = "f300888ca4f512cebdc0020ff0f7224c0db2984c4ad9afb12629f01a8c6a76328bbde17405655dc4e3cb30dad272996fb1dea8e662e640be193e25d41147a904c571b664a7381ab41ef062448ac1e205"
+)
+
+func testKeys(t testing.TB) (alice *Keys, bob *Keys) {
+	alicePriv, err := ecc.NewPrivateKey(aliceWif)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobPriv, err := ecc.NewPrivateKey(bobWif)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice, err = DeriveKeys(alicePriv, bobPriv.PublicKey()); err != nil {
+		t.Fatal(err)
+	}
+	if bob, err = DeriveKeys(bobPriv, alicePriv.PublicKey()); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestSharedSecret(t *testing.T) {
+	alicePriv, _ := ecc.NewPrivateKey(aliceWif)
+	bobPriv, _ := ecc.NewPrivateKey(bobWif)
+	a, err := SharedSecret(alicePriv, bobPriv.PublicKey())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	b, err := SharedSecret(bobPriv, alicePriv.PublicKey())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if hex.EncodeToString(a[:]) != sharedSecret || a != b {
+		t.Error("shared secret did not match the known value")
+	}
+	if _, err = SharedSecret(nil, bobPriv.PublicKey()); err == nil {
+		t.Error("should not accept a nil key")
+	}
+}
+
+func TestKeys_Vector(t *testing.T) {
+	alice, bob := testKeys(t)
+	if *alice != *bob {
+		t.Error("both sides should derive the same keys")
+	}
+	expect, _ := hex.DecodeString(cipherText)
+	plain, _ := hex.DecodeString(plainText)
+
+	encrypted, err := alice.Encrypt(plain, expect[:IVSize])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !bytes.Equal(encrypted, expect) {
+		t.Errorf("did not match vector:\n%x\n%x", encrypted, expect)
+	}
+	decrypted, err := bob.Decrypt(expect)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !bytes.Equal(decrypted, plain) {
+		t.Error("did not decrypt vector")
+	}
+
+	// streaming should give identical output
+	buf := bytes.NewBuffer(nil)
+	w, err := alice.NewWriter(buf, expect[:IVSize])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, b := range plain {
+		if _, err = w.Write([]byte{b}); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(buf.Bytes(), expect) {
+		t.Error("writer output did not match vector")
+	}
+}
+
+func TestKeys_Errors(t *testing.T) {
+	alice, bob := testKeys(t)
+	msg, _ := hex.DecodeString(cipherText)
+
+	for i := range msg {
+		tampered := append([]byte{}, msg...)
+		tampered[i] ^= 0x01
+		if _, err := bob.Decrypt(tampered); err != ErrInvalidMac {
+			t.Errorf("flipping byte %d: expected invalid mac, got %v", i, err)
+		}
+	}
+	if _, err := bob.Decrypt(msg[:MinMessageSize-1]); err != ErrMessageTooShort {
+		t.Error("expected message too short, got", err)
+	}
+	if _, err := bob.Decrypt(msg[:len(msg)-1]); err != ErrMessageLength {
+		t.Error("expected bad length, got", err)
+	}
+	if _, err := alice.Encrypt([]byte("hello"), make([]byte, 8)); err != ErrInvalidIV {
+		t.Error("expected invalid iv, got", err)
+	}
+	if _, err := bob.DecryptBase64("not base64!"); err == nil {
+		t.Error("should not accept invalid base64")
+	}
+
+	// a message with a valid mac but bad padding, built by hand
+	for _, last := range []byte{0, 17, 255} {
+		padded := bytes.Repeat([]byte{3}, aes.BlockSize)
+		padded[aes.BlockSize-1] = last
+		if _, err := bob.Decrypt(encryptRaw(alice, padded)); err != ErrInvalidPadding {
+			t.Errorf("padding byte %d: expected invalid padding, got %v", last, err)
+		}
+	}
+	padded := bytes.Repeat([]byte{4}, aes.BlockSize)
+	padded[aes.BlockSize-3] = 5
+	if _, err := bob.Decrypt(encryptRaw(alice, padded)); err != ErrInvalidPadding {
+		t.Error("inconsistent padding bytes should be rejected, got", err)
+	}
+	padded[aes.BlockSize-3] = 4
+	if plain, err := bob.Decrypt(encryptRaw(alice, padded)); err != nil || len(plain) != aes.BlockSize-4 {
+		t.Error("valid padding was rejected", err)
+	}
+}
+
+// encryptRaw encrypts and signs without adding padding
+func encryptRaw(k *Keys, blocks []byte) []byte {
+	block, _ := aes.NewCipher(k.Encryption[:])
+	msg := make([]byte, IVSize+len(blocks))
+	cipher.NewCBCEncrypter(block, msg[:IVSize]).CryptBlocks(msg[IVSize:], blocks)
+	return k.sign(msg)
+}
+
+func TestStream(t *testing.T) {
+	alice, bob := testKeys(t)
+	for _, size := range []int{0, 1, 15, 16, 17, 47, 48, 49, 1000, 70000} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		buf := bytes.NewBuffer(nil)
+		w, err := alice.NewWriter(buf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < size; i += 7 {
+			end := i + 7
+			if end > size {
+				end = size
+			}
+			if _, err = w.Write(data[i:end]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		// streamed and whole-message output must be interchangeable
+		whole, err := bob.Decrypt(buf.Bytes())
+		if err != nil || !bytes.Equal(whole, data) {
+			t.Errorf("size %d: Decrypt could not read Writer output: %v", size, err)
+		}
+		r, err := bob.NewReader(iotest.OneByteReader(bytes.NewReader(buf.Bytes())))
+		if err != nil {
+			t.Fatal(err)
+		}
+		streamed, err := ioutil.ReadAll(r)
+		if err != nil || !bytes.Equal(streamed, data) {
+			t.Errorf("size %d: Reader did not decrypt: %v", size, err)
+		}
+
+		tampered := append([]byte{}, buf.Bytes()...)
+		tampered[len(tampered)/2] ^= 0x80
+		r, err = bob.NewReader(bytes.NewReader(tampered))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = ioutil.ReadAll(r); err != ErrInvalidMac {
+			t.Errorf("size %d: expected invalid mac from tampered stream, got %v", size, err)
+		}
+	}
+
+	if _, err := bob.NewReader(bytes.NewReader(make([]byte, 8))); err != ErrMessageTooShort {
+		t.Error("expected message too short, got", err)
+	}
+	r, _ := bob.NewReader(bytes.NewReader(make([]byte, IVSize+MacSize+aes.BlockSize+1)))
+	if _, err := ioutil.ReadAll(r); err != ErrMessageLength {
+		t.Error("expected bad length, got", err)
+	}
+}
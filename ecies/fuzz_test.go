@@ -0,0 +1,76 @@
+//go:build go1.18
+// +build go1.18
+
+package ecies
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"testing"
+)
+
+// FuzzDecrypt ensures malformed messages are rejected with an error, and never panic. Any message that does
+// decrypt must have been produced with the correct keys, so it has to round trip.
+func FuzzDecrypt(f *testing.F) {
+	_, bob := testKeys(f)
+	vector, _ := hex.DecodeString(cipherText)
+	f.Add(vector)
+	f.Add(vector[:MinMessageSize])
+	f.Add(vector[:len(vector)-1])
+	f.Add([]byte{})
+	f.Add(make([]byte, 100))
+	f.Fuzz(func(t *testing.T, msg []byte) {
+		plain, err := bob.Decrypt(msg)
+		r, rErr := bob.NewReader(bytes.NewReader(msg))
+		if rErr == nil {
+			var streamed []byte
+			streamed, rErr = ioutil.ReadAll(r)
+			if rErr == nil && !bytes.Equal(streamed, plain) {
+				t.Error("Reader and Decrypt returned different plaintext")
+			}
+		}
+		if (err == nil) != (rErr == nil) {
+			t.Errorf("Reader and Decrypt disagree: %v, %v", err, rErr)
+		}
+		if err != nil {
+			return
+		}
+		again, err := bob.Encrypt(plain, msg[:IVSize])
+		if err != nil || !bytes.Equal(again, msg) {
+			t.Error("decrypted message did not round trip")
+		}
+	})
+}
+
+// FuzzRoundTrip checks that any plaintext survives both the whole-message and streaming APIs
+func FuzzRoundTrip(f *testing.F) {
+	alice, bob := testKeys(f)
+	plain, _ := hex.DecodeString(plainText)
+	f.Add(plain, uint8(7))
+	f.Add([]byte{}, uint8(1))
+	f.Fuzz(func(t *testing.T, data []byte, chunk uint8) {
+		if chunk == 0 {
+			chunk = 1
+		}
+		buf := bytes.NewBuffer(nil)
+		w, err := alice.NewWriter(buf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < len(data); i += int(chunk) {
+			end := i + int(chunk)
+			if end > len(data) {
+				end = len(data)
+			}
+			_, _ = w.Write(data[i:end])
+		}
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := bob.Decrypt(buf.Bytes())
+		if err != nil || !bytes.Equal(decrypted, data) {
+			t.Errorf("round trip failed: %v", err)
+		}
+	})
+}
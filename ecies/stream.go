@@ -0,0 +1,193 @@
+package ecies
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+)
+
+// Writer encrypts a stream, the output is the same as Encrypt. Close must be called to write the final block and
+// the hmac, it does not close the underlying writer.
+type Writer struct {
+	w      io.Writer
+	mode   cipher.BlockMode
+	mac    hash.Hash
+	buf    []byte
+	closed bool
+}
+
+// NewWriter returns a Writer that encrypts to w, if iv is nil a random IV is used. The IV is written immediately.
+func (k *Keys) NewWriter(w io.Writer, iv []byte) (*Writer, error) {
+	iv, err := checkIV(iv)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(k.Encryption[:])
+	if err != nil {
+		return nil, err
+	}
+	sw := &Writer{
+		w:    w,
+		mode: cipher.NewCBCEncrypter(block, iv),
+		mac:  hmac.New(sha256.New, k.Mac[:]),
+	}
+	if err = sw.write(iv); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+// Write encrypts and writes all complete blocks, any remainder is held until the next Write or Close.
+func (sw *Writer) Write(p []byte) (int, error) {
+	if sw.closed {
+		return 0, errors.New("ecies: write to closed writer")
+	}
+	sw.buf = append(sw.buf, p...)
+	n := len(sw.buf) - len(sw.buf)%aes.BlockSize
+	if n == 0 {
+		return len(p), nil
+	}
+	out := make([]byte, n)
+	sw.mode.CryptBlocks(out, sw.buf[:n])
+	sw.buf = append(sw.buf[:0], sw.buf[n:]...)
+	if err := sw.write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close pads and writes the final block, followed by the hmac
+func (sw *Writer) Close() error {
+	if sw.closed {
+		return nil
+	}
+	sw.closed = true
+	final := pad(sw.buf)
+	sw.mode.CryptBlocks(final, final)
+	if err := sw.write(final); err != nil {
+		return err
+	}
+	_, err := sw.w.Write(sw.mac.Sum(nil))
+	return err
+}
+
+func (sw *Writer) write(b []byte) error {
+	sw.mac.Write(b)
+	_, err := sw.w.Write(b)
+	return err
+}
+
+// Reader decrypts a stream created by Writer or Encrypt.
+//
+// Important: the hmac can only be checked at the end of the stream, so plaintext returned before Read returns
+// io.EOF has not been authenticated. If Read returns any other error, including ErrInvalidMac, everything read so far
+// must be discarded. Use Decrypt if the message is small enough to hold in memory.
+type Reader struct {
+	r       io.Reader
+	mode    cipher.BlockMode
+	mac     hash.Hash
+	pending []byte
+	out     []byte
+	chunk   []byte
+	done    bool
+	err     error
+}
+
+// NewReader returns a Reader that decrypts r, the IV is read immediately.
+func (k *Keys) NewReader(r io.Reader) (*Reader, error) {
+	iv := make([]byte, IVSize)
+	if _, err := io.ReadFull(r, iv); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrMessageTooShort
+		}
+		return nil, err
+	}
+	block, err := aes.NewCipher(k.Encryption[:])
+	if err != nil {
+		return nil, err
+	}
+	sr := &Reader{
+		r:     r,
+		mode:  cipher.NewCBCDecrypter(block, iv),
+		mac:   hmac.New(sha256.New, k.Mac[:]),
+		chunk: make([]byte, 32*1024),
+	}
+	sr.mac.Write(iv)
+	return sr, nil
+}
+
+// Read returns decrypted plaintext, see the warning on Reader about unauthenticated output.
+func (sr *Reader) Read(p []byte) (int, error) {
+	for len(sr.out) == 0 {
+		if sr.err != nil {
+			return 0, sr.err
+		}
+		if sr.done {
+			return 0, io.EOF
+		}
+		sr.fill()
+	}
+	n := copy(p, sr.out)
+	sr.out = sr.out[n:]
+	return n, nil
+}
+
+// fill reads from the underlying reader, always holding back the last block and the hmac until the end of the stream
+func (sr *Reader) fill() {
+	n, err := sr.r.Read(sr.chunk)
+	sr.pending = append(sr.pending, sr.chunk[:n]...)
+	if err == io.EOF {
+		sr.finish()
+		return
+	} else if err != nil {
+		sr.err = err
+		return
+	}
+	avail := len(sr.pending) - MacSize - aes.BlockSize
+	if avail < aes.BlockSize {
+		return
+	}
+	avail -= avail % aes.BlockSize
+	sr.decrypt(sr.pending[:avail])
+	sr.pending = append(sr.pending[:0], sr.pending[avail:]...)
+}
+
+func (sr *Reader) finish() {
+	sr.done = true
+	if len(sr.pending) < aes.BlockSize+MacSize {
+		sr.err = ErrMessageTooShort
+		return
+	}
+	if (len(sr.pending)-MacSize)%aes.BlockSize != 0 {
+		sr.err = ErrMessageLength
+		return
+	}
+	body := sr.pending[:len(sr.pending)-MacSize]
+	sr.mac.Write(body)
+	if !hmac.Equal(sr.mac.Sum(nil), sr.pending[len(sr.pending)-MacSize:]) {
+		sr.err = ErrInvalidMac
+		sr.out = nil
+		return
+	}
+	last := make([]byte, len(body))
+	sr.mode.CryptBlocks(last, body)
+	plain, err := unpad(last)
+	if err != nil {
+		sr.err = err
+		sr.out = nil
+		return
+	}
+	sr.out = append(sr.out, plain...)
+	sr.pending = nil
+}
+
+func (sr *Reader) decrypt(b []byte) {
+	sr.mac.Write(b)
+	plain := make([]byte, len(b))
+	sr.mode.CryptBlocks(plain, b)
+	sr.out = append(sr.out, plain...)
+}
@@ -1,9 +1,13 @@
 package eos
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
+
+	"github.com/tidwall/gjson"
 )
 
 // see: libraries/chain/contracts/abi_serializer.cpp:53...
@@ -18,6 +22,8 @@ type ABI struct {
 	ErrorMessages    []ABIErrorMessage `json:"error_messages,omitempty"`
 	Extensions       []*Extension      `json:"abi_extensions,omitempty"`
 	Variants         []VariantDef      `json:"variants,omitempty" eos:"binary_extension"`
+	ActionResults    []ActionResultDef `json:"action_results,omitempty" eos:"binary_extension,omitempty"`
+	KVTables         KVTables          `json:"kv_tables,omitempty" eos:"binary_extension,omitempty"`
 }
 
 func NewABI(r io.Reader) (*ABI, error) {
@@ -67,6 +73,24 @@ func (a *ABI) VariantForName(name string) *VariantDef {
 	return nil
 }
 
+func (a *ABI) ActionResultForName(name ActionName) *ActionResultDef {
+	for _, s := range a.ActionResults {
+		if s.Name == name {
+			return &s
+		}
+	}
+	return nil
+}
+
+func (a *ABI) KVTableForName(name TableName) *KVTableDef {
+	for _, s := range a.KVTables {
+		if s.Name == name {
+			return &s
+		}
+	}
+	return nil
+}
+
 func (a *ABI) TypeNameForNewTypeName(typeName string) (resolvedTypeName string, isAlias bool) {
 	for _, t := range a.Types {
 		if t.NewTypeName == typeName {
@@ -113,6 +137,129 @@ type VariantDef struct {
 	Types []string `json:"types,omitempty"`
 }
 
+// ActionResultDef defines the type returned by an action. See eosio::abi/1.2
+type ActionResultDef struct {
+	Name       ActionName `json:"name"`
+	ResultType string     `json:"result_type"`
+}
+
+// KVTables holds the key-value table definitions from an eosio::abi/1.2 ABI. In JSON it is an object keyed by table
+// name, and it is serialized as a map (sorted by name) in the binary form.
+type KVTables []KVTableDef
+
+// KVTableDef defines a key-value table. See eosio::abi/1.2
+type KVTableDef struct {
+	Name             TableName          `json:"-"`
+	Type             string             `json:"type"`
+	PrimaryIndex     PrimaryKeyIndexDef `json:"primary_index"`
+	SecondaryIndices SecondaryIndices   `json:"secondary_indices"`
+}
+
+// PrimaryKeyIndexDef is the primary index for a kv table
+type PrimaryKeyIndexDef struct {
+	Name Name   `json:"name"`
+	Type string `json:"type"`
+}
+
+// SecondaryIndices holds the secondary indexes for a kv table. Like KVTables it is an object keyed by index name
+// in JSON, and a map in the binary form.
+type SecondaryIndices []SecondaryIndexDef
+
+// SecondaryIndexDef is a secondary index for a kv table
+type SecondaryIndexDef struct {
+	Name Name   `json:"-"`
+	Type string `json:"type"`
+}
+
+func (k KVTables) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBufferString("{")
+	for i, t := range k {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		name, _ := json.Marshal(t.Name)
+		def, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteString(":")
+		buf.Write(def)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+func (k *KVTables) UnmarshalJSON(data []byte) error {
+	tables := make(KVTables, 0)
+	var err error
+	gjson.ParseBytes(data).ForEach(func(key, value gjson.Result) bool {
+		t := KVTableDef{}
+		if err = json.Unmarshal([]byte(value.Raw), &t); err != nil {
+			return false
+		}
+		t.Name = TableName(key.String())
+		tables = append(tables, t)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(tables, func(i, j int) bool {
+		return nameLess(string(tables[i].Name), string(tables[j].Name))
+	})
+	*k = tables
+	return nil
+}
+
+func (s SecondaryIndices) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBufferString("{")
+	for i, idx := range s {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		name, _ := json.Marshal(idx.Name)
+		def, err := json.Marshal(idx)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteString(":")
+		buf.Write(def)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+func (s *SecondaryIndices) UnmarshalJSON(data []byte) error {
+	indices := make(SecondaryIndices, 0)
+	var err error
+	gjson.ParseBytes(data).ForEach(func(key, value gjson.Result) bool {
+		idx := SecondaryIndexDef{}
+		if err = json.Unmarshal([]byte(value.Raw), &idx); err != nil {
+			return false
+		}
+		idx.Name = Name(key.String())
+		indices = append(indices, idx)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return nameLess(string(indices[i].Name), string(indices[j].Name))
+	})
+	*s = indices
+	return nil
+}
+
+// nameLess orders names the same way as the flat_map used by nodeos, by their uint64 value
+func nameLess(a string, b string) bool {
+	na, _ := StringToName(a)
+	nb, _ := StringToName(b)
+	return na < nb
+}
+
 // ClausePair represents clauses, related to Ricardian Contracts.
 type ClausePair struct {
 	ID   string `json:"id"`
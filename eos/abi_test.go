@@ -81,6 +81,51 @@ func TestABI_ReadPacked_V1_1(t *testing.T) {
 	assert.JSONEq(t, systemABIV1_1, string(actualJSON))
 }
 
+func TestABISerialization_V1_2(t *testing.T) {
+	abiJSON := `{
+		"version": "eosio::abi/1.2",
+		"structs": [{"name": "row", "base": "", "fields": [{"name": "id", "type": "uint64"}, {"name": "owner", "type": "name"}, {"name": "memo", "type": "string$"}]}],
+		"actions": [{"name": "getrow", "type": "row", "ricardian_contract": ""}],
+		"action_results": [{"name": "getrow", "result_type": "row"}],
+		"kv_tables": {
+			"rows": {
+				"type": "row",
+				"primary_index": {"name": "id", "type": "uint64"},
+				"secondary_indices": {"owner": {"type": "name"}, "bymemo": {"type": "string"}}
+			},
+			"aaa": {"type": "row", "primary_index": {"name": "id", "type": "uint64"}, "secondary_indices": {}}
+		}
+	}`
+
+	var abiDef ABI
+	require.NoError(t, json.Unmarshal([]byte(abiJSON), &abiDef))
+	require.Len(t, abiDef.KVTables, 2)
+	assert.Equal(t, TableName("aaa"), abiDef.KVTables[0].Name, "kv tables should be sorted by name value")
+	kv := abiDef.KVTableForName("rows")
+	require.NotNil(t, kv)
+	assert.Equal(t, Name("bymemo"), kv.SecondaryIndices[0].Name)
+	assert.Equal(t, "row", abiDef.ActionResultForName("getrow").ResultType)
+
+	bin, err := MarshalBinary(abiDef)
+	require.NoError(t, err)
+
+	var decoded ABI
+	require.NoError(t, UnmarshalBinary(bin, &decoded))
+	actualJSON, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, abiJSON, string(actualJSON))
+
+	// an ABI without 1.2 features should not gain trailing extensions
+	decoded.ActionResults = nil
+	decoded.KVTables = nil
+	withoutExtensions, err := MarshalBinary(decoded)
+	require.NoError(t, err)
+	assert.True(t, len(withoutExtensions) < len(bin))
+	var older ABI
+	require.NoError(t, UnmarshalBinary(withoutExtensions, &older))
+	assert.Len(t, older.KVTables, 0)
+}
+
 var testSystemABIRaw = `{
   "version": "__VERSION__",
   "types": [{
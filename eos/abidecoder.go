@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 	"go.uber.org/zap"
 )
@@ -37,6 +38,46 @@ func (a *ABI) DecodeTableRowTyped(tableType string, data []byte) ([]byte, error)
 	return a.decode(binaryDecoder, tableType)
 }
 
+// DecodeKVTableRow decodes a row from an eosio::abi/1.2 key-value table
+func (a *ABI) DecodeKVTableRow(tableName TableName, data []byte) ([]byte, error) {
+	tbl := a.KVTableForName(tableName)
+	if tbl == nil {
+		return nil, fmt.Errorf("kv table name %s not found in abi", tableName)
+	}
+
+	return a.decodeType(NewDecoder(data), tbl.Type)
+}
+
+// DecodeActionResult decodes the return value of an action, the result type may be a struct or any built-in type.
+func (a *ABI) DecodeActionResult(actionName ActionName, data []byte) ([]byte, error) {
+	result := a.ActionResultForName(actionName)
+	if result == nil {
+		return nil, fmt.Errorf("action result for %s not found in abi", actionName)
+	}
+
+	return a.decodeType(NewDecoder(data), result.ResultType)
+}
+
+// decodeType decodes a single value of any type, unlike decode it is not limited to structs
+func (a *ABI) decodeType(binaryDecoder *Decoder, typeName string) ([]byte, error) {
+	fieldType, isOptional, isArray, _ := analyzeFieldType(typeName)
+	resolvedType, _ := a.TypeNameForNewTypeName(fieldType)
+	if !isOptional && !isArray && a.StructForName(resolvedType) != nil {
+		return a.decode(binaryDecoder, resolvedType)
+	}
+
+	resultingJSON, err := a.decodeField(binaryDecoder, "value", resolvedType, isOptional, isArray, []byte{})
+	if err != nil {
+		return nil, err
+	}
+
+	value := gjson.GetBytes(resultingJSON, "value")
+	if !value.Exists() {
+		return []byte("null"), nil
+	}
+	return []byte(value.Raw), nil
+}
+
 func (a *ABI) decode(binaryDecoder *Decoder, structName string) ([]byte, error) {
 	abiDecoderLog.Debug("decode struct", zap.String("name", structName))
 
@@ -254,7 +295,7 @@ func (a *ABI) read(binaryDecoder *Decoder, fieldName string, fieldType string, j
 	case "block_timestamp_type":
 		value, err = binaryDecoder.ReadBlockTimestamp()
 		if err == nil {
-			value = value.(BlockTimestamp).Time.UTC().Format("2006-01-02T15:04:05")
+			value = value.(BlockTimestamp).Time.UTC().Format("2006-01-02T15:04:05.999")
 		}
 	case "name":
 		value, err = binaryDecoder.ReadName()
@@ -302,18 +343,20 @@ func (a *ABI) read(binaryDecoder *Decoder, fieldName string, fieldType string, j
 	return sjson.SetBytes(json, fieldName, value)
 }
 
+// analyzeFieldType strips the type modifiers, a binary extension may also be optional or an array, ie: "string[]$"
 func analyzeFieldType(fieldType string) (typeName string, isOptional bool, isArray bool, isBinaryExtension bool) {
-	if strings.HasSuffix(fieldType, "?") {
-		return fieldType[0 : len(fieldType)-1], true, false, false
+	typeName = fieldType
+	if strings.HasSuffix(typeName, "$") {
+		typeName, isBinaryExtension = typeName[0:len(typeName)-1], true
 	}
 
-	if strings.HasSuffix(fieldType, "$") {
-		return fieldType[0 : len(fieldType)-1], false, false, true
+	if strings.HasSuffix(typeName, "?") {
+		typeName, isOptional = typeName[0:len(typeName)-1], true
 	}
 
-	if strings.HasSuffix(fieldType, "[]") {
-		return fieldType[0 : len(fieldType)-2], false, true, false
+	if strings.HasSuffix(typeName, "[]") {
+		typeName, isArray = typeName[0:len(typeName)-2], true
 	}
 
-	return fieldType, false, false, false
+	return
 }
@@ -786,6 +786,9 @@ func TestABIDecoder_analyseFieldType(t *testing.T) {
 		{"field.type.1?", "field.type.1", true, false, false},
 		{"field.type.1[]", "field.type.1", false, true, false},
 		{"field.type.1$", "field.type.1", false, false, true},
+		{"field.type.1[]$", "field.type.1", false, true, true},
+		{"field.type.1?$", "field.type.1", true, false, true},
+		{"field.type.1[]?", "field.type.1", true, true, false},
 	}
 
 	for i, test := range testCases {
@@ -807,3 +810,168 @@ func HexString(input string) []byte {
 
 	return buffer
 }
+
+func TestABI_RoundTripBuiltInTypes(t *testing.T) {
+	testCases := []struct {
+		typeName string
+		json     string
+	}{
+		{"int8", "-128"},
+		{"uint8", "255"},
+		{"int16", "-32768"},
+		{"uint16", "65535"},
+		{"int32", "-2147483648"},
+		{"uint32", "4294967295"},
+		{"int64", `"-9223372036854775808"`},
+		{"uint64", `"18446744073709551615"`},
+		{"int128", `"0x01000000000000000200000000000000"`},
+		{"uint128", `"0xffffffffffffffffffffffffffffffff"`},
+		{"varint32", "-2147483648"},
+		{"varuint32", "4294967295"},
+		{"float32", "1.5"},
+		{"float64", "-3.25"},
+		{"float128", `"0x01000000000000000200000000000000"`},
+		{"bool", "true"},
+		{"time_point", `"2018-11-01T15:13:07.001"`},
+		{"time_point_sec", `"2023-04-14T10:55:53"`},
+		{"block_timestamp_type", `"2018-09-05T12:48:54.5"`},
+		{"name", `"eoscanadacom"`},
+		{"bytes", `"0102abcdef"`},
+		{"string", `"a string"`},
+		{"checksum160", `"0102030405060708090a0b0c0d0e0f1011121314"`},
+		{"checksum256", `"0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"`},
+		{"checksum512", `"0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f200102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"`},
+		{"public_key", `"FIO5UhWBMYKPPzb4tigorbnrH9Ft7mogW1MmvViaHJkBif2kSa1f4"`},
+		{"signature", `"SIG_K1_K96L1au4xFJg5edn6qBK6UDbSsC2RKsMs4cXCA2LoCPZxBDMXehdZFWPh1GeRhzGoQjBwNK2eBmUXf4L8SBApL69pGdUJm"`},
+		{"symbol", `"9,FIO"`},
+		{"symbol_code", "4540996"},
+		{"asset", `"10.000000000 FIO"`},
+		{"extended_asset", `{"asset":"0.0010 EOS","Contract":"eoscanadacom"}`},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.typeName, func(t *testing.T) {
+			abi := &ABI{
+				Structs: []StructDef{{Name: "root", Fields: []FieldDef{
+					{Name: "value", Type: c.typeName},
+					{Name: "optional", Type: c.typeName + "?"},
+					{Name: "array", Type: c.typeName + "[]"},
+					{Name: "extension", Type: c.typeName + "$"},
+				}}},
+			}
+			in := fmt.Sprintf(`{"value":%s,"optional":%s,"array":[%s,%s],"extension":%s}`, c.json, c.json, c.json, c.json, c.json)
+
+			var buffer bytes.Buffer
+			require.NoError(t, abi.encode(NewEncoder(&buffer), "root", []byte(in)))
+			out, err := abi.decode(NewDecoder(buffer.Bytes()), "root")
+			require.NoError(t, err)
+			assert.JSONEq(t, in, string(out))
+		})
+	}
+}
+
+func TestABI_RoundTripBinaryExtension(t *testing.T) {
+	abi := &ABI{
+		Structs: []StructDef{{Name: "root", Fields: []FieldDef{
+			{Name: "a", Type: "string"},
+			{Name: "b", Type: "uint8$"},
+			{Name: "c", Type: "string[]$"},
+		}}},
+	}
+
+	var buffer bytes.Buffer
+	require.NoError(t, abi.encode(NewEncoder(&buffer), "root", []byte(`{"a":"a"}`)))
+	assert.Equal(t, "0161", hex.EncodeToString(buffer.Bytes()))
+	out, err := abi.decode(NewDecoder(buffer.Bytes()), "root")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":"a"}`, string(out))
+
+	buffer.Reset()
+	require.NoError(t, abi.encode(NewEncoder(&buffer), "root", []byte(`{"a":"a","b":1}`)))
+	assert.Equal(t, "016101", hex.EncodeToString(buffer.Bytes()))
+
+	buffer.Reset()
+	require.NoError(t, abi.encode(NewEncoder(&buffer), "root", []byte(`{"a":"a","b":1,"c":["c"]}`)))
+	out, err = abi.decode(NewDecoder(buffer.Bytes()), "root")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":"a","b":1,"c":["c"]}`, string(out))
+
+	err = abi.encode(NewEncoder(&buffer), "root", []byte(`{"a":"a","c":["c"]}`))
+	assert.Error(t, err)
+}
+
+func TestABI_RoundTripVariant(t *testing.T) {
+	abi := &ABI{
+		Structs: []StructDef{
+			{Name: "root", Fields: []FieldDef{{Name: "v", Type: "choice"}}},
+			{Name: "base", Fields: []FieldDef{{Name: "id", Type: "uint64"}}},
+			{Name: "child", Base: "base", Fields: []FieldDef{{Name: "memo", Type: "string"}}},
+		},
+		Variants: []VariantDef{{Name: "choice", Types: []string{"uint8", "child"}}},
+	}
+
+	var buffer bytes.Buffer
+	require.NoError(t, abi.encode(NewEncoder(&buffer), "root", []byte(`{"v":["child",{"id":"1","memo":"m"}]}`)))
+	assert.Equal(t, "010100000000000000016d", hex.EncodeToString(buffer.Bytes()))
+	out, err := abi.decode(NewDecoder(buffer.Bytes()), "root")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"v":{"id":1,"memo":"m"}}`, string(out))
+
+	buffer.Reset()
+	require.NoError(t, abi.encode(NewEncoder(&buffer), "root", []byte(`{"v":["uint8",7]}`)))
+	assert.Equal(t, "0007", hex.EncodeToString(buffer.Bytes()))
+
+	// the type can't be guessed from a bare value
+	assert.Error(t, abi.encode(NewEncoder(&buffer), "root", []byte(`{"v":7}`)))
+	assert.Error(t, abi.encode(NewEncoder(&buffer), "root", []byte(`{"v":["uint16",7]}`)))
+}
+
+func TestABI_ActionResultAndKVTable(t *testing.T) {
+	abi := &ABI{
+		Version: "eosio::abi/1.2",
+		Structs: []StructDef{{Name: "row", Fields: []FieldDef{
+			{Name: "id", Type: "uint64"},
+			{Name: "owner", Type: "name"},
+		}}},
+		ActionResults: []ActionResultDef{
+			{Name: "getcount", ResultType: "uint32"},
+			{Name: "getrow", ResultType: "row"},
+			{Name: "getnames", ResultType: "name[]"},
+			{Name: "maybe", ResultType: "string?"},
+		},
+		KVTables: KVTables{{Name: "rows", Type: "row", PrimaryIndex: PrimaryKeyIndexDef{Name: "id", Type: "uint64"}}},
+	}
+
+	testCases := []struct {
+		action ActionName
+		json   string
+		hex    string
+	}{
+		{"getcount", "42", "2a000000"},
+		{"getrow", `{"id":1,"owner":"eosio"}`, "01000000000000000000000000ea3055"},
+		{"getnames", `["eosio"]`, "010000000000ea3055"},
+		{"maybe", "null", "00"},
+	}
+	for _, c := range testCases {
+		t.Run(string(c.action), func(t *testing.T) {
+			bin, err := abi.EncodeActionResult(c.action, []byte(c.json))
+			require.NoError(t, err)
+			assert.Equal(t, c.hex, hex.EncodeToString(bin))
+			out, err := abi.DecodeActionResult(c.action, bin)
+			require.NoError(t, err)
+			assert.JSONEq(t, c.json, string(out))
+		})
+	}
+
+	_, err := abi.DecodeActionResult("missing", []byte{})
+	assert.Error(t, err)
+
+	bin, err := abi.EncodeKVTableRow("rows", []byte(`{"id":1,"owner":"eosio"}`))
+	require.NoError(t, err)
+	out, err := abi.DecodeKVTableRow("rows", bin)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":1,"owner":"eosio"}`, string(out))
+
+	_, err = abi.DecodeKVTableRow("missing", bin)
+	assert.Error(t, err)
+}
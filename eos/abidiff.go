@@ -0,0 +1,313 @@
+package eos
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ABIChangeKind describes what happened to an element of an ABI between two versions.
+type ABIChangeKind string
+
+const (
+	ABIAdded   ABIChangeKind = "added"
+	ABIRemoved ABIChangeKind = "removed"
+	ABIChanged ABIChangeKind = "changed"
+)
+
+// ABIChange is a single difference found by DiffABI. Element is one of "version", "type", "struct", "field",
+// "action", "table", "variant", "action_result" or "kv_table". Fields are named as "struct.field".
+type ABIChange struct {
+	Element  string        `json:"element"`
+	Name     string        `json:"name"`
+	Kind     ABIChangeKind `json:"kind"`
+	Old      string        `json:"old,omitempty"`
+	New      string        `json:"new,omitempty"`
+	Breaking bool          `json:"breaking"`
+	Reason   string        `json:"reason,omitempty"`
+}
+
+func (c ABIChange) String() string {
+	compat := "compatible"
+	if c.Breaking {
+		compat = "BREAKING"
+	}
+	s := fmt.Sprintf("[%s] %s %s %s", compat, c.Kind, c.Element, c.Name)
+	switch {
+	case c.Old != "" && c.New != "":
+		s += fmt.Sprintf(": %q -> %q", c.Old, c.New)
+	case c.Old != "":
+		s += fmt.Sprintf(": %q", c.Old)
+	case c.New != "":
+		s += fmt.Sprintf(": %q", c.New)
+	}
+	if c.Reason != "" {
+		s += " (" + c.Reason + ")"
+	}
+	return s
+}
+
+// ABIDiff is the result of comparing two ABIs.
+type ABIDiff struct {
+	Changes []ABIChange `json:"changes"`
+}
+
+// Breaking returns true if any change would prevent existing binary data or actions from being decoded.
+func (d *ABIDiff) Breaking() bool {
+	for _, c := range d.Changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Empty is true if the ABIs are equivalent.
+func (d *ABIDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+func (d *ABIDiff) String() string {
+	lines := make([]string, len(d.Changes))
+	for i := range d.Changes {
+		lines[i] = d.Changes[i].String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (d *ABIDiff) add(element string, name string, kind ABIChangeKind, old string, new string, breaking bool, reason string) {
+	d.Changes = append(d.Changes, ABIChange{
+		Element:  element,
+		Name:     name,
+		Kind:     kind,
+		Old:      old,
+		New:      new,
+		Breaking: breaking,
+		Reason:   reason,
+	})
+}
+
+// DiffABI compares two ABIs and reports added, removed and changed types, structs, fields, actions, tables and
+// variants. Each change is classified as breaking or binary-compatible: additions are compatible, removals and type
+// changes are breaking, and fields appended to a struct are only compatible when they are binary extensions ($).
+func DiffABI(old *ABI, new *ABI) *ABIDiff {
+	d := &ABIDiff{Changes: make([]ABIChange, 0)}
+	if old == nil {
+		old = &ABI{}
+	}
+	if new == nil {
+		new = &ABI{}
+	}
+	if old.Version != new.Version {
+		d.add("version", "", ABIChanged, old.Version, new.Version, false, "")
+	}
+	diffTypes(d, old, new)
+	diffStructs(d, old, new)
+	diffActions(d, old, new)
+	diffTables(d, old, new)
+	diffVariants(d, old, new)
+	diffActionResults(d, old, new)
+	diffKVTables(d, old, new)
+	return d
+}
+
+func diffTypes(d *ABIDiff, old *ABI, new *ABI) {
+	newTypes := make(map[string]string)
+	for _, t := range new.Types {
+		newTypes[t.NewTypeName] = t.Type
+	}
+	seen := make(map[string]bool)
+	for _, t := range old.Types {
+		seen[t.NewTypeName] = true
+		n, ok := newTypes[t.NewTypeName]
+		switch {
+		case !ok:
+			d.add("type", t.NewTypeName, ABIRemoved, t.Type, "", true, "")
+		case n != t.Type:
+			d.add("type", t.NewTypeName, ABIChanged, t.Type, n, true, "alias points to a different type")
+		}
+	}
+	for _, t := range new.Types {
+		if !seen[t.NewTypeName] {
+			d.add("type", t.NewTypeName, ABIAdded, "", t.Type, false, "")
+		}
+	}
+}
+
+func diffStructs(d *ABIDiff, old *ABI, new *ABI) {
+	seen := make(map[string]bool)
+	for _, o := range old.Structs {
+		seen[o.Name] = true
+		n := new.StructForName(o.Name)
+		if n == nil {
+			d.add("struct", o.Name, ABIRemoved, "", "", true, "")
+			continue
+		}
+		if o.Base != n.Base {
+			d.add("struct", o.Name, ABIChanged, o.Base, n.Base, true, "base struct changed")
+		}
+		diffFields(d, o, *n)
+	}
+	for _, n := range new.Structs {
+		if !seen[n.Name] {
+			d.add("struct", n.Name, ABIAdded, "", "", false, "")
+		}
+	}
+}
+
+// diffFields compares fields positionally, since that is how they are serialized.
+func diffFields(d *ABIDiff, old StructDef, new StructDef) {
+	for i, o := range old.Fields {
+		name := old.Name + "." + o.Name
+		if i >= len(new.Fields) {
+			d.add("field", name, ABIRemoved, o.Type, "", true, "")
+			continue
+		}
+		n := new.Fields[i]
+		if o.Name != n.Name {
+			if o.Type == n.Type {
+				d.add("field", name, ABIChanged, o.Name, n.Name, false, "renamed, binary layout unchanged")
+				continue
+			}
+			d.add("field", name, ABIChanged, o.Name+" "+o.Type, n.Name+" "+n.Type, true, "field replaced")
+			continue
+		}
+		if o.Type != n.Type {
+			if strings.TrimSuffix(o.Type, "$") == strings.TrimSuffix(n.Type, "$") {
+				// only adding the flag to the last field is compatible, removing it means data serialized without
+				// the field no longer decodes
+				if i == len(old.Fields)-1 && strings.HasSuffix(n.Type, "$") {
+					d.add("field", name, ABIChanged, o.Type, n.Type, false, "last field made a binary extension")
+					continue
+				}
+				d.add("field", name, ABIChanged, o.Type, n.Type, true, "binary extension flag changed")
+				continue
+			}
+			d.add("field", name, ABIChanged, o.Type, n.Type, true, "type changed")
+		}
+	}
+	for i := len(old.Fields); i < len(new.Fields); i++ {
+		n := new.Fields[i]
+		name := new.Name + "." + n.Name
+		if strings.HasSuffix(n.Type, "$") {
+			d.add("field", name, ABIAdded, "", n.Type, false, "appended binary extension")
+			continue
+		}
+		d.add("field", name, ABIAdded, "", n.Type, true, "appended field is not a binary extension")
+	}
+}
+
+func diffActions(d *ABIDiff, old *ABI, new *ABI) {
+	seen := make(map[ActionName]bool)
+	for _, o := range old.Actions {
+		seen[o.Name] = true
+		n := new.ActionForName(o.Name)
+		switch {
+		case n == nil:
+			d.add("action", string(o.Name), ABIRemoved, o.Type, "", true, "")
+		case n.Type != o.Type:
+			d.add("action", string(o.Name), ABIChanged, o.Type, n.Type, true, "action type changed")
+		case n.RicardianContract != o.RicardianContract:
+			d.add("action", string(o.Name), ABIChanged, "", "", false, "ricardian contract changed")
+		}
+	}
+	for _, n := range new.Actions {
+		if !seen[n.Name] {
+			d.add("action", string(n.Name), ABIAdded, "", n.Type, false, "")
+		}
+	}
+}
+
+func diffTables(d *ABIDiff, old *ABI, new *ABI) {
+	seen := make(map[TableName]bool)
+	for _, o := range old.Tables {
+		seen[o.Name] = true
+		n := new.TableForName(o.Name)
+		switch {
+		case n == nil:
+			d.add("table", string(o.Name), ABIRemoved, o.Type, "", true, "")
+		case n.Type != o.Type:
+			d.add("table", string(o.Name), ABIChanged, o.Type, n.Type, true, "row type changed")
+		case n.IndexType != o.IndexType || strings.Join(n.KeyTypes, ",") != strings.Join(o.KeyTypes, ","):
+			d.add("table", string(o.Name), ABIChanged, o.IndexType, n.IndexType, true, "index changed")
+		}
+	}
+	for _, n := range new.Tables {
+		if !seen[n.Name] {
+			d.add("table", string(n.Name), ABIAdded, "", n.Type, false, "")
+		}
+	}
+}
+
+func diffVariants(d *ABIDiff, old *ABI, new *ABI) {
+	seen := make(map[string]bool)
+	for _, o := range old.Variants {
+		seen[o.Name] = true
+		n := new.VariantForName(o.Name)
+		if n == nil {
+			d.add("variant", o.Name, ABIRemoved, "", "", true, "")
+			continue
+		}
+		oldTypes, newTypes := strings.Join(o.Types, ","), strings.Join(n.Types, ",")
+		if oldTypes == newTypes {
+			continue
+		}
+		// the variant index is serialized, so appending types is safe but anything else is not.
+		if len(n.Types) > len(o.Types) && (len(o.Types) == 0 || strings.HasPrefix(newTypes, oldTypes+",")) {
+			d.add("variant", o.Name, ABIChanged, oldTypes, newTypes, false, "types appended")
+			continue
+		}
+		d.add("variant", o.Name, ABIChanged, oldTypes, newTypes, true, "types removed or reordered")
+	}
+	for _, n := range new.Variants {
+		if !seen[n.Name] {
+			d.add("variant", n.Name, ABIAdded, "", strings.Join(n.Types, ","), false, "")
+		}
+	}
+}
+
+func diffActionResults(d *ABIDiff, old *ABI, new *ABI) {
+	seen := make(map[ActionName]bool)
+	for _, o := range old.ActionResults {
+		seen[o.Name] = true
+		n := new.ActionResultForName(o.Name)
+		switch {
+		case n == nil:
+			d.add("action_result", string(o.Name), ABIRemoved, o.ResultType, "", true, "")
+		case n.ResultType != o.ResultType:
+			d.add("action_result", string(o.Name), ABIChanged, o.ResultType, n.ResultType, true, "result type changed")
+		}
+	}
+	for _, n := range new.ActionResults {
+		if !seen[n.Name] {
+			d.add("action_result", string(n.Name), ABIAdded, "", n.ResultType, false, "")
+		}
+	}
+}
+
+func diffKVTables(d *ABIDiff, old *ABI, new *ABI) {
+	indices := func(t *KVTableDef) string {
+		s := make([]string, 0)
+		for _, idx := range t.SecondaryIndices {
+			s = append(s, string(idx.Name)+":"+idx.Type)
+		}
+		return strings.Join(s, ",")
+	}
+	seen := make(map[TableName]bool)
+	for _, o := range old.KVTables {
+		seen[o.Name] = true
+		n := new.KVTableForName(o.Name)
+		switch {
+		case n == nil:
+			d.add("kv_table", string(o.Name), ABIRemoved, o.Type, "", true, "")
+		case n.Type != o.Type:
+			d.add("kv_table", string(o.Name), ABIChanged, o.Type, n.Type, true, "row type changed")
+		case n.PrimaryIndex != o.PrimaryIndex || indices(n) != indices(&o):
+			d.add("kv_table", string(o.Name), ABIChanged, indices(&o), indices(n), true, "index changed")
+		}
+	}
+	for _, n := range new.KVTables {
+		if !seen[n.Name] {
+			d.add("kv_table", string(n.Name), ABIAdded, "", n.Type, false, "")
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package eos
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const abiDiffOld = `{
+	"version": "eosio::abi/1.0",
+	"types": [{"new_type_name": "account_name", "type": "name"}, {"new_type_name": "gone", "type": "uint64"}],
+	"structs": [
+		{"name": "transfer", "base": "", "fields": [{"name": "from", "type": "account_name"}, {"name": "amount", "type": "uint64"}]},
+		{"name": "row", "base": "", "fields": [{"name": "id", "type": "uint64"}]},
+		{"name": "removed", "base": "", "fields": []}
+	],
+	"actions": [{"name": "transfer", "type": "transfer", "ricardian_contract": ""}],
+	"tables": [{"name": "rows", "index_type": "i64", "key_names": [], "key_types": [], "type": "row"}],
+	"variants": [{"name": "choice", "types": ["uint8", "string"]}]
+}`
+
+func TestDiffABI(t *testing.T) {
+	old, err := NewABI(strings.NewReader(abiDiffOld))
+	require.NoError(t, err)
+
+	same := DiffABI(old, old)
+	assert.True(t, same.Empty())
+	assert.False(t, same.Breaking())
+
+	compatible, err := NewABI(strings.NewReader(abiDiffOld))
+	require.NoError(t, err)
+	compatible.Version = "eosio::abi/1.1"
+	compatible.Structs[0].Fields = append(compatible.Structs[0].Fields, FieldDef{Name: "memo", Type: "string$"})
+	compatible.Structs[1].Fields[0].Name = "key"
+	compatible.Actions = append(compatible.Actions, ActionDef{Name: "newaction", Type: "row"})
+	compatible.Variants[0].Types = append(compatible.Variants[0].Types, "uint64")
+	compatible.ActionResults = []ActionResultDef{{Name: "transfer", ResultType: "uint64"}}
+	diff := DiffABI(old, compatible)
+	assert.False(t, diff.Breaking(), diff.String())
+	assert.Len(t, diff.Changes, 6)
+
+	breaking, err := NewABI(strings.NewReader(abiDiffOld))
+	require.NoError(t, err)
+	breaking.Types = breaking.Types[:1]
+	breaking.Structs = breaking.Structs[:2]
+	breaking.Structs[0].Fields[1].Type = "uint32"
+	breaking.Structs[1].Fields = append(breaking.Structs[1].Fields, FieldDef{Name: "extra", Type: "string"})
+	breaking.Tables[0].Type = "transfer"
+	breaking.Variants[0].Types = []string{"string", "uint8"}
+	diff = DiffABI(old, breaking)
+	assert.True(t, diff.Breaking())
+
+	expect := map[string]ABIChangeKind{
+		"type gone":             ABIRemoved,
+		"struct removed":        ABIRemoved,
+		"field transfer.amount": ABIChanged,
+		"field row.extra":       ABIAdded,
+		"table rows":            ABIChanged,
+		"variant choice":        ABIChanged,
+	}
+	require.Len(t, diff.Changes, len(expect), diff.String())
+	for _, c := range diff.Changes {
+		assert.True(t, c.Breaking, c.String())
+		assert.Equal(t, expect[c.Element+" "+c.Name], c.Kind, c.String())
+	}
+}
+
+func TestDiffABI_BinaryExtensionFlag(t *testing.T) {
+	old, err := NewABI(strings.NewReader(abiDiffOld))
+	require.NoError(t, err)
+
+	extension, err := NewABI(strings.NewReader(abiDiffOld))
+	require.NoError(t, err)
+	extension.Structs[0].Fields[1].Type = "uint64$"
+	diff := DiffABI(old, extension)
+	require.Len(t, diff.Changes, 1, diff.String())
+	assert.False(t, diff.Breaking(), "T to T$ on the last field is compatible")
+
+	// the reverse can't decode data serialized without the field
+	diff = DiffABI(extension, old)
+	require.Len(t, diff.Changes, 1, diff.String())
+	assert.True(t, diff.Breaking(), "T$ to T should be breaking")
+
+	// the flag is only allowed on trailing fields
+	notLast, err := NewABI(strings.NewReader(abiDiffOld))
+	require.NoError(t, err)
+	notLast.Structs[0].Fields[0].Type = "account_name$"
+	assert.True(t, DiffABI(old, notLast).Breaking())
+}
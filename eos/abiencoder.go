@@ -14,6 +14,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 )
 
 type ABIEncoder struct {
@@ -40,6 +41,67 @@ func (a *ABI) EncodeAction(actionName ActionName, json []byte) ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+// EncodeTableRowTyped encodes a struct by its type name, it is the inverse of DecodeTableRowTyped and is useful for
+// types that are not an action or table in the ABI.
+func (a *ABI) EncodeTableRowTyped(tableType string, json []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := NewEncoder(&buffer)
+	if err := a.encode(encoder, tableType, json); err != nil {
+		return nil, fmt.Errorf("encode %s: %s", tableType, err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// EncodeActionResult encodes the return value of an action, the result type may be a struct or any built-in type.
+func (a *ABI) EncodeActionResult(actionName ActionName, json []byte) ([]byte, error) {
+
+	result := a.ActionResultForName(actionName)
+	if result == nil {
+		return nil, fmt.Errorf("encode action result: action result for %s not found in abi", actionName)
+	}
+
+	var buffer bytes.Buffer
+	err := a.encodeType(NewEncoder(&buffer), result.ResultType, json)
+	if err != nil {
+		return nil, fmt.Errorf("encode action result: %s", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// EncodeKVTableRow encodes a row for an eosio::abi/1.2 key-value table
+func (a *ABI) EncodeKVTableRow(tableName TableName, json []byte) ([]byte, error) {
+
+	tbl := a.KVTableForName(tableName)
+	if tbl == nil {
+		return nil, fmt.Errorf("encode kv table row: kv table name %s not found in abi", tableName)
+	}
+
+	var buffer bytes.Buffer
+	err := a.encodeType(NewEncoder(&buffer), tbl.Type, json)
+	if err != nil {
+		return nil, fmt.Errorf("encode kv table row: %s", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// encodeType encodes a single value of any type, unlike encode it is not limited to structs
+func (a *ABI) encodeType(binaryEncoder *Encoder, typeName string, json []byte) error {
+	fieldType, isOptional, isArray, _ := analyzeFieldType(typeName)
+	resolvedType, _ := a.TypeNameForNewTypeName(fieldType)
+	if !isOptional && !isArray && a.StructForName(resolvedType) != nil {
+		return a.encode(binaryEncoder, resolvedType, json)
+	}
+
+	wrapped := []byte("{}")
+	if value := gjson.ParseBytes(json); value.Exists() && value.Type != gjson.Null {
+		var err error
+		if wrapped, err = sjson.SetRawBytes(wrapped, "value", []byte(value.Raw)); err != nil {
+			return err
+		}
+	}
+	return a.encodeField(binaryEncoder, "value", resolvedType, isOptional, isArray, wrapped)
+}
+
 func (a *ABI) encode(binaryEncoder *Encoder, structureName string, json []byte) error {
 	abiEncoderLog.Debug("abi encode struct", zap.String("name", structureName))
 
@@ -65,11 +127,24 @@ func (a *ABI) encodeFields(binaryEncoder *Encoder, fields []FieldDef, json []byt
 	defer func(prev *zap.Logger) { encoderLog = prev }(encoderLog)
 	encoderLog = encoderLog.Named("fields")
 
+	missingExtension := ""
 	for _, field := range fields {
 
 		abiEncoderLog.Debug("encode field", zap.String("name", field.Name), zap.String("type", field.Type))
 
-		fieldType, isOptional, isArray, _ := analyzeFieldType(field.Type)
+		fieldType, isOptional, isArray, isBinaryExtension := analyzeFieldType(field.Type)
+		if isBinaryExtension {
+			if !gjson.GetBytes(json, field.Name).Exists() {
+				abiEncoderLog.Debug("binary extension field not present", zap.String("name", field.Name))
+				if missingExtension == "" {
+					missingExtension = field.Name
+				}
+				continue
+			}
+			if missingExtension != "" {
+				return fmt.Errorf("encoding fields: binary extension [%s] is present but the preceding extension [%s] is not", field.Name, missingExtension)
+			}
+		}
 		typeName, isAlias := a.TypeNameForNewTypeName(fieldType)
 		fieldName := field.Name
 		if isAlias {
@@ -114,8 +189,10 @@ func (a *ABI) encodeField(binaryEncoder *Encoder, fieldName string, fieldType st
 		results := value.Array()
 		binaryEncoder.writeUVarInt(len(results))
 
-		for _, r := range results {
-			a.writeField(binaryEncoder, fieldName, fieldType, r)
+		for i, r := range results {
+			if err = a.writeField(binaryEncoder, fieldName, fieldType, r); err != nil {
+				return fmt.Errorf("encode field: [%s] index [%d]: %s", fieldName, i, err)
+			}
 		}
 
 		return nil
@@ -128,15 +205,15 @@ func (a *ABI) writeField(binaryEncoder *Encoder, fieldName string, fieldType str
 
 	abiEncoderLog.Debug("write field", zap.String("name", fieldName), zap.String("type", fieldType), zap.String("json", value.Raw))
 
+	if variant := a.VariantForName(fieldType); variant != nil {
+		return a.writeVariant(binaryEncoder, fieldName, variant, value)
+	}
+
 	structure := a.StructForName(fieldType)
 	if structure != nil {
 		abiEncoderLog.Debug("field is a struct", zap.String("name", fieldName))
 
-		err := a.encodeFields(binaryEncoder, structure.Fields, []byte(value.Raw))
-		if err != nil {
-			return err
-		}
-		return nil
+		return a.encode(binaryEncoder, structure.Name, []byte(value.Raw))
 	}
 
 	var object interface{}
@@ -165,18 +242,30 @@ func (a *ABI) writeField(binaryEncoder *Encoder, fieldName string, fieldType str
 			return err
 		}
 		object = uint16(i)
-	case "int32", "varint32":
+	case "int32":
 		i, err := valueToInt(fieldName, value, 32)
 		if err != nil {
 			return err
 		}
 		object = int32(i)
-	case "uint32", "varuint32":
+	case "varint32":
+		i, err := valueToInt(fieldName, value, 32)
+		if err != nil {
+			return err
+		}
+		object = Varint32(i)
+	case "uint32":
 		i, err := valueToUint(fieldName, value, 32)
 		if err != nil {
 			return err
 		}
 		object = uint32(i)
+	case "varuint32":
+		i, err := valueToUint(fieldName, value, 32)
+		if err != nil {
+			return err
+		}
+		object = Varuint32(i)
 	case "int64":
 		var in Int64
 		if err := json.Unmarshal([]byte(value.Raw), &in); err != nil {
@@ -226,17 +315,21 @@ func (a *ABI) writeField(binaryEncoder *Encoder, fieldName string, fieldType str
 		if err != nil {
 			return fmt.Errorf("writing field: time_point_sec: %s", err)
 		}
-		object = TimePointSec(t.UTC().Second())
+		object = TimePointSec(t.Unix())
 	case "time_point":
 		t, err := time.Parse("2006-01-02T15:04:05.999", value.Str)
 		if err != nil {
 			return fmt.Errorf("writing field: time_point: %s", err)
 		}
-		object = TimePoint(t.UTC().Nanosecond() / int(time.Millisecond))
+		object = TimePoint(t.UnixNano() / int64(time.Microsecond))
 	case "block_timestamp_type":
 		t, err := time.Parse("2006-01-02T15:04:05.999999-07:00", value.Str)
 		if err != nil {
-			return fmt.Errorf("writing field: block_timestamp_type: %s", err)
+			// nodeos (and DecodeAction) use UTC without an offset
+			var e error
+			if t, e = time.Parse("2006-01-02T15:04:05", value.Str); e != nil {
+				return fmt.Errorf("writing field: block_timestamp_type: %s", err)
+			}
 		}
 		object = BlockTimestamp{
 			Time: t,
@@ -310,6 +403,14 @@ func (a *ABI) writeField(binaryEncoder *Encoder, fieldName string, fieldType str
 		}
 
 	case "symbol_code":
+		if value.Type == gjson.String {
+			sc, err := StringToSymbolCode(value.Str)
+			if err != nil {
+				return fmt.Errorf("writing field: symbol_code: %s", err)
+			}
+			object = sc
+			break
+		}
 		object = SymbolCode(value.Uint())
 	case "asset":
 		asset, err := NewAsset(value.String())
@@ -333,6 +434,29 @@ func (a *ABI) writeField(binaryEncoder *Encoder, fieldName string, fieldType str
 	return binaryEncoder.Encode(object)
 }
 
+// writeVariant encodes a variant, the value must be in the ["type", value] form used by nodeos. Guessing the type
+// from a bare value is ambiguous for variants such as [uint8, uint16], so it is not supported.
+func (a *ABI) writeVariant(binaryEncoder *Encoder, fieldName string, variant *VariantDef, value gjson.Result) error {
+	abiEncoderLog.Debug("field is a variant", zap.String("name", fieldName), zap.String("variant", variant.Name))
+
+	pair := value.Array()
+	if !value.IsArray() || len(pair) != 2 || pair[0].Type != gjson.String {
+		return fmt.Errorf("writing field: [%s] variant [%s] must be in the form [\"type\", value]", fieldName, variant.Name)
+	}
+	for i, t := range variant.Types {
+		if t != pair[0].Str {
+			continue
+		}
+		if err := binaryEncoder.writeUVarInt(i); err != nil {
+			return err
+		}
+		typeName, _ := a.TypeNameForNewTypeName(t)
+		return a.writeField(binaryEncoder, fieldName, typeName, pair[1])
+	}
+
+	return fmt.Errorf("writing field: [%s] type [%s] is not in variant [%s]", fieldName, pair[0].Str, variant.Name)
+}
+
 func valueToInt(fieldName string, value gjson.Result, bitSize int) (int64, error) {
 	i, err := strconv.ParseInt(value.Raw, 10, bitSize)
 	if err != nil {
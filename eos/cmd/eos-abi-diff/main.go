@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fioprotocol/fio-go/eos"
+)
+
+// eos-abi-diff compares a local ABI file against the ABI currently deployed for an account, and exits with a
+// non-zero status if any of the changes are breaking.
+func main() {
+	url := flag.String("u", "http://127.0.0.1:8888", "nodeos url")
+	account := flag.String("a", "", "account to compare against")
+	asJson := flag.Bool("j", false, "output as json")
+	flag.Parse()
+
+	if *account == "" || len(flag.Args()) != 1 {
+		log.Fatalln("usage: eos-abi-diff -u <url> -a <account> <file.abi>")
+	}
+
+	f, err := os.Open(flag.Args()[0])
+	if err != nil {
+		log.Fatalln("error opening abi:", err)
+	}
+	defer f.Close()
+	local, err := eos.NewABI(f)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	api := eos.New(*url)
+	deployed, err := api.GetABI(eos.AccountName(*account))
+	if err != nil {
+		log.Fatalln("error fetching abi:", err)
+	}
+
+	diff := eos.DiffABI(&deployed.ABI, local)
+	if *asJson {
+		j, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Println(string(j))
+	} else if diff.Empty() {
+		fmt.Println("abi is unchanged")
+	} else {
+		fmt.Println(diff.String())
+	}
+
+	if diff.Breaking() {
+		os.Exit(1)
+	}
+}
@@ -98,6 +98,11 @@ func (d *Decoder) DecodeActions(decode bool) {
 	d.decodeActions = decode
 }
 
+// UnmarshalerBinary is implemented by types that handle their own binary decoding, for example generated variants
+type UnmarshalerBinary interface {
+	UnmarshalBinary(decoder *Decoder) error
+}
+
 func (d *Decoder) Decode(v interface{}) (err error) {
 	rv := reflect.Indirect(reflect.ValueOf(v))
 	if !rv.CanAddr() {
@@ -117,6 +122,10 @@ func (d *Decoder) Decode(v interface{}) (err error) {
 		rv = reflect.Indirect(newRV)
 	}
 
+	if u, ok := rv.Addr().Interface().(UnmarshalerBinary); ok {
+		return u.UnmarshalBinary(d)
+	}
+
 	switch realV := v.(type) {
 	case *string:
 		s, e := d.ReadString()
@@ -178,6 +187,63 @@ func (d *Decoder) Decode(v interface{}) (err error) {
 		n, err = d.ReadUint128("float128")
 		rv.Set(reflect.ValueOf(Float128(n)))
 		return
+	case *int8:
+		var n int8
+		n, err = d.ReadInt8()
+		rv.SetInt(int64(n))
+		return
+	case *Uint64:
+		var n uint64
+		n, err = d.ReadUint64()
+		rv.SetUint(n)
+		return
+	case *Varint32:
+		var n int32
+		n, err = d.ReadVarint32()
+		rv.SetInt(int64(n))
+		return
+	case *float32:
+		var n float32
+		n, err = d.ReadFloat32()
+		rv.SetFloat(float64(n))
+		return
+	case *float64:
+		var n float64
+		n, err = d.ReadFloat64()
+		rv.SetFloat(n)
+		return
+	case *TimePoint:
+		var n TimePoint
+		n, err = d.ReadTimePoint()
+		rv.SetUint(uint64(n))
+		return
+	case *TimePointSec:
+		var n TimePointSec
+		n, err = d.ReadTimePointSec()
+		rv.SetUint(uint64(n))
+		return
+	case *SymbolCode:
+		var n SymbolCode
+		n, err = d.ReadSymbolCode()
+		rv.SetUint(uint64(n))
+		return
+	case *Symbol:
+		var s *Symbol
+		s, err = d.ReadSymbol()
+		if err == nil {
+			rv.Set(reflect.ValueOf(*s))
+		}
+		return
+	case *Checksum160:
+		var s Checksum160
+		s, err = d.ReadChecksum160()
+		rv.SetBytes(s)
+		return
+	case *Checksum512:
+		var s Checksum512
+		s, err = d.ReadChecksum512()
+		rv.SetBytes(s)
+		return
 	case *uint16:
 		var n uint16
 		n, err = d.ReadUint16()
@@ -387,7 +453,7 @@ func (d *Decoder) decodeStruct(v interface{}, t reflect.Type, rv reflect.Value)
 
 	seenBinaryExtensionField := false
 	for i := 0; i < l; i++ {
-		tag := t.Field(i).Tag.Get("eos")
+		tag, _ := parseTag(t.Field(i).Tag.Get("eos"))
 		if tag == "-" {
 			continue
 		}
@@ -413,6 +479,28 @@ func (d *Decoder) decodeStruct(v interface{}, t reflect.Type, rv reflect.Value)
 			}
 		}
 
+		if tag == "optional" {
+			isPresent, e := d.ReadByte()
+			if e != nil {
+				err = fmt.Errorf("decode: %s isPresent, %s", typeField.Name, e)
+				return
+			}
+
+			if isPresent == 0 {
+				decoderLog.Debug("skipping optional", zap.String("name", typeField.Name))
+				continue
+			}
+		}
+
+		if v := rv.Field(i); tag == "optional" && v.CanSet() && v.Kind() == reflect.Ptr {
+			elem := reflect.New(v.Type().Elem())
+			if err = d.Decode(elem.Interface()); err != nil {
+				return
+			}
+			v.Set(elem)
+			continue
+		}
+
 		if v := rv.Field(i); v.CanSet() && typeField.Name != "_" {
 			iface := v.Addr().Interface()
 			decoderLog.Debug("field", zap.String("name", typeField.Name))
@@ -3,6 +3,8 @@ package eos
 import (
 	"encoding/binary"
 	"encoding/hex"
+	"math"
+	"strings"
 	"testing"
 
 	"bytes"
@@ -713,3 +715,119 @@ func estDecoder_SignedBlock_Full(t *testing.T) {
 	assert.Equal(t, []TransactionReceipt{}, signedBlock.Transactions)
 	assert.Equal(t, []*Extension{&Extension{uint16(0), expectedBlockExtension}}, signedBlock.BlockExtensions)
 }
+
+type customBinary struct {
+	Value uint8
+}
+
+func (c customBinary) MarshalBinary(encoder *Encoder) error {
+	return encoder.writeByte(c.Value + 1)
+}
+
+func (c *customBinary) UnmarshalBinary(decoder *Decoder) error {
+	b, err := decoder.ReadByte()
+	c.Value = b - 1
+	return err
+}
+
+func TestDecoder_OptionalAndCustomBinary(t *testing.T) {
+	type withOptional struct {
+		A *string       `eos:"optional"`
+		B *customBinary `eos:"optional"`
+		C customBinary
+	}
+	s := "a"
+	in := withOptional{A: &s, C: customBinary{Value: 2}}
+
+	buf, err := MarshalBinary(in)
+	require.NoError(t, err)
+	assert.Equal(t, "0101610003", hex.EncodeToString(buf))
+
+	out := withOptional{}
+	require.NoError(t, UnmarshalBinary(buf, &out))
+	require.NotNil(t, out.A)
+	assert.Equal(t, "a", *out.A)
+	assert.Nil(t, out.B)
+	assert.Equal(t, uint8(2), out.C.Value)
+}
+
+func TestDecoder_RoundTripBuiltInTypes(t *testing.T) {
+	type builtIns struct {
+		I8   int8
+		U64  Uint64
+		V32  Varint32
+		F32  float32
+		F64  float64
+		TP   TimePoint
+		TPS  TimePointSec
+		SC   SymbolCode
+		Sym  Symbol
+		C160 Checksum160
+		C512 Checksum512
+	}
+	in := builtIns{
+		I8:   -1,
+		U64:  math.MaxUint64,
+		V32:  -2,
+		F32:  1.5,
+		F64:  -2.5,
+		TP:   1541085187001001,
+		TPS:  1681469753,
+		SC:   4540996,
+		Sym:  Symbol{Precision: 9, Symbol: "FIO"},
+		C160: bytes.Repeat([]byte{1}, 20),
+		C512: bytes.Repeat([]byte{2}, 64),
+	}
+
+	buf, err := MarshalBinary(in)
+	require.NoError(t, err)
+	out := builtIns{}
+	require.NoError(t, UnmarshalBinary(buf, &out))
+	assert.Equal(t, in, out)
+}
+
+func TestDecoder_BlockHeaderNewProducers(t *testing.T) {
+	// a block header as serialized by nodeos, up to schedule_version
+	header := "a0c5b74b" + // timestamp
+		"0000000000ea3055" + // producer eosio
+		"0000" + // confirmed
+		"00000001" + strings.Repeat("00", 28) + // previous
+		strings.Repeat("00", 32) + // transaction_mroot
+		strings.Repeat("00", 32) + // action_mroot
+		"02000000" // schedule_version
+	newProducers := "01" + // present
+		"03000000" + // version
+		"01" + // one producer
+		"0000000000ea3055" + // eosio
+		"00" + "038d398fb453f7302ba1d14cd8f3b75464fba23f5a00e160f14d76f5509d66cefa" // K1 block signing key
+	extensions := "00"
+
+	without, err := hex.DecodeString(header + "00" + extensions)
+	require.NoError(t, err)
+	blockHeader := &BlockHeader{}
+	decoder := NewDecoder(without)
+	require.NoError(t, decoder.Decode(blockHeader))
+	assert.Equal(t, 0, decoder.remaining())
+	assert.Nil(t, blockHeader.NewProducers)
+	assert.Equal(t, uint32(2), blockHeader.ScheduleVersion)
+	assert.Equal(t, uint32(2), blockHeader.BlockNumber())
+
+	with, err := hex.DecodeString(header + newProducers + extensions)
+	require.NoError(t, err)
+	blockHeader = &BlockHeader{}
+	decoder = NewDecoder(with)
+	require.NoError(t, decoder.Decode(blockHeader))
+	assert.Equal(t, 0, decoder.remaining())
+	require.NotNil(t, blockHeader.NewProducers)
+	assert.Equal(t, uint32(3), blockHeader.NewProducers.Version)
+	require.Len(t, blockHeader.NewProducers.Producers, 1)
+	assert.Equal(t, AccountName("eosio"), blockHeader.NewProducers.Producers[0].AccountName)
+	assert.Equal(t, "038d398fb453f7302ba1d14cd8f3b75464fba23f5a00e160f14d76f5509d66cefa",
+		hex.EncodeToString(blockHeader.NewProducers.Producers[0].BlockSigningKey.Content))
+	assert.Equal(t, []*Extension{}, blockHeader.HeaderExtensions)
+
+	// decoding the optional directly still reads the present flag
+	var schedule *OptionalProducerSchedule
+	require.NoError(t, NewDecoder([]byte{0}).Decode(&schedule))
+	assert.Nil(t, schedule)
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"strings"
 	"time"
 
 	"math"
@@ -41,7 +42,16 @@ func (e *Encoder) writeName(name Name) error {
 	return e.writeUint64(val)
 }
 
+// MarshalerBinary is implemented by types that handle their own binary encoding, for example generated variants
+type MarshalerBinary interface {
+	MarshalBinary(encoder *Encoder) error
+}
+
 func (e *Encoder) Encode(v interface{}) (err error) {
+	if m, ok := v.(MarshalerBinary); ok {
+		return m.MarshalBinary(e)
+	}
+
 	switch cv := v.(type) {
 	case Name:
 		return e.writeName(cv)
@@ -154,7 +164,7 @@ func (e *Encoder) Encode(v interface{}) (err error) {
 	case TimePoint:
 		return e.writeUint64(uint64(cv))
 	case TimePointSec:
-		return e.writeUint64(uint64(cv))
+		return e.writeUint32(uint32(cv))
 	case nil:
 	default:
 
@@ -202,11 +212,17 @@ func (e *Encoder) Encode(v interface{}) (err error) {
 				field := t.Field(i)
 				encoderLog.Debug("field", zap.String("field", field.Name))
 
-				tag := field.Tag.Get("eos")
+				tag, opts := parseTag(field.Tag.Get("eos"))
 				if tag == "-" {
 					continue
 				}
 
+				// trailing binary extensions with no data are left off entirely, so that older readers are not
+				// presented with bytes they do not expect.
+				if tag == "binary_extension" && opts == "omitempty" && emptyExtensions(rv, i) {
+					break
+				}
+
 				if v := rv.Field(i); t.Field(i).Name != "_" {
 					if v.CanInterface() {
 						isPresent := true
@@ -215,6 +231,10 @@ func (e *Encoder) Encode(v interface{}) (err error) {
 							e.writeBool(isPresent)
 						}
 
+						if isPresent && v.Kind() == reflect.Ptr {
+							v = v.Elem()
+						}
+
 						if isPresent {
 							if err = e.Encode(v.Interface()); err != nil {
 								return
@@ -232,6 +252,32 @@ func (e *Encoder) Encode(v interface{}) (err error) {
 	return
 }
 
+// parseTag splits an eos struct tag into the tag name and options, ie: `eos:"binary_extension,omitempty"`
+func parseTag(tag string) (string, string) {
+	if idx := strings.Index(tag, ","); idx != -1 {
+		return tag[:idx], tag[idx+1:]
+	}
+	return tag, ""
+}
+
+// emptyExtensions is true when the field at index i, and every field after it, holds a zero value
+func emptyExtensions(rv reflect.Value, i int) bool {
+	for ; i < rv.NumField(); i++ {
+		f := rv.Field(i)
+		switch f.Kind() {
+		case reflect.Slice, reflect.Map:
+			if f.Len() > 0 {
+				return false
+			}
+		default:
+			if !f.IsZero() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func (e *Encoder) toWriter(bytes []byte) (err error) {
 	e.count += len(bytes)
 
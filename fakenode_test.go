@@ -0,0 +1,151 @@
+package fio
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/fioprotocol/fio-go/eos"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// tableHandler returns the rows for a get_table_rows request, either as a slice of row structs or a JSON array in a
+// json.RawMessage
+type tableHandler func(req eos.GetTableRowsRequest) (rows interface{}, more bool)
+
+// fakeNode is a fake nodeos for testing API calls. Tables are served by name, as JSON, or as binary rows encoded with
+// the contract's ABI when one has been added with abi. Other endpoints are added with handle, and unknown tables
+// return no rows.
+type fakeNode struct {
+	*httptest.Server
+	mux    sync.Mutex
+	tables map[string]tableHandler
+	paths  map[string]http.HandlerFunc
+	abis   map[eos.AccountName]json.RawMessage
+}
+
+// newFakeNode starts a fakeNode, it is closed and its ABIs are removed from the cache when the test finishes
+func newFakeNode(t *testing.T) (*fakeNode, *API) {
+	n := &fakeNode{
+		tables: make(map[string]tableHandler),
+		paths:  make(map[string]http.HandlerFunc),
+		abis:   make(map[eos.AccountName]json.RawMessage),
+	}
+	n.Server = httptest.NewServer(http.HandlerFunc(n.serve))
+	api := &API{*eos.New(n.URL)}
+	t.Cleanup(func() {
+		api.InvalidateAbiCache("")
+		n.Close()
+	})
+	return n, api
+}
+
+// table sets the handler for a table
+func (n *fakeNode) table(name string, h tableHandler) {
+	n.mux.Lock()
+	n.tables[name] = h
+	n.mux.Unlock()
+}
+
+// rows serves the same JSON rows for every request to a table
+func (n *fakeNode) rows(name string, rows string) {
+	n.table(name, func(eos.GetTableRowsRequest) (interface{}, bool) {
+		return json.RawMessage(rows), false
+	})
+}
+
+// handle sets the handler for an endpoint other than get_table_rows
+func (n *fakeNode) handle(path string, h http.HandlerFunc) {
+	n.mux.Lock()
+	n.paths[path] = h
+	n.mux.Unlock()
+}
+
+// abi sets the ABI for a contract, used for get_abi and to encode binary rows
+func (n *fakeNode) abi(account eos.AccountName, abi string) {
+	n.mux.Lock()
+	n.abis[account] = json.RawMessage(abi)
+	n.mux.Unlock()
+}
+
+func (n *fakeNode) serve(w http.ResponseWriter, r *http.Request) {
+	n.mux.Lock()
+	h := n.paths[r.URL.Path]
+	n.mux.Unlock()
+	if h != nil {
+		h(w, r)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v1/chain/get_code_hash", "/v1/chain/get_abi":
+		req := struct {
+			AccountName eos.AccountName `json:"account_name"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		n.mux.Lock()
+		abi := n.abis[req.AccountName]
+		n.mux.Unlock()
+		if abi == nil {
+			http.Error(w, "unknown account", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"account_name": req.AccountName,
+			"code_hash":    strings.Repeat("01", 32),
+			"abi":          abi,
+		})
+	case "/v1/chain/get_table_rows":
+		req := eos.GetTableRowsRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		n.mux.Lock()
+		table := n.tables[req.Table]
+		n.mux.Unlock()
+		rows, more := interface{}(json.RawMessage(`[]`)), false
+		if table != nil {
+			rows, more = table(req)
+		}
+		j, err := json.Marshal(rows)
+		if err == nil && !req.JSON {
+			j, err = n.encodeRows(eos.AccountName(req.Code), req.Table, j)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"rows": json.RawMessage(j), "more": more})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// encodeRows converts a JSON array of rows to hex encoded binary rows, as nodeos returns when json is false
+func (n *fakeNode) encodeRows(account eos.AccountName, table string, rows []byte) ([]byte, error) {
+	n.mux.Lock()
+	j := n.abis[account]
+	n.mux.Unlock()
+	abi, err := eos.NewABI(strings.NewReader(string(j)))
+	if err != nil {
+		return nil, err
+	}
+	def := abi.TableForName(eos.TableName(table))
+	if def == nil {
+		return nil, errors.New("table " + table + " is not in the abi")
+	}
+	objects := make([]json.RawMessage, 0)
+	if err = json.Unmarshal(rows, &objects); err != nil {
+		return nil, err
+	}
+	hexRows := make([]string, len(objects))
+	for i := range objects {
+		bin, err := abi.EncodeTableRowTyped(def.Type, objects[i])
+		if err != nil {
+			return nil, err
+		}
+		hexRows[i] = hex.EncodeToString(bin)
+	}
+	return json.Marshal(hexRows)
+}
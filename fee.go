@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"github.com/fioprotocol/fio-go/eos"
 	"io/ioutil"
+	"sort"
 	"sync"
 )
 
 const (
+	FeeAddFioPermission     = "add_fio_permission"
+	FeeAddNft               = "add_nft"
 	FeeAddPubAddress        = "add_pub_address"
 	FeeAddToWhitelist       = "add_to_whitelist"
 	FeeAuthDelete           = "auth_delete"
@@ -32,61 +35,79 @@ const (
 	FeeRejectFundsRequest   = "reject_funds_request"
 	FeeRemoveFromWhitelist  = "remove_from_whitelist"
 	FeeRemoveAllAddresses   = "remove_pub_addresses"
+	FeeRemoveAllNfts        = "remove_all_nfts"
+	FeeRemoveFioPermission  = "remove_fio_permission"
+	FeeRemoveNft            = "remove_nft"
 	FeeRemovePubAddress     = "remove_pub_address"
 	FeeRenewFioAddress      = "renew_fio_address"
 	FeeRenewFioDomain       = "renew_fio_domain"
 	FeeSetDomainPub         = "set_fio_domain_public"
+	FeeStakeFioTokens       = "stake_fio_tokens"
 	FeeSubmitBundledTrans   = "submit_bundled_transaction"
 	FeeTransferAddress      = "transfer_fio_address"
 	FeeTransferDom          = "transfer_fio_domain"
+	FeeTransferLockedTokens = "transfer_locked_tokens"
 	FeeTransferTokensPubKey = "transfer_tokens_pub_key"
 	FeeUnregisterProducer   = "unregister_producer"
 	FeeUnregisterProxy      = "unregister_proxy"
+	FeeUnStakeFioTokens     = "unstake_fio_tokens"
 	FeeVoteProducer         = "vote_producer"
+	FeeWrapFioDomain        = "wrap_fio_domain"
+	FeeWrapFioTokens        = "wrap_fio_tokens"
 )
 
 var (
-	// maxFees holds the fees for transactions
+	// maxFees holds the fees for transactions in SUF
 	// use fio.GetMaxFee() instead of directly accessing this map to ensure concurrent safe access
 	//
 	// *IMPORTANT:* these are _default_ values: call `fio.UpdateMaxFees` to refresh values from the on-chain table.
 	// fees are automatically updated on first connect on a best-effort basis. If voting for fees it is a good
 	// idea to update immediately after voting.
-	maxFees = map[string]float64{
-		"add_pub_address":             0.4,
-		"add_to_whitelist":            0.0,
-		"auth_delete":                 0.4,
-		"auth_link":                   0.4,
-		"auth_update":                 0.4,
-		"burnexpired":                 0.1,
-		"cancel_funds_request":        0.6,
-		"msig_approve":                0.4,
-		"msig_cancel":                 0.4,
-		"msig_exec":                   0.4,
-		"msig_invalidate":             0.4,
-		"msig_propose":                0.4,
-		"msig_unapprove":              0.4,
-		"new_funds_request":           0.8,
-		"proxy_vote":                  0.4,
-		"record_obt_data":             0.8,
-		"register_fio_address":        40.0,
-		"register_fio_domain":         800.0,
-		"register_producer":           200.0,
-		"register_proxy":              0.4,
-		"reject_funds_request":        0.4,
-		"remove_from_whitelist":       0.0,
-		"remove_pub_address":          0.6,
-		"remove_pub_addresses":        0.6,
-		"renew_fio_address":           40.0,
-		"renew_fio_domain":            800.0,
-		"set_fio_domain_public":       0.4,
-		"submit_bundled_transaction":  0.0,
-		"transfer_fio_address":        1.0,
-		"transfer_fio_domain":         1.0,
-		"transfer_tokens_fio_address": 0.1,
-		"transfer_tokens_pub_key":     2.0,
-		"unregister_proxy":            0.4,
-		"vote_producer":               0.4,
+	maxFees = map[string]uint64{
+		"add_fio_permission":          500_000_000,
+		"add_nft":                     400_000_000,
+		"add_pub_address":             400_000_000,
+		"add_to_whitelist":            0,
+		"auth_delete":                 400_000_000,
+		"auth_link":                   400_000_000,
+		"auth_update":                 400_000_000,
+		"burnexpired":                 100_000_000,
+		"cancel_funds_request":        600_000_000,
+		"msig_approve":                400_000_000,
+		"msig_cancel":                 400_000_000,
+		"msig_exec":                   400_000_000,
+		"msig_invalidate":             400_000_000,
+		"msig_propose":                400_000_000,
+		"msig_unapprove":              400_000_000,
+		"new_funds_request":           800_000_000,
+		"proxy_vote":                  400_000_000,
+		"record_obt_data":             800_000_000,
+		"register_fio_address":        40_000_000_000,
+		"register_fio_domain":         800_000_000_000,
+		"register_producer":           200_000_000_000,
+		"register_proxy":              400_000_000,
+		"reject_funds_request":        400_000_000,
+		"remove_from_whitelist":       0,
+		"remove_all_nfts":             400_000_000,
+		"remove_fio_permission":       500_000_000,
+		"remove_nft":                  400_000_000,
+		"remove_pub_address":          600_000_000,
+		"remove_pub_addresses":        600_000_000,
+		"renew_fio_address":           40_000_000_000,
+		"renew_fio_domain":            800_000_000_000,
+		"set_fio_domain_public":       400_000_000,
+		"stake_fio_tokens":            3_000_000_000,
+		"submit_bundled_transaction":  0,
+		"transfer_fio_address":        1_000_000_000,
+		"transfer_fio_domain":         1_000_000_000,
+		"transfer_locked_tokens":      2_000_000_000,
+		"transfer_tokens_fio_address": 100_000_000,
+		"transfer_tokens_pub_key":     2_000_000_000,
+		"unregister_proxy":            400_000_000,
+		"unstake_fio_tokens":          3_000_000_000,
+		"vote_producer":               400_000_000,
+		"wrap_fio_domain":             400_000_000,
+		"wrap_fio_tokens":             400_000_000,
 	}
 
 	// maxFeesByAction correlates fee name to action name, useful when working directly with contracts, not API endpoint
@@ -123,6 +144,16 @@ var (
 		"trnsfiopubky": FeeTransferTokensPubKey,
 		"xferaddress":  FeeTransferAddress,
 		"xferdomain":   FeeTransferDom,
+		"stakefio":     FeeStakeFioTokens,
+		"unstakefio":   FeeUnStakeFioTokens,
+		"trnsloctoks":  FeeTransferLockedTokens,
+		"addnft":       FeeAddNft,
+		"remnft":       FeeRemoveNft,
+		"remallnfts":   FeeRemoveAllNfts,
+		"wraptokens":   FeeWrapFioTokens,
+		"wrapdomain":   FeeWrapFioDomain,
+		"addperm":      FeeAddFioPermission,
+		"remperm":      FeeRemoveFioPermission,
 	}
 	maxFeeActionMutex = sync.RWMutex{}
 	maxFeeMutex       = sync.RWMutex{}
@@ -153,7 +184,7 @@ func UpdateMaxFees(api *API) bool {
 	}
 	maxFeeMutex.Lock()
 	for _, f := range results {
-		maxFees[f.EndPoint] = float64(f.SufAmount) / 1000000000.0
+		maxFees[f.EndPoint] = f.SufAmount
 	}
 	maxFeeMutex.Unlock()
 	maxFeesUpdated = true
@@ -163,20 +194,30 @@ func UpdateMaxFees(api *API) bool {
 // GetMaxFee looks up a fee from the map, this is based on the values in the fiofees table, and does not take into
 // account any bundled transactions for the user, use GetFee() for that.
 func GetMaxFee(name string) (fioTokens float64) {
-	maxFeeMutex.RLock()
-	fioTokens = maxFees[name]
-	maxFeeMutex.RUnlock()
-	return fioTokens
+	return float64(GetMaxFeeAmount(name)) / 1000000000.0
 }
 
 // GetMaxFeeByAction allows getting a fee given the contract action name instead of the API endpoint name.
 func GetMaxFeeByAction(name string) (fioTokens float64) {
+	return float64(GetMaxFeeAmountByAction(name)) / 1000000000.0
+}
+
+// GetMaxFeeAmount is GetMaxFee as an exact Amount
+func GetMaxFeeAmount(name string) Amount {
+	maxFeeMutex.RLock()
+	fee := maxFees[name]
+	maxFeeMutex.RUnlock()
+	return Amount(fee)
+}
+
+// GetMaxFeeAmountByAction is GetMaxFeeByAction as an exact Amount
+func GetMaxFeeAmountByAction(name string) Amount {
 	maxFeeMutex.RLock()
 	maxFeeActionMutex.RLock()
-	fioTokens = maxFees[maxFeesByAction[name]]
+	fee := maxFees[maxFeesByAction[name]]
 	maxFeeMutex.RUnlock()
 	maxFeeActionMutex.RUnlock()
-	return fioTokens
+	return Amount(fee)
 }
 
 type GetFeeRequest struct {
@@ -213,6 +254,12 @@ func (api *API) GetFee(fioAddress string, endPoint string) (fee uint64, err erro
 	return feeResp.Fee, nil
 }
 
+// GetFeeAmount is GetFee returning an Amount
+func (api *API) GetFeeAmount(fioAddress string, endPoint string) (Amount, error) {
+	fee, err := api.GetFee(fioAddress, endPoint)
+	return Amount(fee), err
+}
+
 // MaxFeesUpdated checks if the fee map has been updated, or if using the default (possibly wrong) values
 func MaxFeesUpdated() bool {
 	return maxFeesUpdated
@@ -220,9 +267,13 @@ func MaxFeesUpdated() bool {
 
 // MaxFeesJson provides a JSON representation of the current fee map
 func MaxFeesJson() []byte {
+	fees := make(map[string]float64)
 	maxFeeMutex.RLock()
-	j, _ := json.MarshalIndent(maxFees, "", "  ")
+	for k, v := range maxFees {
+		fees[k] = float64(v) / 1000000000.0
+	}
 	maxFeeMutex.RUnlock()
+	j, _ := json.MarshalIndent(fees, "", "  ")
 	return j
 }
 
@@ -251,6 +302,18 @@ func NewSetFeeVote(ratios []FeeValue, actor eos.AccountName) *Action {
 		})
 }
 
+// NewSetFeeVoteAmounts is NewSetFeeVote taking exact Amounts keyed by end point
+func NewSetFeeVoteAmounts(fees map[string]Amount, actor eos.AccountName) *Action {
+	ratios := make([]FeeValue, 0, len(fees))
+	for endPoint, fee := range fees {
+		ratios = append(ratios, FeeValue{EndPoint: endPoint, Value: fee.Suf()})
+	}
+	sort.Slice(ratios, func(i, j int) bool {
+		return ratios[i].EndPoint < ratios[j].EndPoint
+	})
+	return NewSetFeeVote(ratios, actor)
+}
+
 // BundleVote is used by block producers to vote for the number of free transactions included when registering or
 // renewing a FIO address
 type BundleVote struct {
@@ -0,0 +1,185 @@
+package fio
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxAddressLen is the longest FIO address, including the name, @ and domain
+	MaxAddressLen = 64
+	// MaxDomainLen is the longest FIO domain
+	MaxDomainLen = 62
+)
+
+// Handle is a parsed FIO address. It is always normalized to lower case, which is how the fio.address contract
+// stores names, and how AddressHash and DomainNameHash must be calculated.
+type Handle struct {
+	Name   string
+	Domain string
+}
+
+// confusables maps non-ASCII characters that are commonly mistaken for ASCII characters allowed in a FIO address,
+// it is only used to give a clearer error.
+var confusables = map[rune]rune{
+	'а': 'a', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'і': 'i', 'ј': 'j', 'ӏ': 'l', 'о': 'o', 'р': 'p', 'ԛ': 'q',
+	'ѕ': 's', 'ԝ': 'w', 'х': 'x', 'у': 'y', 'ɡ': 'g', 'ο': 'o', 'ν': 'v', 'ı': 'i', 'ℓ': 'l', '‐': '-', '‑': '-',
+	'‒': '-', '–': '-', '—': '-', '−': '-', '﹫': '@',
+}
+
+// checkChars rejects anything other than ASCII letters, digits, dash and @, explaining look-alike characters.
+func checkChars(s string) error {
+	if !utf8.ValidString(s) {
+		return errors.New("invalid utf-8")
+	}
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '@':
+			continue
+		case r == ':':
+			return errors.New("':' is not a valid separator, use name@domain")
+		case r >= 0xff01 && r <= 0xff5e:
+			return fmt.Errorf("full-width character %q at position %d looks like %q", r, i, r-0xfee0)
+		case confusables[r] != 0:
+			return fmt.Errorf("character %q (U+%04X) at position %d looks like %q", r, r, i, confusables[r])
+		case r >= utf8.RuneSelf:
+			return fmt.Errorf("non-ASCII character %q (U+%04X) at position %d", r, r, i)
+		default:
+			return fmt.Errorf("invalid character %q at position %d", r, i)
+		}
+	}
+	return nil
+}
+
+// checkLabel enforces the dash rules shared by names and domains
+func checkLabel(kind string, s string) error {
+	switch {
+	case s == "":
+		return fmt.Errorf("%s is empty", kind)
+	case strings.HasPrefix(s, "-") || strings.HasSuffix(s, "-"):
+		return fmt.Errorf("%s can not begin or end with a dash", kind)
+	case strings.Contains(s, "--"):
+		// this also rejects punycode (xn--) internationalized names, which the contract does not support
+		return fmt.Errorf("%s can not contain consecutive dashes", kind)
+	case strings.Contains(s, "@"):
+		return fmt.Errorf("%s can not contain @", kind)
+	}
+	return nil
+}
+
+// ParseDomain validates and normalizes a FIO domain: 1 to 62 characters of a-z, 0-9 and dash, where a dash can't
+// be the first or last character. Upper case is converted to lower case, any other character is rejected.
+func ParseDomain(domain string) (string, error) {
+	if err := checkChars(domain); err != nil {
+		return "", err
+	}
+	if len(domain) > MaxDomainLen {
+		return "", fmt.Errorf("domain is longer than %d characters", MaxDomainLen)
+	}
+	if err := checkLabel("domain", domain); err != nil {
+		return "", err
+	}
+	return strings.ToLower(domain), nil
+}
+
+// ValidDomain checks if a FIO domain is valid, see ParseDomain
+func ValidDomain(domain string) bool {
+	_, err := ParseDomain(domain)
+	return err == nil
+}
+
+// ParseHandle strictly parses a FIO address in the form name@domain. Upper case is converted to lower case, but no
+// other changes are made: whitespace, non-ASCII look-alike characters and the old name:domain form are all errors.
+func ParseHandle(s string) (Handle, error) {
+	if err := checkChars(s); err != nil {
+		return Handle{}, err
+	}
+	if len(s) > MaxAddressLen {
+		return Handle{}, fmt.Errorf("address is longer than %d characters", MaxAddressLen)
+	}
+	parts := strings.Split(s, "@")
+	if len(parts) != 2 {
+		return Handle{}, errors.New("address must contain exactly one @")
+	}
+	if err := checkLabel("name", parts[0]); err != nil {
+		return Handle{}, err
+	}
+	domain, err := ParseDomain(parts[1])
+	if err != nil {
+		return Handle{}, err
+	}
+	return Handle{Name: strings.ToLower(parts[0]), Domain: domain}, nil
+}
+
+// MustParseHandle panics on an invalid address
+func MustParseHandle(s string) Handle {
+	h, err := ParseHandle(s)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// Handle parses an Address, see ParseHandle
+func (a Address) Handle() (Handle, error) {
+	return ParseHandle(string(a))
+}
+
+func (h Handle) String() string {
+	return h.Name + "@" + h.Domain
+}
+
+// Address converts a Handle to an Address
+func (h Handle) Address() Address {
+	return Address(h.String())
+}
+
+// Hash is the fionames table index 5 value for the address, see AddressHash
+func (h Handle) Hash() string {
+	return AddressHash(h.String())
+}
+
+// DomainHash is the domains table index 4 value for the domain, see DomainNameHash
+func (h Handle) DomainHash() string {
+	return DomainNameHash(h.Domain)
+}
+
+// MatchesHash checks if the namehash of a fionames row is for this address
+func (h Handle) MatchesHash(hash eos.Uint128) bool {
+	return FormatI128Hash(hash) == h.Hash()
+}
+
+// MatchesDomainHash checks if the domainhash of a domains row is for this domain
+func (h Handle) MatchesDomainHash(hash eos.Uint128) bool {
+	return FormatI128Hash(hash) == h.DomainHash()
+}
+
+// ParseI128Hash converts a hash from I128Hash, which is formatted as a big-endian number for use as a table bound,
+// to the eos.Uint128 value found in table rows. Note that table rows print uint128 values as little-endian bytes,
+// so the JSON form of the result will not look the same as the hash.
+func ParseI128Hash(hash string) (eos.Uint128, error) {
+	if !strings.HasPrefix(hash, "0x") && !strings.HasPrefix(hash, "0X") {
+		return eos.Uint128{}, errors.New("hash must have a 0x prefix")
+	}
+	b, err := hex.DecodeString(hash[2:])
+	if err != nil {
+		return eos.Uint128{}, err
+	}
+	if len(b) != 16 {
+		return eos.Uint128{}, fmt.Errorf("hash must be 16 bytes, got %d", len(b))
+	}
+	return eos.Uint128{Hi: binary.BigEndian.Uint64(b[:8]), Lo: binary.BigEndian.Uint64(b[8:])}, nil
+}
+
+// FormatI128Hash is the reverse of ParseI128Hash, formatting a uint128 from a table row the same as I128Hash
+func FormatI128Hash(hash eos.Uint128) string {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b[:8], hash.Hi)
+	binary.BigEndian.PutUint64(b[8:], hash.Lo)
+	return "0x" + hex.EncodeToString(b)
+}
@@ -0,0 +1,42 @@
+//go:build go1.18
+// +build go1.18
+
+package fio
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzParseHandle(f *testing.F) {
+	for _, s := range []string{"alice@fio", "Alice@FIO", "a-b@c-d", "alice:fio", "аlice@fio", "a--b@c", "@", ""} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		h, err := ParseHandle(s)
+		if err != nil {
+			return
+		}
+		if h.String() != strings.ToLower(s) {
+			t.Errorf("%q: normalized to %q", s, h.String())
+		}
+		if !h.Address().Valid() || !ValidDomain(h.Domain) {
+			t.Errorf("%q: parsed handle is not valid", s)
+		}
+		again, err := ParseHandle(h.String())
+		if err != nil || again != h {
+			t.Errorf("%q: did not round trip: %v", s, err)
+		}
+	})
+}
+
+func FuzzI128Hash(f *testing.F) {
+	f.Add("alice@fio")
+	f.Fuzz(func(t *testing.T, s string) {
+		hash := I128Hash(s)
+		u, err := ParseI128Hash(hash)
+		if err != nil || FormatI128Hash(u) != hash {
+			t.Errorf("%q: hash did not round trip: %v", s, err)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package fio
+
+import (
+	"encoding/json"
+	"github.com/fioprotocol/fio-go/eos"
+	"strings"
+	"testing"
+)
+
+func TestParseHandle(t *testing.T) {
+	for _, test := range []struct {
+		in     string
+		name   string
+		domain string
+		err    string
+	}{
+		{in: "alice@fio", name: "alice", domain: "fio"},
+		{in: "Alice@FIO", name: "alice", domain: "fio"},
+		{in: "a-1@b-2", name: "a-1", domain: "b-2"},
+		{in: "a@b", name: "a", domain: "b"},
+		{in: strings.Repeat("a", 1) + "@" + strings.Repeat("b", 62), name: "a", domain: strings.Repeat("b", 62)},
+		{in: strings.Repeat("a", 2) + "@" + strings.Repeat("b", 62), err: "longer than 64"},
+		{in: "alice:fio", err: "separator"},
+		{in: "alice", err: "exactly one @"},
+		{in: "alice@fio@fio", err: "exactly one @"},
+		{in: "@fio", err: "name is empty"},
+		{in: "alice@", err: "domain is empty"},
+		{in: "-alice@fio", err: "dash"},
+		{in: "alice-@fio", err: "dash"},
+		{in: "alice@-fio", err: "dash"},
+		{in: "al--ice@fio", err: "consecutive"},
+		{in: "alice@xn--fio", err: "consecutive"},
+		{in: " alice@fio", err: "invalid character"},
+		{in: "alice@fio\n", err: "invalid character"},
+		{in: "alice_1@fio", err: "invalid character"},
+		{in: "аlice@fio", err: "looks like 'a'"},       // cyrillic a
+		{in: "alice＠fio", err: "full-width character"}, // full-width @
+		{in: "alice@fіo", err: "looks like 'i'"},       // cyrillic i
+		{in: "alice@fio​", err: "non-ASCII character"}, // zero width space
+		{in: "alice‐bob@fio", err: "looks like '-'"},   // unicode hyphen
+		{in: "ålice@fio", err: "non-ASCII"},
+		{in: "alice@fio\xff", err: "utf-8"},
+	} {
+		h, err := ParseHandle(test.in)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%q: expected error containing %q, got %v", test.in, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", test.in, err)
+			continue
+		}
+		if h.Name != test.name || h.Domain != test.domain {
+			t.Errorf("%q: got %+v", test.in, h)
+		}
+		if !h.Address().Valid() {
+			t.Errorf("%q: parsed address should be valid", test.in)
+		}
+	}
+}
+
+func TestParseDomain(t *testing.T) {
+	for in, valid := range map[string]bool{
+		"fio":                   true,
+		"FIOTestnet":            true,
+		"a":                     true,
+		strings.Repeat("d", 62): true,
+		strings.Repeat("d", 63): false,
+		"fio@fio":               false,
+		"-fio":                  false,
+		"f--io":                 false,
+		"":                      false,
+		"fio.com":               false,
+	} {
+		if ValidDomain(in) != valid {
+			t.Errorf("%q: expected valid to be %v", in, valid)
+		}
+	}
+	if d, _ := ParseDomain("FIOTestnet"); d != "fiotestnet" {
+		t.Error("domain was not lower-cased")
+	}
+}
+
+func TestHandle_Hash(t *testing.T) {
+	// known values from the AddressHash and DomainNameHash examples
+	h := MustParseHandle("Test@FIOTestnet")
+	if h.Hash() != "0xeb0816aeb936141ebec9a4a76c64df58" {
+		t.Error("wrong address hash", h.Hash())
+	}
+	if (Handle{Domain: "fio"}).DomainHash() != "0x8d9d3bd8a6fb22345ce8fa3c416a28e5" {
+		t.Error("wrong domain hash")
+	}
+
+	u, err := ParseI128Hash(h.Hash())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !h.MatchesHash(u) || FormatI128Hash(u) != h.Hash() {
+		t.Error("hash did not round trip")
+	}
+	if h.MatchesDomainHash(u) {
+		t.Error("address hash should not match the domain")
+	}
+
+	// a fionames row for test@fiotestnet as returned by get_table_rows, nodeos prints uint128 as little-endian bytes
+	// so these are the leading bytes of the sha1 sums of the address and domain.
+	type fioNameRow struct {
+		NameHash   eos.Uint128 `json:"namehash"`
+		DomainHash eos.Uint128 `json:"domainhash"`
+	}
+	row := fioNameRow{}
+	err = json.Unmarshal([]byte(`{"id":3,"name":"test@fiotestnet","namehash":"0x58df646ca7a4c9be1e1436b9ae1608eb",
+"domain":"fiotestnet","domainhash":"0xe5a9aedfa0746ce5aa88530709c70f01","expiration":1700000000,"owner_account":"hzhqyv5n2ut5"}`), &row)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !h.MatchesHash(row.NameHash) || !h.MatchesDomainHash(row.DomainHash) || h.MatchesHash(row.DomainHash) {
+		t.Errorf("hashes from the json row did not match: %s %s", FormatI128Hash(row.NameHash), FormatI128Hash(row.DomainHash))
+	}
+
+	// the same row decoded from binary with the abi
+	abi, err := eos.NewABI(strings.NewReader(`{"version": "eosio::abi/1.1", "structs": [{"name": "fioname", "base": "", "fields": [
+		{"name": "namehash", "type": "uint128"}, {"name": "domainhash", "type": "uint128"}]}]}`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	decoded, err := DecodeTableRowsTyped(abi, "fioname", json.RawMessage(`["58df646ca7a4c9be1e1436b9ae1608ebe5a9aedfa0746ce5aa88530709c70f01"]`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	rows := make([]fioNameRow, 0)
+	if err = json.Unmarshal(decoded, &rows); err != nil || len(rows) != 1 {
+		t.Error("could not decode binary row", err)
+		return
+	}
+	if !h.MatchesHash(rows[0].NameHash) || !h.MatchesDomainHash(rows[0].DomainHash) {
+		t.Errorf("hashes from the binary row did not match: %s %s", FormatI128Hash(rows[0].NameHash), FormatI128Hash(rows[0].DomainHash))
+	}
+	if _, err = ParseI128Hash("eb0816aeb936141ebec9a4a76c64df58"); err == nil {
+		t.Error("hash without 0x prefix should be rejected")
+	}
+	if _, err = ParseI128Hash("0xeb0816"); err == nil {
+		t.Error("short hash should be rejected")
+	}
+}
@@ -0,0 +1,512 @@
+package fio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status strings returned by the get_*_fio_requests endpoints
+const (
+	RequestStatusRequested = "requested"
+	RequestStatusRejected  = "rejected"
+	RequestStatusPaid      = "sent_to_blockchain"
+	RequestStatusCancelled = "cancelled"
+)
+
+// InboxRequest is a FIO request with the content decrypted, and if it has been paid, the recordobt response
+type InboxRequest struct {
+	RequestStatus
+
+	// Incoming is true if the account is the payer (the request was sent to the account)
+	Incoming bool `json:"incoming"`
+
+	Request  *ObtRequestContent      `json:"request,omitempty"`
+	Record   *ObtRecordContent       `json:"record,omitempty"`
+	Response *FundsRequestStatusResp `json:"response,omitempty"`
+
+	// DecryptErr is set if the content could not be decrypted, the request is still returned so it can be
+	// rejected or cancelled.
+	DecryptErr error `json:"-"`
+}
+
+// CounterpartyPubKey is the public key of the other side of the request, used for decrypting the content
+func (r *InboxRequest) CounterpartyPubKey() string {
+	if r.Incoming {
+		return r.PayeeFioPublicKey
+	}
+	return r.PayerFioPublicKey
+}
+
+// InboxFilter limits the requests returned by an Inbox, empty fields match everything. Token and chain codes
+// are compared case-insensitively against the decrypted content.
+type InboxFilter struct {
+	TokenCode string
+	ChainCode string
+	Status    []string
+}
+
+func (f *InboxFilter) matchStatus(status string) bool {
+	if f == nil || len(f.Status) == 0 {
+		return true
+	}
+	for _, s := range f.Status {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *InboxFilter) matchContent(r *InboxRequest) bool {
+	if r.Request == nil {
+		return f.matchCodes(false, "", "")
+	}
+	return f.matchCodes(true, r.Request.TokenCode, r.Request.ChainCode)
+}
+
+func (f *InboxFilter) matchCodes(decrypted bool, tokenCode string, chainCode string) bool {
+	if f == nil || (f.TokenCode == "" && f.ChainCode == "") {
+		return true
+	}
+	if !decrypted {
+		return false
+	}
+	if f.TokenCode != "" && !strings.EqualFold(f.TokenCode, tokenCode) {
+		return false
+	}
+	if f.ChainCode != "" && !strings.EqualFold(f.ChainCode, chainCode) {
+		return false
+	}
+	return true
+}
+
+// Inbox lists and watches the FIO requests for an Account
+type Inbox struct {
+	Account  *Account
+	Api      *API
+	PageSize int
+
+	mux  sync.Mutex
+	seen map[uint64]bool
+}
+
+// NewInbox returns an Inbox for an account
+func NewInbox(account *Account, api *API) *Inbox {
+	return &Inbox{
+		Account:  account,
+		Api:      api,
+		PageSize: 100,
+		seen:     make(map[uint64]bool),
+	}
+}
+
+// Pending returns the requests sent to the account that have not been responded to
+func (inbox *Inbox) Pending(filter *InboxFilter) ([]*InboxRequest, error) {
+	return inbox.list("pending", true, filter)
+}
+
+// Incoming returns all requests sent to the account
+func (inbox *Inbox) Incoming(filter *InboxFilter) ([]*InboxRequest, error) {
+	return inbox.list("received", true, filter)
+}
+
+// Outgoing returns all requests sent by the account
+func (inbox *Inbox) Outgoing(filter *InboxFilter) ([]*InboxRequest, error) {
+	return inbox.list("sent", false, filter)
+}
+
+// Cancelled returns requests sent by the account that were cancelled
+func (inbox *Inbox) Cancelled(filter *InboxFilter) ([]*InboxRequest, error) {
+	return inbox.list("cancelled", false, filter)
+}
+
+// Rejected returns incoming and outgoing requests that were rejected by the payer
+func (inbox *Inbox) Rejected(filter *InboxFilter) ([]*InboxRequest, error) {
+	return inbox.both(RequestStatusRejected, filter)
+}
+
+// Paid returns incoming and outgoing requests that have a recordobt response, the Record field holds the
+// decrypted response.
+func (inbox *Inbox) Paid(filter *InboxFilter) ([]*InboxRequest, error) {
+	return inbox.both(RequestStatusPaid, filter)
+}
+
+func (inbox *Inbox) both(status string, filter *InboxFilter) ([]*InboxRequest, error) {
+	f := InboxFilter{Status: []string{status}}
+	if filter != nil {
+		f.TokenCode, f.ChainCode = filter.TokenCode, filter.ChainCode
+	}
+	in, err := inbox.Incoming(&f)
+	if err != nil {
+		return nil, err
+	}
+	out, err := inbox.Outgoing(&f)
+	if err != nil {
+		return nil, err
+	}
+	return append(in, out...), nil
+}
+
+// list pages through a request endpoint, decrypting and filtering the results
+func (inbox *Inbox) list(requestType string, incoming bool, filter *InboxFilter) ([]*InboxRequest, error) {
+	if inbox.Account == nil || inbox.Api == nil {
+		return nil, errors.New("inbox requires an account and api")
+	}
+	pageSize := inbox.PageSize
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+	result := make([]*InboxRequest, 0)
+	for offset := 0; ; {
+		page, _, err := inbox.Api.getFioRequests(requestType, inbox.Account.PubKey, pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, rs := range page.Requests {
+			if requestType == "pending" && rs.Status == "" {
+				rs.Status = RequestStatusRequested
+			}
+			if !filter.matchStatus(rs.Status) {
+				continue
+			}
+			r, err := inbox.join(rs, incoming)
+			if err != nil {
+				return nil, err
+			}
+			if filter.matchContent(r) {
+				result = append(result, r)
+			}
+		}
+		offset += len(page.Requests)
+		if page.More == 0 || len(page.Requests) == 0 {
+			break
+		}
+	}
+	return result, nil
+}
+
+// join decrypts a request, and if it was paid looks up and decrypts the recordobt response
+func (inbox *Inbox) join(rs RequestStatus, incoming bool) (*InboxRequest, error) {
+	r := &InboxRequest{RequestStatus: rs, Incoming: incoming}
+	content, err := DecryptContent(inbox.Account, r.CounterpartyPubKey(), rs.Content, ObtRequestType)
+	if err != nil {
+		r.DecryptErr = err
+	} else {
+		r.Request = content.Request
+	}
+	if rs.Status != RequestStatusPaid && rs.Status != RequestStatusRejected {
+		return r, nil
+	}
+	found, resp, err := inbox.Api.GetFioRequestStatus(rs.FioRequestId)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return r, nil
+	}
+	r.Response = resp
+	if resp.Metadata == "" {
+		return r, nil
+	}
+	record, err := DecryptContent(inbox.Account, r.CounterpartyPubKey(), resp.Metadata, ObtResponseType)
+	if err != nil {
+		r.DecryptErr = err
+		return r, nil
+	}
+	r.Record = record.Record
+	return r, nil
+}
+
+// newPending returns pending requests that have not been returned by a previous call
+func (inbox *Inbox) newPending(filter *InboxFilter) ([]*InboxRequest, error) {
+	pending, err := inbox.Pending(filter)
+	if err != nil {
+		return nil, err
+	}
+	inbox.mux.Lock()
+	defer inbox.mux.Unlock()
+	if inbox.seen == nil {
+		inbox.seen = make(map[uint64]bool)
+	}
+	fresh := make([]*InboxRequest, 0)
+	for _, r := range pending {
+		if inbox.seen[r.FioRequestId] {
+			continue
+		}
+		inbox.seen[r.FioRequestId] = true
+		fresh = append(fresh, r)
+	}
+	return fresh, nil
+}
+
+// Watch polls for pending requests, calling handler once for each request it has not seen before. Requests that
+// are already pending are reported on the first poll. It returns when the context is done or a query fails.
+func (inbox *Inbox) Watch(ctx context.Context, interval time.Duration, filter *InboxFilter, handler func(*InboxRequest)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		fresh, err := inbox.newPending(filter)
+		if err != nil {
+			return err
+		}
+		for _, r := range fresh {
+			handler(r)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// WatchBlocks follows irreversible blocks, starting at startBlock, or the current last irreversible block if 0.
+// Pending requests are only queried when a block contains a newfundsreq for one of the account's FIO addresses, so
+// this is much lighter on the API than Watch with a short interval. Addresses are loaded with Account.GetNames if
+// the account has none.
+func (inbox *Inbox) WatchBlocks(ctx context.Context, startBlock uint32, filter *InboxFilter, handler func(*InboxRequest)) error {
+	if len(inbox.Account.Addresses) == 0 {
+		if _, _, err := inbox.Account.GetNames(inbox.Api); err != nil {
+			return err
+		}
+	}
+	addresses := make(map[string]bool)
+	for _, a := range inbox.Account.Addresses {
+		addresses[a.FioAddress] = true
+	}
+
+	// mark everything currently pending as seen, only new requests are reported
+	if _, err := inbox.newPending(filter); err != nil {
+		return err
+	}
+
+	return inbox.Api.FollowBlocks(ctx, startBlock, 500*time.Millisecond, func(block *eos.BlockResp) error {
+		if !BlockHasRequestFor(block, addresses) {
+			return nil
+		}
+		fresh, err := inbox.newPending(filter)
+		if err != nil {
+			return err
+		}
+		for _, r := range fresh {
+			handler(r)
+		}
+		return nil
+	})
+}
+
+// BlockHasRequestFor checks if a block contains a newfundsreq action with one of the payer addresses
+func BlockHasRequestFor(block *eos.BlockResp, payers map[string]bool) bool {
+	for _, action := range BlockActions(block) {
+		if action.Account != "fio.reqobt" || action.Name != "newfundsreq" {
+			continue
+		}
+		payer, err := eos.NewDecoder(action.HexData).ReadString()
+		if err != nil {
+			continue
+		}
+		if payers[payer] {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFee returns an error if the fee from get_fee is higher than the max_fee from GetMaxFee
+func (inbox *Inbox) checkFee(feeName string, fioAddress string) error {
+	fee, err := inbox.Api.GetFee(fioAddress, feeName)
+	if err != nil {
+		return err
+	}
+	if maxFee := Tokens(GetMaxFee(feeName)); fee > maxFee {
+		return fmt.Errorf("%s fee is %d but max_fee is %d, call UpdateMaxFees to refresh the fee schedule", feeName, fee, maxFee)
+	}
+	return nil
+}
+
+// checkPending ensures a request has not been responded to, rejected, or cancelled. The status row is checked
+// on-chain rather than trusting the InboxRequest, which may be stale.
+func (inbox *Inbox) checkPending(req *InboxRequest) error {
+	if req == nil {
+		return errors.New("request is nil")
+	}
+	if req.Status != "" && req.Status != RequestStatusRequested {
+		return fmt.Errorf("request %d is not pending, status is %s", req.FioRequestId, req.Status)
+	}
+	found, status, err := inbox.Api.GetFioRequestStatus(req.FioRequestId)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("request %d is not pending, status is %d", req.FioRequestId, status.Status)
+	}
+	return nil
+}
+
+// RecordForRequest builds the recordobt action for paying an incoming request. The amount, chain and token codes,
+// and the payee's public address are copied from the request, payerAddress is the public address the payment was
+// sent from. The content is encrypted to the payee's key. If status is empty "sent_to_blockchain" is used.
+func (inbox *Inbox) RecordForRequest(req *InboxRequest, payerAddress string, obtId string, status string) (*Action, error) {
+	if req == nil || !req.Incoming {
+		return nil, errors.New("can only respond to incoming requests")
+	}
+	if req.Request == nil {
+		if req.DecryptErr != nil {
+			return nil, fmt.Errorf("request content was not decrypted: %s", req.DecryptErr)
+		}
+		return nil, errors.New("request content was not decrypted")
+	}
+	if status == "" {
+		status = RequestStatusPaid
+	}
+	content, err := ObtRecordContent{
+		PayerPublicAddress: payerAddress,
+		PayeePublicAddress: req.Request.PayeePublicAddress,
+		Amount:             req.Request.Amount,
+		ChainCode:          req.Request.ChainCode,
+		TokenCode:          req.Request.TokenCode,
+		Status:             status,
+		ObtId:              obtId,
+		Hash:               req.Request.Hash,
+		OfflineUrl:         req.Request.OfflineUrl,
+	}.Encrypt(inbox.Account, req.PayeeFioPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecordSend(
+		inbox.Account.Actor, strconv.FormatUint(req.FioRequestId, 10), req.PayerFioAddress, req.PayeeFioAddress, content,
+	), nil
+}
+
+// RespondToRequest records the transaction id (obtId) from the other chain as the response to an incoming request,
+// after checking that it is still pending. Sending the funds on the other chain is left to the caller.
+func (inbox *Inbox) RespondToRequest(req *InboxRequest, payerAddress string, obtId string, status string) (*eos.PushTransactionFullResp, error) {
+	if err := inbox.checkPending(req); err != nil {
+		return nil, err
+	}
+	if err := inbox.checkFee(FeeRecordObtData, req.PayerFioAddress); err != nil {
+		return nil, err
+	}
+	action, err := inbox.RecordForRequest(req, payerAddress, obtId, status)
+	if err != nil {
+		return nil, err
+	}
+	return inbox.Api.SignPushActions(action)
+}
+
+// RejectRequest rejects an incoming request after checking that it is still pending
+func (inbox *Inbox) RejectRequest(req *InboxRequest) (*eos.PushTransactionFullResp, error) {
+	if req == nil || !req.Incoming {
+		return nil, errors.New("can only reject incoming requests")
+	}
+	if err := inbox.checkPending(req); err != nil {
+		return nil, err
+	}
+	if err := inbox.checkFee(FeeRejectFundsRequest, req.PayerFioAddress); err != nil {
+		return nil, err
+	}
+	return inbox.Api.SignPushActions(NewRejectFndReq(inbox.Account.Actor, strconv.FormatUint(req.FioRequestId, 10)))
+}
+
+// CancelRequest cancels an outgoing request after checking that it is still pending
+func (inbox *Inbox) CancelRequest(req *InboxRequest) (*eos.PushTransactionFullResp, error) {
+	if req == nil || req.Incoming {
+		return nil, errors.New("can only cancel outgoing requests")
+	}
+	if err := inbox.checkPending(req); err != nil {
+		return nil, err
+	}
+	if err := inbox.checkFee(FeeCancelFundsRequest, req.PayeeFioAddress); err != nil {
+		return nil, err
+	}
+	return inbox.Api.SignPushActions(NewCancelFndReq(inbox.Account.Actor, req.FioRequestId))
+}
+
+// PaymentRecord is an OBT record with the content decrypted
+type PaymentRecord struct {
+	ObtDataRecord
+
+	// Incoming is true if the account is the payee
+	Incoming bool              `json:"incoming"`
+	Record   *ObtRecordContent `json:"record,omitempty"`
+
+	DecryptErr error `json:"-"`
+}
+
+// History returns the account's OBT records, including records sent in response to a request and records sent
+// without one, so it is a complete payment history.
+func (inbox *Inbox) History(filter *InboxFilter) ([]*PaymentRecord, error) {
+	if inbox.Account == nil || inbox.Api == nil {
+		return nil, errors.New("inbox requires an account and api")
+	}
+	pageSize := inbox.PageSize
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+	result := make([]*PaymentRecord, 0)
+	for offset := 0; ; {
+		page, _, err := inbox.Api.GetObtData(inbox.Account.PubKey, pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range page.Records {
+			if !filter.matchStatus(rec.Status) {
+				continue
+			}
+			p := &PaymentRecord{ObtDataRecord: rec, Incoming: rec.PayeeFioPublicKey == inbox.Account.PubKey}
+			p.Record, p.DecryptErr = rec.Decrypt(inbox.Account)
+			if p.Record == nil {
+				if filter.matchCodes(false, "", "") {
+					result = append(result, p)
+				}
+				continue
+			}
+			if filter.matchCodes(true, p.Record.TokenCode, p.Record.ChainCode) {
+				result = append(result, p)
+			}
+		}
+		offset += len(page.Records)
+		if page.More == 0 || len(page.Records) == 0 {
+			break
+		}
+	}
+	return result, nil
+}
+
+// RecordForPayment builds the recordobt action for a payment that was not requested. The record is encrypted to the
+// payee's FIO public key, which is looked up from the payee's FIO address.
+func (inbox *Inbox) RecordForPayment(payerFio string, payeeFio string, record ObtRecordContent) (*Action, error) {
+	payeeKey, found, err := inbox.Api.PubAddressLookup(Address(payeeFio), "FIO", "FIO")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no FIO public key found for %s", payeeFio)
+	}
+	if record.Status == "" {
+		record.Status = RequestStatusPaid
+	}
+	content, err := record.Encrypt(inbox.Account, payeeKey.PublicAddress)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecordObt(inbox.Account.Actor, payerFio, payeeFio, content), nil
+}
+
+// RecordPayment records a payment that was not requested, see RecordForPayment
+func (inbox *Inbox) RecordPayment(payerFio string, payeeFio string, record ObtRecordContent) (*eos.PushTransactionFullResp, error) {
+	if err := inbox.checkFee(FeeRecordObtData, payerFio); err != nil {
+		return nil, err
+	}
+	action, err := inbox.RecordForPayment(payerFio, payeeFio, record)
+	if err != nil {
+		return nil, err
+	}
+	return inbox.Api.SignPushActions(action)
+}
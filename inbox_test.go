@@ -0,0 +1,417 @@
+package fio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// inboxServer is a fake node holding requests between two accounts
+type inboxServer struct {
+	*httptest.Server
+	pending  []RequestStatus
+	received []RequestStatus
+	sent     []RequestStatus
+	statuses map[uint64]FundsRequestStatusResp
+	obtData  []ObtDataRecord
+}
+
+func newInboxServer(me *Account, other *Account) (*inboxServer, error) {
+	s := &inboxServer{statuses: make(map[uint64]FundsRequestStatusResp)}
+	newReq := func(id uint64, incoming bool, token string, status string) (RequestStatus, error) {
+		from, to := other, me
+		payer, payee := me, other
+		if !incoming {
+			from, to = me, other
+			payer, payee = other, me
+		}
+		content, err := ObtRequestContent{
+			PayeePublicAddress: "payee-" + token,
+			Amount:             "1.5",
+			ChainCode:          token,
+			TokenCode:          token,
+			Memo:               fmt.Sprintf("request %d", id),
+		}.Encrypt(from, to.PubKey)
+		if err != nil {
+			return RequestStatus{}, err
+		}
+		return RequestStatus{
+			FioRequestId:      id,
+			PayerFioAddress:   "payer@fiotestnet",
+			PayeeFioAddress:   "payee@fiotestnet",
+			PayerFioPublicKey: payer.PubKey,
+			PayeeFioPublicKey: payee.PubKey,
+			Content:           content,
+			Status:            status,
+		}, nil
+	}
+
+	incomingPending, err := newReq(1, true, "BTC", RequestStatusRequested)
+	if err != nil {
+		return nil, err
+	}
+	incomingRejected, err := newReq(2, true, "ETH", RequestStatusRejected)
+	if err != nil {
+		return nil, err
+	}
+	outgoingPaid, err := newReq(3, false, "BTC", RequestStatusPaid)
+	if err != nil {
+		return nil, err
+	}
+	outgoingCancelled, err := newReq(4, false, "ETH", RequestStatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+	s.pending = []RequestStatus{incomingPending}
+	s.received = []RequestStatus{incomingPending, incomingRejected}
+	s.sent = []RequestStatus{outgoingPaid, outgoingCancelled}
+
+	// the payer (other) records the payment, encrypted to the payee (me)
+	record, err := ObtRecordContent{
+		PayerPublicAddress: "payer-BTC",
+		PayeePublicAddress: "payee-BTC",
+		Amount:             "1.5",
+		ChainCode:          "BTC",
+		TokenCode:          "BTC",
+		Status:             "sent_to_blockchain",
+		ObtId:              "abcdef",
+	}.Encrypt(other, me.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	s.statuses[3] = FundsRequestStatusResp{Id: 1, FioRequestId: 3, Status: 2, Metadata: record}
+	s.statuses[2] = FundsRequestStatusResp{Id: 0, FioRequestId: 2, Status: 1}
+
+	// obt data has the record for request 3, and a payment sent by me without a request
+	unsolicited, err := ObtRecordContent{
+		PayerPublicAddress: "payer-ETH",
+		PayeePublicAddress: "payee-ETH",
+		Amount:             "3",
+		ChainCode:          "ETH",
+		TokenCode:          "ETH",
+		Status:             "sent_to_blockchain",
+		ObtId:              "0x123",
+	}.Encrypt(me, other.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	s.obtData = []ObtDataRecord{
+		{PayerFioAddress: "payer@fiotestnet", PayeeFioAddress: "payee@fiotestnet", PayerFioPublicKey: other.PubKey,
+			PayeeFioPublicKey: me.PubKey, Content: record, FioRequestId: 3, Status: RequestStatusPaid},
+		{PayerFioAddress: "payer@fiotestnet", PayeeFioAddress: "payee@fiotestnet", PayerFioPublicKey: me.PubKey,
+			PayeeFioPublicKey: other.PubKey, Content: unsolicited, Status: RequestStatusPaid},
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		q := getPendingFioNamesRequest{}
+		_ = json.Unmarshal(body, &q)
+		var list []RequestStatus
+		switch r.URL.Path {
+		case "/v1/chain/get_pending_fio_requests":
+			list = s.pending
+		case "/v1/chain/get_received_fio_requests":
+			list = s.received
+		case "/v1/chain/get_sent_fio_requests":
+			list = s.sent
+		case "/v1/chain/get_cancelled_fio_requests":
+			list = []RequestStatus{outgoingCancelled}
+		case "/v1/chain/get_pub_address":
+			if strings.Contains(string(body), `"chain_code":"FIO"`) {
+				_, _ = w.Write([]byte(`{"public_address":"` + other.PubKey + `"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"public_address":"payer-BTC"}`))
+			return
+		case "/v1/chain/get_obt_data":
+			j, _ := json.Marshal(ObtDataResponse{Records: s.obtData})
+			_, _ = w.Write(j)
+			return
+		case "/v1/chain/get_fee":
+			_, _ = w.Write([]byte(`{"fee":999000000000}`))
+			return
+		case "/v1/chain/get_table_rows":
+			req := eos.GetTableRowsRequest{}
+			_ = json.Unmarshal(body, &req)
+			var id uint64
+			_, _ = fmt.Sscanf(req.LowerBound, "%d", &id)
+			rows := make([]FundsRequestStatusResp, 0)
+			if st, ok := s.statuses[id]; ok {
+				rows = append(rows, st)
+			}
+			j, _ := json.Marshal(rows)
+			_, _ = w.Write([]byte(`{"more":false,"rows":` + string(j) + `}`))
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// serve one request per page to exercise paging
+		resp := PendingFioRequestsResponse{Requests: []RequestStatus{}}
+		if q.Offset < len(list) {
+			resp.Requests = list[q.Offset : q.Offset+1]
+			resp.More = len(list) - q.Offset - 1
+		}
+		j, _ := json.Marshal(resp)
+		_, _ = w.Write(j)
+	}))
+	return s, nil
+}
+
+func TestInbox(t *testing.T) {
+	me, err := NewRandomAccount()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	other, err := NewRandomAccount()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	server, err := newInboxServer(me, other)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer server.Close()
+	inbox := NewInbox(me, &API{*eos.New(server.URL)})
+
+	pending, err := inbox.Pending(nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(pending) != 1 || pending[0].Request == nil || pending[0].Request.Memo != "request 1" || !pending[0].Incoming {
+		t.Errorf("did not get decrypted pending request: %+v", pending)
+	}
+
+	incoming, err := inbox.Incoming(nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(incoming) != 2 {
+		t.Errorf("expected 2 incoming requests, got %d", len(incoming))
+	}
+
+	outgoing, err := inbox.Outgoing(&InboxFilter{TokenCode: "btc"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(outgoing) != 1 || outgoing[0].Request == nil || outgoing[0].Request.TokenCode != "BTC" {
+		t.Errorf("token filter failed: %+v", outgoing)
+	}
+
+	paid, err := inbox.Paid(nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(paid) != 1 || paid[0].Incoming || paid[0].Record == nil || paid[0].Record.ObtId != "abcdef" {
+		t.Errorf("paid request was not joined to its record: %+v", paid)
+	} else if paid[0].Response == nil || paid[0].Response.Status != 2 {
+		t.Error("paid request should include the status row")
+	}
+
+	rejected, err := inbox.Rejected(&InboxFilter{ChainCode: "ETH"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(rejected) != 1 || rejected[0].FioRequestId != 2 || rejected[0].Record != nil {
+		t.Errorf("rejected request not found: %+v", rejected)
+	}
+
+	cancelled, err := inbox.Cancelled(nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(cancelled) != 1 || cancelled[0].Request == nil || cancelled[0].Request.Memo != "request 4" {
+		t.Errorf("cancelled request not decrypted: %+v", cancelled)
+	}
+
+	// a third party can list, but not decrypt
+	stranger, _ := NewRandomAccount()
+	strangerInbox := NewInbox(stranger, inbox.Api)
+	pending, err = strangerInbox.Pending(nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(pending) != 1 || pending[0].DecryptErr == nil || pending[0].Request != nil {
+		t.Error("should not have decrypted another account's request")
+	}
+}
+
+func TestInbox_Watch(t *testing.T) {
+	me, _ := NewRandomAccount()
+	other, _ := NewRandomAccount()
+	server, err := newInboxServer(me, other)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer server.Close()
+	inbox := NewInbox(me, &API{*eos.New(server.URL)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	events := make([]uint64, 0)
+	err = inbox.Watch(ctx, 10*time.Millisecond, nil, func(r *InboxRequest) {
+		events = append(events, r.FioRequestId)
+	})
+	if err != context.DeadlineExceeded {
+		t.Error("watch should stop when the context is done, got:", err)
+	}
+	if len(events) != 1 || events[0] != 1 {
+		t.Errorf("expected a single event for request 1, got %v", events)
+	}
+}
+
+func TestBlockHasRequestFor(t *testing.T) {
+	tx := eos.NewTransaction([]*eos.Action{
+		NewFundsReq("aloha", "payer@fiotestnet", "payee@fiotestnet", "content").ToEos(),
+	}, &eos.TxOptions{})
+	packed, err := eos.NewSignedTransaction(tx).Pack(eos.CompressionNone)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	block := &eos.BlockResp{}
+	block.Transactions = []eos.TransactionReceipt{{Transaction: eos.TransactionWithID{Packed: packed}}}
+	if !BlockHasRequestFor(block, map[string]bool{"payer@fiotestnet": true}) {
+		t.Error("did not find request for payer")
+	}
+	if BlockHasRequestFor(block, map[string]bool{"payee@fiotestnet": true}) {
+		t.Error("should only match the payer address")
+	}
+}
+
+func TestInbox_RecordForRequest(t *testing.T) {
+	me, _ := NewRandomAccount()
+	other, _ := NewRandomAccount()
+	server, err := newInboxServer(me, other)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer server.Close()
+	inbox := NewInbox(me, &API{*eos.New(server.URL)})
+	incoming, err := inbox.Incoming(nil)
+	if err != nil || len(incoming) != 2 {
+		t.Error("could not get incoming requests", err)
+		return
+	}
+
+	if err = inbox.checkPending(incoming[0]); err != nil {
+		t.Error(err)
+	}
+	if err = inbox.checkPending(incoming[1]); err == nil {
+		t.Error("rejected request should not be pending")
+	}
+	incoming[1].Status = ""
+	if err = inbox.checkPending(incoming[1]); err == nil {
+		t.Error("should use the on-chain status when checking if pending")
+	}
+	if _, err = inbox.CancelRequest(incoming[0]); err == nil {
+		t.Error("should not cancel an incoming request")
+	}
+
+	action, err := inbox.RecordForRequest(incoming[0], "payer-BTC", "0xabc", "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	rs, ok := action.Data.(RecordSend)
+	if !ok {
+		t.Error("action data should be a RecordSend")
+		return
+	}
+	if rs.FioRequestId != "1" || rs.Actor != string(me.Actor) || rs.PayeeFioAddress != "payee@fiotestnet" {
+		t.Errorf("record has wrong fields: %+v", rs)
+	}
+	if rs.MaxFee != Tokens(GetMaxFee(FeeRecordObtData)) {
+		t.Error("max fee should come from the fee schedule, got", rs.MaxFee)
+	}
+	// get_fee is higher than the schedule
+	if err = inbox.checkFee(FeeRecordObtData, "payer@fiotestnet"); err == nil || !strings.Contains(err.Error(), "UpdateMaxFees") {
+		t.Error("a stale fee schedule should be an error, got", err)
+	}
+	decrypted, err := DecryptContent(other, me.PubKey, rs.Content, ObtResponseType)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	rec := decrypted.Record
+	if rec.ObtId != "0xabc" || rec.Status != RequestStatusPaid || rec.Amount != "1.5" || rec.TokenCode != "BTC" ||
+		rec.PayeePublicAddress != "payee-BTC" || rec.PayerPublicAddress != "payer-BTC" {
+		t.Errorf("record content was not copied from the request: %+v", rec)
+	}
+
+	outgoing, _ := inbox.Outgoing(nil)
+	if _, err = inbox.RecordForRequest(outgoing[0], "payer-BTC", "0xabc", ""); err == nil {
+		t.Error("should not respond to an outgoing request")
+	}
+}
+
+func TestInbox_History(t *testing.T) {
+	me, _ := NewRandomAccount()
+	other, _ := NewRandomAccount()
+	server, err := newInboxServer(me, other)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer server.Close()
+	inbox := NewInbox(me, &API{*eos.New(server.URL)})
+
+	history, err := inbox.History(nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(history) != 2 {
+		t.Errorf("expected 2 records, got %d", len(history))
+		return
+	}
+	if !history[0].Incoming || history[0].Record == nil || history[0].Record.ObtId != "abcdef" {
+		t.Errorf("received record was not decrypted: %+v", history[0])
+	}
+	if history[1].Incoming || history[1].Record == nil || history[1].Record.ObtId != "0x123" {
+		t.Errorf("sent record was not decrypted: %+v", history[1])
+	}
+	eth, err := inbox.History(&InboxFilter{ChainCode: "eth"})
+	if err != nil || len(eth) != 1 || eth[0].FioRequestId != 0 {
+		t.Error("chain filter failed", err)
+	}
+
+	action, err := inbox.RecordForPayment("payer@fiotestnet", "payee@fiotestnet", ObtRecordContent{
+		Amount: "1", ChainCode: "BTC", TokenCode: "BTC", ObtId: "txid",
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	rs := action.Data.(RecordSend)
+	if rs.FioRequestId != "" || rs.PayeeFioAddress != "payee@fiotestnet" {
+		t.Errorf("unsolicited record should not have a request id: %+v", rs)
+	}
+	rec, err := DecryptContent(other, me.PubKey, rs.Content, ObtResponseType)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if rec.Record.ObtId != "txid" || rec.Record.Status != RequestStatusPaid {
+		t.Errorf("record content is wrong: %+v", rec.Record)
+	}
+}
@@ -0,0 +1,193 @@
+// Package message sends signed and encrypted off-chain messages between FIO addresses, for example invoices or KYC
+// payloads, using the same shared-secret encryption as FIO request content (see the ecies package).
+//
+// An Envelope is self-describing JSON: it names its version and scheme, the sender and recipient addresses and keys,
+// and the content type of the encrypted payload. The sender signs a digest of every field, so an envelope can be
+// checked against the sender's FIO key before it is decrypted.
+package message
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go"
+	"github.com/fioprotocol/fio-go/eos"
+	"github.com/fioprotocol/fio-go/eos/ecc"
+	"time"
+)
+
+const (
+	Version = 1
+
+	// Scheme describes how the payload is encrypted, see the ecies package.
+	Scheme = "fio-ecies-aes256cbc-hmacsha256"
+)
+
+// Envelope is a signed, encrypted message. Payload is the base64 ecies ciphertext, and Signature covers every other
+// field.
+type Envelope struct {
+	Version     uint8     `json:"version"`
+	Scheme      string    `json:"scheme"`
+	ContentType string    `json:"content_type"`
+	From        string    `json:"from"`
+	FromKey     string    `json:"from_key"`
+	To          string    `json:"to"`
+	ToKey       string    `json:"to_key"`
+	Time        time.Time `json:"time"`
+	Payload     string    `json:"payload"`
+	Signature   string    `json:"signature"`
+}
+
+// signedFields is serialized with the eos binary encoder to build the signing digest, this keeps the digest
+// independent of JSON formatting.
+type signedFields struct {
+	Version     uint8
+	Scheme      string
+	ContentType string
+	From        string
+	FromKey     string
+	To          string
+	ToKey       string
+	Time        int64
+	Payload     string
+}
+
+// Seal encrypts and signs a payload for a FIO address, the recipient's key is found with
+// PubAddressLookup(to, "FIO", "FIO").
+func Seal(api *fio.API, sender *fio.Account, from fio.Address, to fio.Address, contentType string, payload []byte) (*Envelope, error) {
+	if !to.Valid() {
+		return nil, errors.New("invalid recipient address")
+	}
+	pub, found, err := api.PubAddressLookup(to, "FIO", "FIO")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no FIO public key found for %s", to)
+	}
+	return SealToKey(sender, from, to, pub.PublicAddress, contentType, payload)
+}
+
+// SealToKey is the same as Seal, but uses a known public key instead of looking it up.
+func SealToKey(sender *fio.Account, from fio.Address, to fio.Address, toKey string, contentType string, payload []byte) (*Envelope, error) {
+	if sender == nil || sender.KeyBag == nil || len(sender.KeyBag.Keys) == 0 {
+		return nil, errors.New("sender does not have a private key")
+	}
+	if !from.Valid() {
+		return nil, errors.New("invalid sender address")
+	}
+	encrypted, err := fio.EciesEncrypt(sender, toKey, payload, nil)
+	if err != nil {
+		return nil, err
+	}
+	e := &Envelope{
+		Version:     Version,
+		Scheme:      Scheme,
+		ContentType: contentType,
+		From:        string(from),
+		FromKey:     sender.PubKey,
+		To:          string(to),
+		ToKey:       toKey,
+		Time:        time.Now().UTC().Truncate(time.Second),
+		Payload:     encrypted,
+	}
+	digest, err := e.Digest()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := sender.KeyBag.Keys[0].Sign(digest)
+	if err != nil {
+		return nil, err
+	}
+	e.Signature = sig.String()
+	return e, nil
+}
+
+// Parse reads an envelope, checking that the version and scheme are supported.
+func Parse(data []byte) (*Envelope, error) {
+	e := &Envelope{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+	if e.Version != Version {
+		return nil, fmt.Errorf("unsupported envelope version %d", e.Version)
+	}
+	if e.Scheme != Scheme {
+		return nil, fmt.Errorf("unsupported encryption scheme %s", e.Scheme)
+	}
+	return e, nil
+}
+
+// Digest is the sha256 hash that is signed by the sender
+func (e *Envelope) Digest() ([]byte, error) {
+	bin, err := eos.MarshalBinary(signedFields{
+		Version:     e.Version,
+		Scheme:      e.Scheme,
+		ContentType: e.ContentType,
+		From:        e.From,
+		FromKey:     e.FromKey,
+		To:          e.To,
+		ToKey:       e.ToKey,
+		Time:        e.Time.Unix(),
+		Payload:     e.Payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(bin)
+	return h[:], nil
+}
+
+// Verify checks the signature against FromKey. It does not check that FromKey belongs to the From address, use
+// VerifySender for that.
+func (e *Envelope) Verify() error {
+	if e.Signature == "" {
+		return errors.New("envelope is not signed")
+	}
+	pub, err := ecc.NewPublicKey(e.FromKey)
+	if err != nil {
+		return fmt.Errorf("invalid sender key: %s", err)
+	}
+	sig, err := ecc.NewSignature(e.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %s", err)
+	}
+	digest, err := e.Digest()
+	if err != nil {
+		return err
+	}
+	if !sig.Verify(digest, pub) {
+		return errors.New("signature does not match sender key")
+	}
+	return nil
+}
+
+// VerifySender checks the signature, and that FromKey is the current FIO public key for the From address.
+func (e *Envelope) VerifySender(api *fio.API) error {
+	if err := e.Verify(); err != nil {
+		return err
+	}
+	pub, found, err := api.PubAddressLookup(fio.Address(e.From), "FIO", "FIO")
+	if err != nil {
+		return err
+	}
+	if !found || pub.PublicAddress != e.FromKey {
+		return fmt.Errorf("%s is not the FIO public key for %s", e.FromKey, e.From)
+	}
+	return nil
+}
+
+// Open verifies the signature and decrypts the payload.
+func (e *Envelope) Open(recipient *fio.Account) ([]byte, error) {
+	if recipient == nil {
+		return nil, errors.New("recipient is nil")
+	}
+	if recipient.PubKey != e.ToKey {
+		return nil, errors.New("envelope was not sent to this account")
+	}
+	if err := e.Verify(); err != nil {
+		return nil, err
+	}
+	return fio.EciesDecrypt(recipient, e.FromKey, e.Payload)
+}
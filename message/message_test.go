@@ -0,0 +1,102 @@
+package message
+
+import (
+	"encoding/json"
+	"github.com/fioprotocol/fio-go"
+	"github.com/fioprotocol/fio-go/eos"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// keyServer answers get_pub_address with a FIO key for each address
+func keyServer(keys map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chain/get_pub_address" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		req := struct {
+			FioAddress string `json:"fio_address"`
+		}{}
+		_ = json.Unmarshal(body, &req)
+		j, _ := json.Marshal(map[string]string{"public_address": keys[req.FioAddress]})
+		_, _ = w.Write(j)
+	}))
+}
+
+func TestEnvelope(t *testing.T) {
+	alice, _ := fio.NewRandomAccount()
+	bob, _ := fio.NewRandomAccount()
+	eve, _ := fio.NewRandomAccount()
+	server := keyServer(map[string]string{"alice@fio": alice.PubKey, "bob@fio": bob.PubKey})
+	defer server.Close()
+	api := &fio.API{API: *eos.New(server.URL)}
+
+	invoice := []byte(`{"invoice":42,"amount":"100.00 USD"}`)
+	e, err := Seal(api, alice, "alice@fio", "bob@fio", "application/json", invoice)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if e.ToKey != bob.PubKey || e.FromKey != alice.PubKey {
+		t.Error("envelope has the wrong keys")
+	}
+
+	// round trip through JSON
+	j, _ := json.Marshal(e)
+	e, err = Parse(j)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = e.VerifySender(api); err != nil {
+		t.Error(err)
+	}
+	opened, err := e.Open(bob)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(opened) != string(invoice) {
+		t.Error("payload did not decrypt")
+	}
+	if _, err = e.Open(eve); err == nil {
+		t.Error("should not open an envelope for another account")
+	}
+
+	// any change to a signed field must be detected
+	tampered := *e
+	tampered.ContentType = "text/plain"
+	if tampered.Verify() == nil {
+		t.Error("changed content type was not detected")
+	}
+	tampered = *e
+	tampered.From = "eve@fio"
+	if tampered.Verify() == nil {
+		t.Error("changed sender was not detected")
+	}
+
+	// a valid signature from a key that doesn't own the address
+	forged, err := SealToKey(eve, "alice@fio", "bob@fio", bob.PubKey, "text/plain", []byte("hi"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = forged.Verify(); err != nil {
+		t.Error(err)
+	}
+	if err = forged.VerifySender(api); err == nil {
+		t.Error("should not verify a sender using another key")
+	}
+
+	if _, err = Seal(api, alice, "alice@fio", "nobody@fio", "text/plain", []byte("hi")); err == nil {
+		t.Error("should not seal to an address without a FIO key")
+	}
+	if _, err = Parse([]byte(strings.Replace(string(j), `"version":1`, `"version":2`, 1))); err == nil {
+		t.Error("should not parse an unknown version")
+	}
+}
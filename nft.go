@@ -0,0 +1,328 @@
+package fio
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"io/ioutil"
+	"net/http"
+)
+
+// Limits enforced by the fio.address contract for NFT signatures
+const (
+	MaxNftsPerAction   = 3
+	MaxNftContractLen  = 128
+	MaxNftTokenIdLen   = 128
+	MaxNftUrlLen       = 128
+	MaxNftMetadataLen  = 128
+	nftHashLen         = 64
+	defaultNftPageSize = 100
+)
+
+// Nft is an NFT signature mapped to a FIO address. Hash is an optional hex sha256 of the asset, and Url and
+// Metadata are optional.
+type Nft struct {
+	ChainCode       string `json:"chain_code"`
+	ContractAddress string `json:"contract_address"`
+	TokenId         string `json:"token_id"`
+	Url             string `json:"url"`
+	Hash            string `json:"hash"`
+	Metadata        string `json:"metadata"`
+}
+
+// Validate checks the lengths and chain code before the contract does
+func (n Nft) Validate() error {
+	if err := n.validateId(); err != nil {
+		return err
+	}
+	switch {
+	case len(n.Url) > MaxNftUrlLen:
+		return fmt.Errorf("url is longer than %d characters", MaxNftUrlLen)
+	case len(n.Metadata) > MaxNftMetadataLen:
+		return fmt.Errorf("metadata is longer than %d characters", MaxNftMetadataLen)
+	case n.Hash != "":
+		if _, err := hex.DecodeString(n.Hash); err != nil || len(n.Hash) != nftHashLen {
+			return errors.New("hash must be a hex encoded sha256")
+		}
+	}
+	return nil
+}
+
+// validateId checks the fields that identify an NFT, shared by addnft and remnft
+func (n Nft) validateId() error {
+	switch {
+	case !ValidChainCode(n.ChainCode):
+		return fmt.Errorf("invalid chain code %q", n.ChainCode)
+	case n.ContractAddress == "":
+		return errors.New("contract address is required")
+	case len(n.ContractAddress) > MaxNftContractLen:
+		return fmt.Errorf("contract address is longer than %d characters", MaxNftContractLen)
+	case len(n.TokenId) > MaxNftTokenIdLen:
+		return fmt.Errorf("token id is longer than %d characters", MaxNftTokenIdLen)
+	}
+	return nil
+}
+
+// AddNft maps NFT signatures to a FIO address
+type AddNft struct {
+	FioAddress string          `json:"fio_address"`
+	Nfts       []Nft           `json:"nfts"`
+	MaxFee     uint64          `json:"max_fee"`
+	Actor      eos.AccountName `json:"actor"`
+	Tpid       string          `json:"tpid"`
+}
+
+// NewAddNft builds an addnft action, up to MaxNftsPerAction NFTs can be added at once
+func NewAddNft(actor eos.AccountName, fioAddress Address, nfts []Nft) (*Action, error) {
+	if len(nfts) == 0 || len(nfts) > MaxNftsPerAction {
+		return nil, fmt.Errorf("must add between 1 and %d nfts", MaxNftsPerAction)
+	}
+	if !fioAddress.Valid() {
+		return nil, errors.New("invalid fio address")
+	}
+	for i := range nfts {
+		if err := nfts[i].Validate(); err != nil {
+			return nil, fmt.Errorf("nft %d: %s", i, err)
+		}
+	}
+	return NewAction(
+		"fio.address", "addnft", actor,
+		AddNft{
+			FioAddress: string(fioAddress),
+			Nfts:       nfts,
+			MaxFee:     Tokens(GetMaxFee(FeeAddNft)),
+			Actor:      actor,
+			Tpid:       CurrentTpid(),
+		},
+	), nil
+}
+
+// NftId identifies an NFT to remove
+type NftId struct {
+	ChainCode       string `json:"chain_code"`
+	ContractAddress string `json:"contract_address"`
+	TokenId         string `json:"token_id"`
+}
+
+// RemNft removes NFT signatures from a FIO address
+type RemNft struct {
+	FioAddress string          `json:"fio_address"`
+	Nfts       []NftId         `json:"nfts"`
+	MaxFee     uint64          `json:"max_fee"`
+	Actor      eos.AccountName `json:"actor"`
+	Tpid       string          `json:"tpid"`
+}
+
+// NewRemNft builds a remnft action, up to MaxNftsPerAction NFTs can be removed at once
+func NewRemNft(actor eos.AccountName, fioAddress Address, nfts []NftId) (*Action, error) {
+	if len(nfts) == 0 || len(nfts) > MaxNftsPerAction {
+		return nil, fmt.Errorf("must remove between 1 and %d nfts", MaxNftsPerAction)
+	}
+	if !fioAddress.Valid() {
+		return nil, errors.New("invalid fio address")
+	}
+	for i, n := range nfts {
+		if err := (Nft{ChainCode: n.ChainCode, ContractAddress: n.ContractAddress, TokenId: n.TokenId}).validateId(); err != nil {
+			return nil, fmt.Errorf("nft %d: %s", i, err)
+		}
+	}
+	return NewAction(
+		"fio.address", "remnft", actor,
+		RemNft{
+			FioAddress: string(fioAddress),
+			Nfts:       nfts,
+			MaxFee:     Tokens(GetMaxFee(FeeRemoveNft)),
+			Actor:      actor,
+			Tpid:       CurrentTpid(),
+		},
+	), nil
+}
+
+// RemAllNfts removes every NFT signature from a FIO address
+type RemAllNfts struct {
+	FioAddress string          `json:"fio_address"`
+	MaxFee     uint64          `json:"max_fee"`
+	Actor      eos.AccountName `json:"actor"`
+	Tpid       string          `json:"tpid"`
+}
+
+// NewRemAllNfts builds a remallnfts action
+func NewRemAllNfts(actor eos.AccountName, fioAddress Address) (*Action, error) {
+	if !fioAddress.Valid() {
+		return nil, errors.New("invalid fio address")
+	}
+	return NewAction(
+		"fio.address", "remallnfts", actor,
+		RemAllNfts{
+			FioAddress: string(fioAddress),
+			MaxFee:     Tokens(GetMaxFee(FeeRemoveAllNfts)),
+			Actor:      actor,
+			Tpid:       CurrentTpid(),
+		},
+	), nil
+}
+
+// NftInfo is an NFT returned by the lookup endpoints, FioAddress is empty for get_nfts_fio_address
+type NftInfo struct {
+	FioAddress string `json:"fio_address,omitempty"`
+	Nft
+}
+
+// NftsResponse is a page of results from the NFT lookup endpoints, More is the number of remaining results
+type NftsResponse struct {
+	Nfts []*NftInfo `json:"nfts"`
+	More uint32     `json:"more"`
+}
+
+// getNfts posts to an NFT endpoint, a 404 means there are no results and returns an empty page
+func (api *API) getNfts(endpoint string, req interface{}) (*NftsResponse, error) {
+	j, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := api.HttpClient.Post(api.BaseURL+"/v1/chain/"+endpoint, "application/json", bytes.NewReader(j))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return &NftsResponse{Nfts: make([]*NftInfo, 0)}, nil
+	default:
+		return nil, fmt.Errorf("error %d: %s", resp.StatusCode, string(body))
+	}
+	nfts := &NftsResponse{}
+	err = json.Unmarshal(body, nfts)
+	if err != nil {
+		return nil, err
+	}
+	return nfts, nil
+}
+
+// GetNftsFioAddress gets a page of NFTs mapped to a FIO address
+func (api *API) GetNftsFioAddress(fioAddress Address, offset uint32, limit uint32) (*NftsResponse, error) {
+	return api.getNfts("get_nfts_fio_address", map[string]interface{}{
+		"fio_address": fioAddress,
+		"offset":      offset,
+		"limit":       limit,
+	})
+}
+
+// GetNftsContract gets a page of NFTs for a contract, tokenId is optional
+func (api *API) GetNftsContract(chainCode string, contractAddress string, tokenId string, offset uint32, limit uint32) (*NftsResponse, error) {
+	return api.getNfts("get_nfts_contract", map[string]interface{}{
+		"chain_code":       chainCode,
+		"contract_address": contractAddress,
+		"token_id":         tokenId,
+		"offset":           offset,
+		"limit":            limit,
+	})
+}
+
+// GetNftsHash gets a page of NFTs with a hash
+func (api *API) GetNftsHash(hash string, offset uint32, limit uint32) (*NftsResponse, error) {
+	return api.getNfts("get_nfts_hash", map[string]interface{}{
+		"hash":   hash,
+		"offset": offset,
+		"limit":  limit,
+	})
+}
+
+// NftIterator pages through NFT lookup results:
+//
+//	it := api.NftsByFioAddress("alice@fio", 0)
+//	for it.Next() {
+//		fmt.Println(it.Nft().ContractAddress)
+//	}
+//	if it.Err() != nil {
+//		...
+//	}
+type NftIterator struct {
+	fetch    func(offset uint32, limit uint32) (*NftsResponse, error)
+	pageSize uint32
+	offset   uint32
+	page     []*NftInfo
+	current  *NftInfo
+	done     bool
+	err      error
+}
+
+func newNftIterator(pageSize uint32, fetch func(offset uint32, limit uint32) (*NftsResponse, error)) *NftIterator {
+	if pageSize == 0 {
+		pageSize = defaultNftPageSize
+	}
+	return &NftIterator{fetch: fetch, pageSize: pageSize}
+}
+
+// Next advances to the next NFT, fetching another page when needed. It returns false at the end or on an error.
+func (it *NftIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if len(it.page) == 0 {
+		if it.done {
+			return false
+		}
+		resp, err := it.fetch(it.offset, it.pageSize)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page = resp.Nfts
+		it.offset += uint32(len(resp.Nfts))
+		it.done = resp.More == 0 || len(resp.Nfts) == 0
+		if len(it.page) == 0 {
+			return false
+		}
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Nft is the current result
+func (it *NftIterator) Nft() *NftInfo {
+	return it.current
+}
+
+// Err returns the error that stopped the iterator, if any
+func (it *NftIterator) Err() error {
+	return it.err
+}
+
+// All reads the remaining results
+func (it *NftIterator) All() ([]*NftInfo, error) {
+	all := make([]*NftInfo, 0)
+	for it.Next() {
+		all = append(all, it.Nft())
+	}
+	return all, it.Err()
+}
+
+// NftsByFioAddress iterates over the NFTs mapped to a FIO address, pageSize defaults to 100
+func (api *API) NftsByFioAddress(fioAddress Address, pageSize uint32) *NftIterator {
+	return newNftIterator(pageSize, func(offset uint32, limit uint32) (*NftsResponse, error) {
+		return api.GetNftsFioAddress(fioAddress, offset, limit)
+	})
+}
+
+// NftsByContract iterates over the NFTs for a contract, tokenId is optional
+func (api *API) NftsByContract(chainCode string, contractAddress string, tokenId string, pageSize uint32) *NftIterator {
+	return newNftIterator(pageSize, func(offset uint32, limit uint32) (*NftsResponse, error) {
+		return api.GetNftsContract(chainCode, contractAddress, tokenId, offset, limit)
+	})
+}
+
+// NftsByHash iterates over the NFTs with a hash
+func (api *API) NftsByHash(hash string, pageSize uint32) *NftIterator {
+	return newNftIterator(pageSize, func(offset uint32, limit uint32) (*NftsResponse, error) {
+		return api.GetNftsHash(hash, offset, limit)
+	})
+}
@@ -0,0 +1,87 @@
+package fio
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewAddNft(t *testing.T) {
+	valid := Nft{ChainCode: "ETH", ContractAddress: "0x06012c8cf97bead5deae237070f9587f8e7a266d", TokenId: "1"}
+	for _, test := range []struct {
+		name  string
+		nft   func(n Nft) Nft
+		valid bool
+	}{
+		{"ok", func(n Nft) Nft { return n }, true},
+		{"no token id", func(n Nft) Nft { n.TokenId = ""; return n }, true},
+		{"hash", func(n Nft) Nft { n.Hash = strings.Repeat("ab", 32); return n }, true},
+		{"chain code", func(n Nft) Nft { n.ChainCode = "ETH-MAIN"; return n }, false},
+		{"no contract", func(n Nft) Nft { n.ContractAddress = ""; return n }, false},
+		{"long contract", func(n Nft) Nft { n.ContractAddress = strings.Repeat("a", 129); return n }, false},
+		{"long token id", func(n Nft) Nft { n.TokenId = strings.Repeat("1", 129); return n }, false},
+		{"long url", func(n Nft) Nft { n.Url = "https://" + strings.Repeat("a", 128); return n }, false},
+		{"short hash", func(n Nft) Nft { n.Hash = "abcd"; return n }, false},
+		{"bad hash", func(n Nft) Nft { n.Hash = strings.Repeat("zz", 32); return n }, false},
+	} {
+		_, err := NewAddNft("aloha", "alice@fio", []Nft{test.nft(valid)})
+		if test.valid && err != nil {
+			t.Errorf("%s: %s", test.name, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%s: should be invalid", test.name)
+		}
+	}
+	if _, err := NewAddNft("aloha", "alice@fio", []Nft{valid, valid, valid, valid}); err == nil {
+		t.Error("should not add more than 3 nfts")
+	}
+	if _, err := NewRemNft("aloha", "alice@fio", []NftId{{ChainCode: "ETH"}}); err == nil {
+		t.Error("remnft should require a contract address")
+	}
+	if _, err := NewRemAllNfts("aloha", "not valid"); err == nil {
+		t.Error("remallnfts should require a valid address")
+	}
+}
+
+func TestNftIterator(t *testing.T) {
+	const total = 7
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		req := struct {
+			Hash   string `json:"hash"`
+			Offset int    `json:"offset"`
+			Limit  int    `json:"limit"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/v1/chain/get_nfts_hash" || req.Hash != "abc" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"No NFTS are mapped"}`))
+			return
+		}
+		nfts := make([]string, 0)
+		for i := req.Offset; i < total && i < req.Offset+req.Limit; i++ {
+			nfts = append(nfts, fmt.Sprintf(`{"fio_address":"alice@fio","chain_code":"ETH","contract_address":"0x1","token_id":"%d"}`, i))
+		}
+		_, _ = fmt.Fprintf(w, `{"nfts":[%s],"more":%d}`, strings.Join(nfts, ","), total-req.Offset-len(nfts))
+	}))
+	defer server.Close()
+
+	api := &API{*eos.New(server.URL)}
+	nfts, err := api.NftsByHash("abc", 3).All()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(nfts) != total || requests != 3 || nfts[6].TokenId != "6" || nfts[0].FioAddress != "alice@fio" {
+		t.Error("wrong results", len(nfts), requests)
+	}
+
+	it := api.NftsByFioAddress("bob@fio", 0)
+	if it.Next() || it.Err() != nil {
+		t.Error("not found should be an empty result", it.Err())
+	}
+}
@@ -0,0 +1,199 @@
+package fio
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"strings"
+	"sync"
+)
+
+// Built-in OBT content versions. ObtContentV1 is the format used by fiojs and the TypeScript SDK, where memo, hash and
+// offline_url are optional, ObtContentV1Strict has all fields required, which some older wallets used.
+const (
+	ObtContentV1       = "1.0"
+	ObtContentV1Strict = "1.0-strict"
+)
+
+// ObtContentSchema is a registered version of the ABI used to serialize the encrypted content of FIO requests and
+// records. The ABI holds a struct for each content type, for example new_funds_content and record_send_content.
+type ObtContentSchema struct {
+	Version string
+	Abi     *eos.ABI
+}
+
+// obtSchemas is ordered by priority when decrypting, newest registrations first
+var obtSchemas = make([]*ObtContentSchema, 0)
+var obtSchemaMux sync.RWMutex
+
+func init() {
+	// strict is registered first so that it is tried last
+	for _, s := range []struct{ version, abi string }{
+		{ObtContentV1Strict, ObtAbiJson},
+		{ObtContentV1, obtAbiJsonOmit},
+	} {
+		if err := RegisterObtContent(s.version, s.abi); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterObtContent adds a content schema, or replaces a schema with the same version. New schemas are tried
+// before existing ones when decrypting, so a newer format that is a superset of an older one should be registered
+// after it.
+func RegisterObtContent(version string, abiJson string) error {
+	if version == "" {
+		return errors.New("version is required")
+	}
+	abi, err := eos.NewABI(strings.NewReader(abiJson))
+	if err != nil {
+		return fmt.Errorf("invalid abi for content version %s: %s", version, err)
+	}
+	obtSchemaMux.Lock()
+	defer obtSchemaMux.Unlock()
+	schemas := []*ObtContentSchema{{Version: version, Abi: abi}}
+	for _, s := range obtSchemas {
+		if s.Version != version {
+			schemas = append(schemas, s)
+		}
+	}
+	obtSchemas = schemas
+	return nil
+}
+
+// UnregisterObtContent removes a content schema, it returns false if the version was not registered
+func UnregisterObtContent(version string) bool {
+	obtSchemaMux.Lock()
+	defer obtSchemaMux.Unlock()
+	for i, s := range obtSchemas {
+		if s.Version == version {
+			obtSchemas = append(obtSchemas[:i:i], obtSchemas[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ObtContentVersions lists the registered content versions, in the order they are tried when decrypting
+func ObtContentVersions() []string {
+	obtSchemaMux.RLock()
+	defer obtSchemaMux.RUnlock()
+	versions := make([]string, len(obtSchemas))
+	for i := range obtSchemas {
+		versions[i] = obtSchemas[i].Version
+	}
+	return versions
+}
+
+// GetObtContentSchema returns a registered schema by version
+func GetObtContentSchema(version string) (*ObtContentSchema, error) {
+	obtSchemaMux.RLock()
+	defer obtSchemaMux.RUnlock()
+	for _, s := range obtSchemas {
+		if s.Version == version {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("content version %s is not registered", version)
+}
+
+// Encode serializes content, which can be a struct or map, empty optional fields are omitted.
+func (s *ObtContentSchema) Encode(contentType string, content interface{}) ([]byte, error) {
+	st := s.Abi.StructForName(contentType)
+	if st == nil {
+		return nil, fmt.Errorf("content type %s is not in version %s", contentType, s.Version)
+	}
+	j, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]interface{})
+	if err = json.Unmarshal(j, &fields); err != nil {
+		return nil, err
+	}
+	for _, f := range st.Fields {
+		if !strings.HasSuffix(f.Type, "?") {
+			continue
+		}
+		if v, ok := fields[f.Name]; ok && (v == nil || v == "") {
+			delete(fields, f.Name)
+		}
+	}
+	if j, err = json.Marshal(fields); err != nil {
+		return nil, err
+	}
+	return s.Abi.EncodeTableRowTyped(contentType, j)
+}
+
+// Decode deserializes content to JSON. It is an error if the content does not use all of the data, which
+// prevents an older schema from matching content that has additional fields.
+func (s *ObtContentSchema) Decode(contentType string, bin []byte) (json.RawMessage, error) {
+	if s.Abi.StructForName(contentType) == nil {
+		return nil, fmt.Errorf("content type %s is not in version %s", contentType, s.Version)
+	}
+	decoded, err := s.Abi.DecodeTableRowTyped(contentType, bin)
+	if err != nil {
+		return nil, err
+	}
+	again, err := s.Abi.EncodeTableRowTyped(contentType, decoded)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(again, bin) {
+		return nil, fmt.Errorf("content does not match version %s", s.Version)
+	}
+	return decoded, nil
+}
+
+// EncryptObtContent serializes content using a specific content version and encrypts it. content can be any value
+// that marshals to a JSON object with the fields of the content type.
+func EncryptObtContent(from *Account, toPubKey string, version string, contentType string, content interface{}) (string, error) {
+	schema, err := GetObtContentSchema(version)
+	if err != nil {
+		return "", err
+	}
+	bin, err := schema.Encode(contentType, content)
+	if err != nil {
+		return "", err
+	}
+	return EciesEncrypt(from, toPubKey, bin, nil)
+}
+
+// DecryptObtContent decrypts content and tries each registered schema until one matches, returning the content as
+// JSON, and the version that matched.
+func DecryptObtContent(to *Account, fromPubKey string, encrypted string, contentType string) (content json.RawMessage, version string, err error) {
+	bin, err := EciesDecrypt(to, fromPubKey, encrypted)
+	if err != nil {
+		return nil, "", err
+	}
+	return decodeObtContent(bin, contentType)
+}
+
+func decodeObtContent(bin []byte, contentType string) (content json.RawMessage, version string, err error) {
+	obtSchemaMux.RLock()
+	schemas := make([]*ObtContentSchema, len(obtSchemas))
+	copy(schemas, obtSchemas)
+	obtSchemaMux.RUnlock()
+
+	for _, s := range schemas {
+		if s.Abi.StructForName(contentType) == nil {
+			continue
+		}
+		if content, err = s.Decode(contentType, bin); err == nil {
+			return content, s.Version, nil
+		}
+	}
+	return nil, "", fmt.Errorf("content did not match any registered version of %s", contentType)
+}
+
+// EncryptVersion is the same as Encrypt, but uses a specific content version
+func (req ObtRequestContent) EncryptVersion(from *Account, toPubKey string, version string) (content string, err error) {
+	return EncryptObtContent(from, toPubKey, version, ObtRequestType.String(), req)
+}
+
+// EncryptVersion is the same as Encrypt, but uses a specific content version
+func (rec ObtRecordContent) EncryptVersion(from *Account, toPubKey string, version string) (content string, err error) {
+	return EncryptObtContent(from, toPubKey, version, ObtResponseType.String(), rec)
+}
@@ -0,0 +1,246 @@
+package fio
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// registerTestObtContent registers a content schema for the duration of a test
+func registerTestObtContent(t *testing.T, version string, abiJson string) error {
+	if err := RegisterObtContent(version, abiJson); err != nil {
+		return err
+	}
+	t.Cleanup(func() { UnregisterObtContent(version) })
+	return nil
+}
+
+func TestObtContent_TypescriptVector(t *testing.T) {
+	// from the fiojs encryption tests: alice encrypts new_funds_content to bob with a fixed IV. The vector predates
+	// chain_code, so that version of the schema is registered here.
+	const expect = "f300888ca4f512cebdc0020ff0f7224c0db2984c4ad9afb12629f01a8c6a76328bbde17405655dc4e3cb30dad272996fb1dea8e662e640be193e25d41147a904c571b664a7381ab41ef062448ac1e205"
+	const fiojs = "fiojs-test"
+	err := registerTestObtContent(t, fiojs, `{
+		"version": "eosio::abi/1.0",
+		"structs": [{"name": "new_funds_content", "base": "", "fields": [
+			{"name": "payee_public_address", "type": "string"},
+			{"name": "amount", "type": "string"},
+			{"name": "token_code", "type": "string"},
+			{"name": "memo", "type": "string?"},
+			{"name": "hash", "type": "string?"},
+			{"name": "offline_url", "type": "string?"}
+		]}]
+	}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	alice, _ := NewAccountFromWif("5J9bWm2ThenDm3tjvmUgHtWCVMUdjRR1pxnRtnJjvKA4b2ut5WK")
+	bob, _ := NewAccountFromWif("5JoQtsKQuH8hC9MyvfJAqo6qmKLm8ePYNucs7tPu2YxG12trzBt")
+	req := ObtRequestContent{PayeePublicAddress: "purse.alice", Amount: "1", TokenCode: "fio.reqobt"}
+
+	schema, err := GetObtContentSchema(fiojs)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	bin, err := schema.Encode(ObtRequestType.String(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	iv, _ := hex.DecodeString(expect[:32])
+	encrypted, err := EciesEncrypt(alice, bob.PubKey, bin, iv)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	raw, _ := base64.StdEncoding.DecodeString(encrypted)
+	if hex.EncodeToString(raw) != expect {
+		t.Errorf("did not match typescript vector:\n%s\n%s", hex.EncodeToString(raw), expect)
+	}
+
+	decrypted, err := DecryptContent(bob, alice.PubKey, encrypted, ObtRequestType)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if decrypted.Version != fiojs || *decrypted.Request != req {
+		t.Errorf("wrong decrypted content: %s %+v", decrypted.Version, decrypted.Request)
+	}
+
+	// the current format should not match the vector
+	if !UnregisterObtContent(fiojs) {
+		t.Error("could not unregister", fiojs)
+	}
+	if _, err = DecryptContent(bob, alice.PubKey, encrypted, ObtRequestType); err == nil {
+		t.Error("vector should not decode with the current schemas")
+	}
+}
+
+// testObtAbiJsonV2 is a newer content version, new_funds_content has an additional payee_metadata field
+const testObtAbiJsonV2 = `{
+    "version": "eosio::abi/1.0",
+    "structs": [{
+        "name": "new_funds_content",
+        "base": "",
+        "fields": [
+            {"name": "payee_public_address", "type": "string"},
+            {"name": "amount", "type": "string"},
+            {"name": "chain_code", "type": "string"},
+            {"name": "token_code", "type": "string"},
+            {"name": "memo", "type": "string?"},
+            {"name": "hash", "type": "string?"},
+            {"name": "offline_url", "type": "string?"},
+            {"name": "payee_metadata", "type": "string?"}
+        ]
+    }]
+}`
+
+func TestObtContent_BuiltinVectors(t *testing.T) {
+	// fixed IV ciphertexts from alice to bob, decrypted with the built-in versions. record_obt_data_content has the
+	// same fields as record_send_content, so records are read as ObtResponseType.
+	alice, _ := NewAccountFromWif("5J9bWm2ThenDm3tjvmUgHtWCVMUdjRR1pxnRtnJjvKA4b2ut5WK")
+	bob, _ := NewAccountFromWif("5JoQtsKQuH8hC9MyvfJAqo6qmKLm8ePYNucs7tPu2YxG12trzBt")
+	req := ObtRequestContent{PayeePublicAddress: "0xab5801a7d398351b8be11c439e05c5b3259aec9b", Amount: "1.5", ChainCode: "ETH", TokenCode: "USDC", Memo: "invoice 42"}
+	rec := ObtRecordContent{
+		PayerPublicAddress: "0x71c7656ec7ab88b098defb751b7401b5f6d8976f",
+		PayeePublicAddress: "0xab5801a7d398351b8be11c439e05c5b3259aec9b",
+		Amount:             "1.5",
+		ChainCode:          "ETH",
+		TokenCode:          "USDC",
+		Status:             "sent_to_blockchain",
+		ObtId:              "0xf6eaddd3851923f6f9653838d3021c02ab123a4a6e4a9acc6f94ee3a4c0a6c39",
+		Memo:               "invoice 42",
+	}
+	for _, test := range []struct {
+		version   string
+		obtType   ObtType
+		content   interface{}
+		plain     string
+		encrypted string
+	}{
+		{
+			version:   ObtContentV1,
+			obtType:   ObtRequestType,
+			content:   req,
+			plain:     "2a30786162353830316137643339383335316238626531316334333965303563356233323539616563396203312e35034554480455534443010a696e766f6963652034320000",
+			encrypted: "8wCIjKT1Es69wAIP8PciTHrGUXZ9qZjUajwI7RXkvd4EQlbA+su07hYk55Lo8iaS4IFslwoiQ5fBPqIe1qo3AmvVW41iZogGDdctEyIqkgVatxAY07SjOAjcLNvPlikKSnAIiiLGF94CCCKtPwvgS3fLhtkNfB3cFi8avUcaa8A=",
+		},
+		{
+			version:   ObtContentV1,
+			obtType:   ObtResponseType,
+			content:   rec,
+			plain:     "2a3078373163373635366563376162383862303938646566623735316237343031623566366438393736662a30786162353830316137643339383335316238626531316334333965303563356233323539616563396203312e350345544804555344431273656e745f746f5f626c6f636b636861696e42307866366561646464333835313932336636663936353338333864333032316330326162313233613461366534613961636336663934656533613463306136633339010a696e766f6963652034320000",
+			encrypted: "8wCIjKT1Es69wAIP8PciTLoNiyccCOCaxe+JgU91ILsV99MX39OcentkMZF+BaB3MxNDKosyB6SC3V0r2r9r7KCKFATBDEpcFrJZST3jyUr8y4eHd9+1EVR1IViTrMeV2Kfgmk5in2F4jmpx5KsaP+K5QkYejoE1Uym4pVzbNa6TWXKimeCvMuwag4QjagbbusoIhd8L8lWEv5zZNblLdRfXtt0LLED5X/hgPk5F+j1tBA2zsnjvWwEMdYxsWzkrFCScSNs8i1YRPNpo+wA0LqSuW1+Tu58RubfqZZJPdPAG2LS3ExwEdDxzpK1UzQJcBKt6lKDaJIeK7l71aAyPHA==",
+		},
+		{
+			version:   ObtContentV1Strict,
+			obtType:   ObtRequestType,
+			content:   req,
+			plain:     "2a30786162353830316137643339383335316238626531316334333965303563356233323539616563396203312e350345544804555344430a696e766f6963652034320000",
+			encrypted: "8wCIjKT1Es69wAIP8PciTHrGUXZ9qZjUajwI7RXkvd4EQlbA+su07hYk55Lo8iaS4IFslwoiQ5fBPqIe1qo3Aq+CAZuktCK4h0u9Ba3R+FY/QV99X31fYFvgBlhnF2rpQrR81bp3qP/zIbYMH4bHMHCVFXQYy2Z+nhVAfratnQ8=",
+		},
+	} {
+		name := test.version + " " + test.obtType.String()
+		schema, err := GetObtContentSchema(test.version)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		bin, err := schema.Encode(test.obtType.String(), test.content)
+		if err != nil || hex.EncodeToString(bin) != test.plain {
+			t.Errorf("%s: wrong serialized content %x %v", name, bin, err)
+		}
+		plain, err := EciesDecrypt(bob, alice.PubKey, test.encrypted)
+		if err != nil || hex.EncodeToString(plain) != test.plain {
+			t.Errorf("%s: wrong plain text %x %v", name, plain, err)
+		}
+		decrypted, err := DecryptContent(bob, alice.PubKey, test.encrypted, test.obtType)
+		if err != nil {
+			t.Error(name, err)
+			continue
+		}
+		if decrypted.Version != test.version {
+			t.Errorf("%s: decrypted as version %s", name, decrypted.Version)
+		}
+		switch test.obtType {
+		case ObtRequestType:
+			if *decrypted.Request != test.content {
+				t.Errorf("%s: wrong request %+v", name, decrypted.Request)
+			}
+		case ObtResponseType:
+			if *decrypted.Record != test.content {
+				t.Errorf("%s: wrong record %+v", name, decrypted.Record)
+			}
+		}
+	}
+}
+
+func TestObtContent_Versions(t *testing.T) {
+	alice, _ := NewRandomAccount()
+	bob, _ := NewRandomAccount()
+	req := ObtRequestContent{PayeePublicAddress: "0xabc", Amount: "2.5", ChainCode: "ETH", TokenCode: "USDT", Memo: "invoice 1"}
+
+	strict, err := req.EncryptVersion(alice, bob.PubKey, ObtContentV1Strict)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	decrypted, err := DecryptContent(bob, alice.PubKey, strict, ObtRequestType)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if decrypted.Version != ObtContentV1Strict || *decrypted.Request != req {
+		t.Errorf("strict content did not round trip: %s %+v", decrypted.Version, decrypted.Request)
+	}
+
+	// a newer version with an additional field
+	const v2 = "test-2.0"
+	if err = registerTestObtContent(t, v2, testObtAbiJsonV2); err != nil {
+		t.Error(err)
+		return
+	}
+	if versions := ObtContentVersions(); len(versions) != 3 || versions[0] != v2 {
+		t.Error("new version should be tried first", versions)
+	}
+	content, err := EncryptObtContent(alice, bob.PubKey, v2, ObtRequestType.String(), map[string]string{
+		"payee_public_address": "0xabc", "amount": "2.5", "chain_code": "ETH", "token_code": "USDT",
+		"payee_metadata": "extra",
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	decrypted, err = DecryptContent(bob, alice.PubKey, content, ObtRequestType)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if decrypted.Version != v2 || decrypted.Request.Amount != "2.5" || !strings.Contains(string(decrypted.Content), `"payee_metadata":"extra"`) {
+		t.Errorf("v2 content not decoded: %s %s", decrypted.Version, string(decrypted.Content))
+	}
+
+	// older content must still be detected as the older version
+	v1, _ := req.Encrypt(alice, bob.PubKey)
+	decrypted, err = DecryptContent(bob, alice.PubKey, v1, ObtRequestType)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if decrypted.Version != ObtContentV1 {
+		t.Error("expected version 1.0, got", decrypted.Version)
+	}
+
+	if _, err = req.EncryptVersion(alice, bob.PubKey, "missing"); err == nil {
+		t.Error("should not encrypt to an unregistered version")
+	}
+	if err = RegisterObtContent("bad", "{"); err == nil {
+		t.Error("should not register an invalid abi")
+	}
+	if UnregisterObtContent("missing") {
+		t.Error("should not unregister a missing version")
+	}
+}
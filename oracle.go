@@ -0,0 +1,486 @@
+package fio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Oracle fee names returned by get_oracle_fees
+const (
+	OracleFeeWrapTokens = "wrap_fio_tokens"
+	OracleFeeWrapDomain = "wrap_fio_domain"
+)
+
+// OracleFees holds the total oracle fee for each wrap type in SUF, this is paid in addition to the FIO fee
+type OracleFees map[string]uint64
+
+// GetOracleFees calls /v1/chain/get_oracle_fees
+func (api *API) GetOracleFees() (OracleFees, error) {
+	resp, err := api.HttpClient.Post(api.BaseURL+"/v1/chain/get_oracle_fees", "application/json", bytes.NewReader([]byte("{}")))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error %d: %s", resp.StatusCode, string(body))
+	}
+	result := struct {
+		OracleFees []struct {
+			FeeName   string `json:"fee_name"`
+			FeeAmount uint64 `json:"fee_amount"`
+		} `json:"oracle_fees"`
+	}{}
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, err
+	}
+	fees := make(OracleFees)
+	for _, f := range result.OracleFees {
+		fees[f.FeeName] = f.FeeAmount
+	}
+	return fees, nil
+}
+
+// WrapTokens sends tokens to fio.oracle to be minted as wrapped FIO on another chain
+type WrapTokens struct {
+	Amount        uint64          `json:"amount"`
+	ChainCode     string          `json:"chain_code"`
+	PublicAddress string          `json:"public_address"`
+	MaxOracleFee  uint64          `json:"max_oracle_fee"`
+	MaxFee        uint64          `json:"max_fee"`
+	Tpid          string          `json:"tpid"`
+	Actor         eos.AccountName `json:"actor"`
+}
+
+// WrapDomain transfers a domain to fio.oracle to be minted as an NFT on another chain
+type WrapDomain struct {
+	FioDomain     string          `json:"fio_domain"`
+	ChainCode     string          `json:"chain_code"`
+	PublicAddress string          `json:"public_address"`
+	MaxOracleFee  uint64          `json:"max_oracle_fee"`
+	MaxFee        uint64          `json:"max_fee"`
+	Tpid          string          `json:"tpid"`
+	Actor         eos.AccountName `json:"actor"`
+}
+
+// checkWrapTarget validates the destination address on the other chain
+func checkWrapTarget(chainCode string, publicAddress string) error {
+	return TokenPubAddr{ChainCode: chainCode, TokenCode: chainCode, PublicAddress: publicAddress}.Validate()
+}
+
+// NewWrapTokens builds a wraptokens action, amount is in SUF. The max oracle fee is the current fee from
+// get_oracle_fees, the public address is checked with any registered PubAddressValidator for the chain.
+func (api *API) NewWrapTokens(actor eos.AccountName, amount uint64, chainCode string, publicAddress string) (*Action, error) {
+	return api.newWrapTokens(actor, amount, chainCode, publicAddress, Tokens(GetMaxFee(FeeWrapFioTokens)))
+}
+
+// NewWrapTokensAmount is NewWrapTokens using an exact Amount
+func (api *API) NewWrapTokensAmount(actor eos.AccountName, amount Amount, chainCode string, publicAddress string) (*Action, error) {
+	return api.newWrapTokens(actor, amount.Suf(), chainCode, publicAddress, GetMaxFeeAmount(FeeWrapFioTokens).Suf())
+}
+
+func (api *API) newWrapTokens(actor eos.AccountName, amount uint64, chainCode string, publicAddress string, maxFee uint64) (*Action, error) {
+	if amount == 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	if err := checkWrapTarget(chainCode, publicAddress); err != nil {
+		return nil, err
+	}
+	fees, err := api.GetOracleFees()
+	if err != nil {
+		return nil, err
+	}
+	oracleFee, ok := fees[OracleFeeWrapTokens]
+	if !ok {
+		return nil, errors.New("no oracle fee for wrapping tokens, are oracles registered?")
+	}
+	return NewAction(
+		"fio.oracle", "wraptokens", actor,
+		WrapTokens{
+			Amount:        amount,
+			ChainCode:     chainCode,
+			PublicAddress: publicAddress,
+			MaxOracleFee:  oracleFee,
+			MaxFee:        maxFee,
+			Tpid:          CurrentTpid(),
+			Actor:         actor,
+		},
+	), nil
+}
+
+// NewWrapDomain builds a wrapdomain action, see NewWrapTokens
+func (api *API) NewWrapDomain(actor eos.AccountName, domain string, chainCode string, publicAddress string) (*Action, error) {
+	if !ValidDomain(domain) {
+		return nil, errors.New("invalid domain")
+	}
+	if err := checkWrapTarget(chainCode, publicAddress); err != nil {
+		return nil, err
+	}
+	fees, err := api.GetOracleFees()
+	if err != nil {
+		return nil, err
+	}
+	oracleFee, ok := fees[OracleFeeWrapDomain]
+	if !ok {
+		return nil, errors.New("no oracle fee for wrapping domains, are oracles registered?")
+	}
+	return NewAction(
+		"fio.oracle", "wrapdomain", actor,
+		WrapDomain{
+			FioDomain:     domain,
+			ChainCode:     chainCode,
+			PublicAddress: publicAddress,
+			MaxOracleFee:  oracleFee,
+			MaxFee:        Tokens(GetMaxFee(FeeWrapFioDomain)),
+			Tpid:          CurrentTpid(),
+			Actor:         actor,
+		},
+	), nil
+}
+
+// UnwrapTokens is a vote by an oracle to release tokens that were burned on another chain, ObtId is the
+// transaction id on that chain. Tokens are released once every registered oracle has voted.
+type UnwrapTokens struct {
+	Amount     uint64          `json:"amount"`
+	ObtId      string          `json:"obt_id"`
+	FioAddress string          `json:"fio_address"`
+	Actor      eos.AccountName `json:"actor"`
+}
+
+// NewUnwrapTokens builds an unwraptokens action, only registered oracles can call it
+func NewUnwrapTokens(oracle eos.AccountName, amount uint64, obtId string, fioAddress Address) *Action {
+	return NewAction(
+		"fio.oracle", "unwraptokens", oracle,
+		UnwrapTokens{Amount: amount, ObtId: obtId, FioAddress: string(fioAddress), Actor: oracle},
+	)
+}
+
+// UnwrapDomain is an oracle vote to release a wrapped domain, see UnwrapTokens
+type UnwrapDomain struct {
+	FioDomain  string          `json:"fio_domain"`
+	ObtId      string          `json:"obt_id"`
+	FioAddress string          `json:"fio_address"`
+	Actor      eos.AccountName `json:"actor"`
+}
+
+// NewUnwrapDomain builds an unwrapdomain action, only registered oracles can call it
+func NewUnwrapDomain(oracle eos.AccountName, domain string, obtId string, fioAddress Address) *Action {
+	return NewAction(
+		"fio.oracle", "unwrapdomain", oracle,
+		UnwrapDomain{FioDomain: domain, ObtId: obtId, FioAddress: string(fioAddress), Actor: oracle},
+	)
+}
+
+// OracleLedgerEntry is a row in the fio.oracle oracleldgrs table, written for each wrap. NftName is the domain for
+// a domain wrap, otherwise Amount is the tokens wrapped.
+type OracleLedgerEntry struct {
+	Id         uint64          `json:"id"`
+	Actor      eos.AccountName `json:"actor"`
+	ChainCode  string          `json:"chaincode"`
+	PubAddress string          `json:"pubaddress"`
+	NftName    string          `json:"nftname"`
+	Amount     uint64          `json:"amount"`
+	Fees       uint64          `json:"fees"`
+	Timestamp  uint64          `json:"timestamp"`
+}
+
+// GetOracleLedger gets a page of the oracle ledger, starting at the primary key lowerBound
+func (api *API) GetOracleLedger(lowerBound uint64, limit uint32) (entries []*OracleLedgerEntry, more bool, err error) {
+	gtr, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:       "fio.oracle",
+		Scope:      "fio.oracle",
+		Table:      "oracleldgrs",
+		LowerBound: fmt.Sprintf("%d", lowerBound),
+		Limit:      limit,
+		KeyType:    "i64",
+		Index:      "1",
+		JSON:       true,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	entries = make([]*OracleLedgerEntry, 0)
+	err = json.Unmarshal(gtr.Rows, &entries)
+	if err != nil {
+		return nil, false, err
+	}
+	return entries, gtr.More, nil
+}
+
+// GetOracleLedgerByActor gets the ledger entries for an account's wraps, oldest first, using the actor index
+func (api *API) GetOracleLedgerByActor(actor eos.AccountName) ([]*OracleLedgerEntry, error) {
+	entries := make([]*OracleLedgerEntry, 0)
+	err := api.getAllIndexRows(eos.GetTableRowsRequest{
+		Code:       "fio.oracle",
+		Scope:      "fio.oracle",
+		Table:      "oracleldgrs",
+		LowerBound: string(actor),
+		UpperBound: string(actor),
+		Limit:      1000,
+		KeyType:    "name",
+		Index:      "2",
+		JSON:       true,
+	}, &entries, func(i int) uint64 {
+		return entries[i].Id
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// OracleVote is a row in the fio.oracle oravotes table, tracking the oracles that have voted for an unwrap
+type OracleVote struct {
+	Id         uint64            `json:"id"`
+	Voters     []eos.AccountName `json:"voters"`
+	ObtId      string            `json:"obt_id"`
+	FioAddress string            `json:"fio_address"`
+	IdHash     eos.Uint128       `json:"idhash"`
+	Amount     uint64            `json:"amount"`
+	NftName    string            `json:"nftname"`
+	IsComplete uint8             `json:"isComplete"`
+	Timestamp  uint64            `json:"timestamp"`
+}
+
+// GetOracleVote finds the unwrap votes for a transaction on the other chain, it returns nil if no oracle has voted
+func (api *API) GetOracleVote(obtId string) (*OracleVote, error) {
+	gtr, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:       "fio.oracle",
+		Scope:      "fio.oracle",
+		Table:      "oravotes",
+		LowerBound: I128Hash(obtId),
+		UpperBound: I128Hash(obtId),
+		Limit:      1,
+		KeyType:    "i128",
+		Index:      "2",
+		JSON:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	votes := make([]*OracleVote, 0)
+	err = json.Unmarshal(gtr.Rows, &votes)
+	if err != nil {
+		return nil, err
+	}
+	if len(votes) == 0 || votes[0].ObtId != obtId {
+		return nil, nil
+	}
+	return votes[0], nil
+}
+
+// Oracle is a registered oracle from the fio.oracle oracless table
+type Oracle struct {
+	Actor eos.AccountName `json:"actor"`
+	Fees  []struct {
+		FeeName   string `json:"fee_name"`
+		FeeAmount uint64 `json:"fee_amount"`
+	} `json:"fees"`
+}
+
+// GetOracles lists the registered oracles
+func (api *API) GetOracles() ([]*Oracle, error) {
+	gtr, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:  "fio.oracle",
+		Scope: "fio.oracle",
+		Table: "oracless",
+		Limit: 100,
+		JSON:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	oracles := make([]*Oracle, 0)
+	err = json.Unmarshal(gtr.Rows, &oracles)
+	if err != nil {
+		return nil, err
+	}
+	return oracles, nil
+}
+
+// Progress of a wrap or unwrap, in order
+const (
+	WrapSubmitted    = "submitted"
+	WrapIncluded     = "included"
+	WrapIrreversible = "irreversible"
+	WrapRecorded     = "recorded"
+	WrapConfirmed    = "confirmed"
+)
+
+// WrapStatus is reported by WrapTracker each time a wrap or unwrap progresses
+type WrapStatus struct {
+	State  string             `json:"state"`
+	TxId   string             `json:"tx_id,omitempty"`
+	Block  uint32             `json:"block,omitempty"`
+	Entry  *OracleLedgerEntry `json:"entry,omitempty"`
+	Vote   *OracleVote        `json:"vote,omitempty"`
+	Votes  int                `json:"votes,omitempty"`
+	Needed int                `json:"needed,omitempty"`
+}
+
+// WrapTracker follows a wrap from submission until the oracles confirm it.
+//
+// A wrap is recorded once its oracle ledger entry exists in an irreversible block. The oracles act on the other
+// chain, which fio-go can't see, so it is only confirmed when Confirm returns true, for example after checking for
+// the mint on Ethereum. If Confirm is nil a recorded wrap is treated as confirmed.
+type WrapTracker struct {
+	Api          *API
+	PollInterval time.Duration
+	Confirm      func(entry *OracleLedgerEntry) (bool, error)
+	// OnUpdate is called each time the state changes, and for an unwrap each time the number of votes changes
+	OnUpdate func(status WrapStatus)
+}
+
+// NewWrapTracker polls every two seconds
+func NewWrapTracker(api *API) *WrapTracker {
+	return &WrapTracker{Api: api, PollInterval: 2 * time.Second}
+}
+
+// update sets the state, calling OnUpdate if it or anything else in the status has changed
+func (wt *WrapTracker) update(status *WrapStatus, state string, changed bool) {
+	if status.State == state && !changed {
+		return
+	}
+	status.State = state
+	if wt.OnUpdate != nil {
+		wt.OnUpdate(*status)
+	}
+}
+
+func (wt *WrapTracker) wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wt.PollInterval):
+		return nil
+	}
+}
+
+// waitIrreversible waits until the transaction's block is irreversible, checks that it is in the block and returns
+// the block's time
+func (wt *WrapTracker) waitIrreversible(ctx context.Context, status *WrapStatus) (time.Time, error) {
+	for {
+		info, err := wt.Api.GetInfo()
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.HeadBlockNum >= status.Block {
+			wt.update(status, WrapIncluded, false)
+		}
+		if info.LastIrreversibleBlockNum >= status.Block {
+			break
+		}
+		if err = wt.wait(ctx); err != nil {
+			return time.Time{}, err
+		}
+	}
+	block, err := wt.Api.GetBlockByNum(status.Block)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, receipt := range block.Transactions {
+		if receipt.Transaction.ID.String() == status.TxId {
+			wt.update(status, WrapIrreversible, false)
+			return block.Timestamp.Time, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("transaction %s was not found in block %d, it may have been dropped", status.TxId, status.Block)
+}
+
+// TrackWrap follows a wrap pushed in txId and included in block. The ledger entry is the oldest one for the wrap's
+// actor with the same chain code, public address, and amount or domain that was recorded no earlier than the block.
+func (wt *WrapTracker) TrackWrap(ctx context.Context, txId string, block uint32, wrap *Action) (*WrapStatus, error) {
+	status := &WrapStatus{TxId: txId, Block: block}
+	wt.update(status, WrapSubmitted, false)
+	var match func(e *OracleLedgerEntry) bool
+	var actor eos.AccountName
+	switch w := wrap.Data.(type) {
+	case WrapTokens:
+		actor = w.Actor
+		match = func(e *OracleLedgerEntry) bool {
+			return e.ChainCode == w.ChainCode && e.PubAddress == w.PublicAddress && e.Amount == w.Amount
+		}
+	case WrapDomain:
+		actor = w.Actor
+		match = func(e *OracleLedgerEntry) bool {
+			return e.ChainCode == w.ChainCode && e.PubAddress == w.PublicAddress && e.NftName == w.FioDomain
+		}
+	default:
+		return nil, errors.New("action is not a wraptokens or wrapdomain")
+	}
+	blockTime, err := wt.waitIrreversible(ctx, status)
+	if err != nil {
+		return status, err
+	}
+
+	entries, err := wt.Api.GetOracleLedgerByActor(actor)
+	if err != nil {
+		return status, err
+	}
+	for _, e := range entries {
+		if e.Timestamp >= uint64(blockTime.Unix()) && match(e) {
+			status.Entry = e
+			break
+		}
+	}
+	if status.Entry == nil {
+		return status, errors.New("oracle ledger entry not found")
+	}
+	wt.update(status, WrapRecorded, false)
+
+	for wt.Confirm != nil {
+		ok, err := wt.Confirm(status.Entry)
+		if err != nil {
+			return status, err
+		}
+		if ok {
+			break
+		}
+		if err = wt.wait(ctx); err != nil {
+			return status, err
+		}
+	}
+	wt.update(status, WrapConfirmed, false)
+	return status, nil
+}
+
+// TrackUnwrap follows the oracle votes for an unwrap until every registered oracle has voted
+func (wt *WrapTracker) TrackUnwrap(ctx context.Context, obtId string) (*WrapStatus, error) {
+	status := &WrapStatus{}
+	wt.update(status, WrapSubmitted, false)
+	oracles, err := wt.Api.GetOracles()
+	if err != nil {
+		return status, err
+	}
+	status.Needed = len(oracles)
+	for {
+		vote, err := wt.Api.GetOracleVote(obtId)
+		if err != nil {
+			return status, err
+		}
+		if vote != nil {
+			status.Vote = vote
+			changed := len(vote.Voters) != status.Votes
+			status.Votes = len(vote.Voters)
+			wt.update(status, WrapRecorded, changed)
+			if vote.IsComplete == 1 {
+				wt.update(status, WrapConfirmed, false)
+				return status, nil
+			}
+		}
+		if err = wt.wait(ctx); err != nil {
+			return status, err
+		}
+	}
+}
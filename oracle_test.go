@@ -0,0 +1,120 @@
+package fio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrapTracker(t *testing.T) {
+	const txId = "2e3e5fc9dbd2b3e0c9f4a0fba4f4b5e18ec1ecf29ab5a9a9ad5d9d8ec5c33ab1"
+	const ethAddr = "0x1234567890abcdef1234567890abcdef12345678"
+	var lib, votes uint32
+	node, api := newFakeNode(t)
+	node.handle("/v1/chain/get_oracle_fees", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"oracle_fees":[{"fee_name":"wrap_fio_domain","fee_amount":6000000000},{"fee_name":"wrap_fio_tokens","fee_amount":3000000000}]}`))
+	})
+	node.handle("/v1/chain/get_info", func(w http.ResponseWriter, r *http.Request) {
+		lib++
+		_, _ = fmt.Fprintf(w, `{"head_block_num":%d,"last_irreversible_block_num":%d}`, lib+2, lib)
+	})
+	node.handle("/v1/chain/get_block", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"block_num":3,"timestamp":"2020-09-13T12:26:45.000","transactions":[{"status":"executed","trx":"` + txId + `"}]}`))
+	})
+	// aloha has 1000 older wraps, entry 1001 is the same wrap made before the tracked block, and entry 1003 is a
+	// repeat after it. Like nodeos, the actor index returns at most limit rows starting from the first for the actor.
+	ledger := make([]string, 0)
+	for id := 1; id <= 1000; id++ {
+		ledger = append(ledger, fmt.Sprintf(`{"id":%d,"actor":"aloha","chaincode":"ETH","pubaddress":"`+ethAddr+`","nftname":"","amount":1,"fees":3000000000,"timestamp":1600000000}`, id))
+	}
+	ledger = append(ledger,
+		`{"id":1001,"actor":"aloha","chaincode":"ETH","pubaddress":"`+ethAddr+`","nftname":"","amount":100,"fees":3000000000,"timestamp":1600000001}`,
+		`{"id":1002,"actor":"aloha","chaincode":"ETH","pubaddress":"`+ethAddr+`","nftname":"","amount":100,"fees":3000000000,"timestamp":1600000005}`,
+		`{"id":1003,"actor":"aloha","chaincode":"ETH","pubaddress":"`+ethAddr+`","nftname":"","amount":100,"fees":3000000000,"timestamp":1600000020}`,
+	)
+	node.table("oracleldgrs", func(req eos.GetTableRowsRequest) (interface{}, bool) {
+		if req.Index != "2" || req.KeyType != "name" || req.LowerBound != "aloha" || req.UpperBound != "aloha" {
+			t.Errorf("ledger should be read from the actor index: %+v", req)
+			return json.RawMessage(`[]`), false
+		}
+		if int(req.Limit) >= len(ledger) {
+			return json.RawMessage(`[` + strings.Join(ledger, ",") + `]`), false
+		}
+		return json.RawMessage(`[` + strings.Join(ledger[:req.Limit], ",") + `]`), true
+	})
+	node.rows("oracless", `[{"actor":"oracle1","fees":[]},{"actor":"oracle2","fees":[]}]`)
+	node.table("oravotes", func(eos.GetTableRowsRequest) (interface{}, bool) {
+		votes++
+		voters, complete := `["oracle1"]`, 0
+		if votes > 1 {
+			voters, complete = `["oracle1","oracle2"]`, 1
+		}
+		return json.RawMessage(fmt.Sprintf(`[{"id":0,"voters":%s,"obt_id":"0xabc","fio_address":"alice@fio","amount":100,"nftname":"","isComplete":%d,"timestamp":1600000020}]`, voters, complete)), false
+	})
+
+	if _, err := api.NewWrapTokens("aloha", 100, "ETH", ""); err == nil {
+		t.Error("empty public address should be invalid")
+	}
+	if _, err := api.NewWrapDomain("aloha", "not a domain", "ETH", ethAddr); err == nil {
+		t.Error("invalid domain should be rejected")
+	}
+	act, err := api.NewWrapTokens("aloha", 100, "ETH", ethAddr)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if act.Data.(WrapTokens).MaxOracleFee != 3000000000 {
+		t.Error("wrong oracle fee", act.Data.(WrapTokens).MaxOracleFee)
+	}
+
+	entries, err := api.GetOracleLedgerByActor("aloha")
+	if err != nil || len(entries) != len(ledger) {
+		t.Errorf("should page through the ledger, got %d entries %v", len(entries), err)
+	}
+
+	wt := NewWrapTracker(api)
+	wt.PollInterval = time.Millisecond
+	states := make([]string, 0)
+	wt.OnUpdate = func(status WrapStatus) {
+		states = append(states, status.State)
+	}
+	var checked bool
+	wt.Confirm = func(entry *OracleLedgerEntry) (bool, error) {
+		defer func() { checked = true }()
+		return checked, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := wt.TrackWrap(ctx, txId, 3, act)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if status.Entry == nil || status.Entry.Id != 1002 {
+		t.Errorf("wrong ledger entry: %+v", status.Entry)
+	}
+	want := []string{WrapSubmitted, WrapIncluded, WrapIrreversible, WrapRecorded, WrapConfirmed}
+	if fmt.Sprint(states) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, states)
+	}
+
+	states = states[:0]
+	status, err = wt.TrackUnwrap(ctx, "0xabc")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if status.Votes != 2 || status.Needed != 2 || status.State != WrapConfirmed {
+		t.Errorf("unwrap should be confirmed: %+v", status)
+	}
+	// one update for each vote, the second only changes the vote count
+	want = []string{WrapSubmitted, WrapRecorded, WrapRecorded, WrapConfirmed}
+	if fmt.Sprint(states) != fmt.Sprint(want) {
+		t.Errorf("expected an update for each vote %v, got %v", want, states)
+	}
+}
@@ -0,0 +1,226 @@
+package fio
+
+import (
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"time"
+)
+
+// PermRegisterAddressOnDomain is the fio.perms permission allowing the grantee to register addresses on a domain
+const PermRegisterAddressOnDomain = "register_address_on_domain"
+
+// PermAllDomains is used as the object name to grant a permission on every domain the owner holds
+const PermAllDomains = "*"
+
+// AddPerm grants an account a permission on an object owned by the actor, currently only
+// PermRegisterAddressOnDomain is supported, with a domain (or PermAllDomains) as the object.
+type AddPerm struct {
+	GranteeAccount string          `json:"grantee_account"`
+	PermissionName string          `json:"permission_name"`
+	PermissionInfo string          `json:"permission_info"`
+	ObjectName     string          `json:"object_name"`
+	MaxFee         uint64          `json:"max_fee"`
+	Tpid           string          `json:"tpid"`
+	Actor          eos.AccountName `json:"actor"`
+}
+
+// RemPerm revokes a permission granted with AddPerm
+type RemPerm struct {
+	GranteeAccount string          `json:"grantee_account"`
+	PermissionName string          `json:"permission_name"`
+	ObjectName     string          `json:"object_name"`
+	MaxFee         uint64          `json:"max_fee"`
+	Tpid           string          `json:"tpid"`
+	Actor          eos.AccountName `json:"actor"`
+}
+
+// checkPermTarget validates the grantee and domain for addperm and remperm
+func checkPermTarget(grantee eos.AccountName, domain string) error {
+	// StringToName never fails, so the name is only valid if it survives the round trip
+	if n, err := eos.StringToName(string(grantee)); err != nil || grantee == "" || eos.NameToString(n) != string(grantee) {
+		return fmt.Errorf("invalid grantee account %q", grantee)
+	}
+	if domain != PermAllDomains && !ValidDomain(domain) {
+		return fmt.Errorf("invalid domain %q", domain)
+	}
+	return nil
+}
+
+// NewAddPerm builds an addperm action allowing grantee to register addresses on the actor's domain, use
+// PermAllDomains for every domain owned by the actor.
+func NewAddPerm(actor eos.AccountName, grantee eos.AccountName, domain string) (*Action, error) {
+	if err := checkPermTarget(grantee, domain); err != nil {
+		return nil, err
+	}
+	return NewAction(
+		"fio.perms", "addperm", actor,
+		AddPerm{
+			GranteeAccount: string(grantee),
+			PermissionName: PermRegisterAddressOnDomain,
+			ObjectName:     domain,
+			MaxFee:         Tokens(GetMaxFee(FeeAddFioPermission)),
+			Tpid:           CurrentTpid(),
+			Actor:          actor,
+		},
+	), nil
+}
+
+// NewRemPerm builds a remperm action, revoking a grant made with NewAddPerm
+func NewRemPerm(actor eos.AccountName, grantee eos.AccountName, domain string) (*Action, error) {
+	if err := checkPermTarget(grantee, domain); err != nil {
+		return nil, err
+	}
+	return NewAction(
+		"fio.perms", "remperm", actor,
+		RemPerm{
+			GranteeAccount: string(grantee),
+			PermissionName: PermRegisterAddressOnDomain,
+			ObjectName:     domain,
+			MaxFee:         Tokens(GetMaxFee(FeeRemoveFioPermission)),
+			Tpid:           CurrentTpid(),
+			Actor:          actor,
+		},
+	), nil
+}
+
+// PermissionInfo is a row in the fio.perms permissions table, created by the first addperm for an object
+type PermissionInfo struct {
+	Id             uint64          `json:"id"`
+	ObjectType     string          `json:"object_type"`
+	ObjectName     string          `json:"object_name"`
+	PermissionName string          `json:"permission_name"`
+	OwnerAccount   eos.AccountName `json:"owner_account"`
+	AuxilliaryInfo string          `json:"auxilliary_info"`
+}
+
+// PermissionAccess is a row in the fio.perms accesses table, granting a permission to an account
+type PermissionAccess struct {
+	Id             uint64          `json:"id"`
+	PermissionId   uint64          `json:"permission_id"`
+	GranteeAccount eos.AccountName `json:"grantee_account"`
+}
+
+// GetPermissions lists the permissions owned by an account, using the byowner index
+func (api *API) GetPermissions(owner eos.AccountName) ([]*PermissionInfo, error) {
+	perms := make([]*PermissionInfo, 0)
+	err := api.getAllIndexRows(permsIndexRequest("permissions", "6", owner), &perms, func(i int) uint64 {
+		return perms[i].Id
+	})
+	if err != nil {
+		return nil, err
+	}
+	return perms, nil
+}
+
+// GetPermissionAccesses lists the permissions granted to an account, using the bygrantee index
+func (api *API) GetPermissionAccesses(grantee eos.AccountName) ([]*PermissionAccess, error) {
+	accesses := make([]*PermissionAccess, 0)
+	err := api.getAllIndexRows(permsIndexRequest("accesses", "3", grantee), &accesses, func(i int) uint64 {
+		return accesses[i].Id
+	})
+	if err != nil {
+		return nil, err
+	}
+	return accesses, nil
+}
+
+// permsIndexRequest requests an account's rows from a fio.perms table index keyed by account name
+func permsIndexRequest(table string, index string, account eos.AccountName) eos.GetTableRowsRequest {
+	return eos.GetTableRowsRequest{
+		Code:       "fio.perms",
+		Scope:      "fio.perms",
+		Table:      table,
+		LowerBound: string(account),
+		UpperBound: string(account),
+		Limit:      1000,
+		KeyType:    "name",
+		Index:      index,
+		JSON:       true,
+	}
+}
+
+// DomainAccess explains whether an account may register addresses on a domain. Permission is set when access
+// comes from a fio.perms grant.
+type DomainAccess struct {
+	Domain     string          `json:"domain"`
+	Actor      eos.AccountName `json:"actor"`
+	Allowed    bool            `json:"allowed"`
+	Reason     string          `json:"reason"`
+	Permission *PermissionInfo `json:"permission,omitempty"`
+}
+
+// CanRegisterOnDomain checks whether actor may register an address on a domain: the domain must not be expired,
+// and must be public, owned by the actor, or the owner must have granted the actor PermRegisterAddressOnDomain for
+// the domain or for all of their domains.
+func (api *API) CanRegisterOnDomain(actor eos.AccountName, domain string) (*DomainAccess, error) {
+	domain, err := ParseDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	access := &DomainAccess{Domain: domain, Actor: actor}
+	d, err := api.GetDomain(domain)
+	if err != nil {
+		return nil, fmt.Errorf("domain %s: %s", domain, err)
+	}
+	switch {
+	case d.Expiration < time.Now().Unix():
+		access.Reason = "domain is expired"
+		return access, nil
+	case d.IsPublic == 1:
+		access.Allowed, access.Reason = true, "domain is public"
+		return access, nil
+	case d.Account == nil:
+		return nil, errors.New("domain has no owner")
+	case *d.Account == actor:
+		access.Allowed, access.Reason = true, "actor owns the domain"
+		return access, nil
+	}
+
+	perms, err := api.GetPermissions(*d.Account)
+	if err != nil {
+		return nil, err
+	}
+	granted := make(map[uint64]*PermissionInfo)
+	for _, p := range perms {
+		if p.PermissionName == PermRegisterAddressOnDomain && (p.ObjectName == domain || p.ObjectName == PermAllDomains) {
+			granted[p.Id] = p
+		}
+	}
+	if len(granted) > 0 {
+		accesses, err := api.GetPermissionAccesses(actor)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range accesses {
+			if p := granted[a.PermissionId]; p != nil {
+				access.Allowed, access.Permission = true, p
+				access.Reason = fmt.Sprintf("granted by %s for %s", p.OwnerAccount, p.ObjectName)
+				return access, nil
+			}
+		}
+	}
+	access.Reason = "domain is private and the owner has not granted access"
+	return access, nil
+}
+
+// NewRegAddressChecked is NewRegAddress, but first uses CanRegisterOnDomain so that registering on a private
+// domain fails before any fee is paid.
+func (api *API) NewRegAddressChecked(actor eos.AccountName, address Address, ownerPubKey string) (*Action, error) {
+	h, err := address.Handle()
+	if err != nil {
+		return nil, err
+	}
+	access, err := api.CanRegisterOnDomain(actor, h.Domain)
+	if err != nil {
+		return nil, err
+	}
+	if !access.Allowed {
+		return nil, fmt.Errorf("%s cannot register on %s: %s", actor, h.Domain, access.Reason)
+	}
+	action, ok := NewRegAddress(actor, h.Address(), ownerPubKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid address %s", h.Address())
+	}
+	return action, nil
+}
@@ -0,0 +1,106 @@
+package fio
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewAddPerm(t *testing.T) {
+	if _, err := NewAddPerm("aloha", "not valid", "test"); err == nil {
+		t.Error("invalid grantee should be rejected")
+	}
+	if _, err := NewAddPerm("aloha", "bob", "test"); err != nil {
+		t.Error("short account names are valid grantees:", err)
+	}
+	if _, err := NewRemPerm("aloha", "qbxn5zhw2ypw", "-test"); err == nil {
+		t.Error("invalid domain should be rejected")
+	}
+	act, err := NewAddPerm("aloha", "qbxn5zhw2ypw", PermAllDomains)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if ap := act.Data.(AddPerm); ap.PermissionName != PermRegisterAddressOnDomain || ap.ObjectName != "*" {
+		t.Errorf("wrong addperm: %+v", ap)
+	}
+}
+
+func TestAPI_CanRegisterOnDomain(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Unix()
+	domains := map[string]string{
+		DomainNameHash("public"):  fmt.Sprintf(`{"name":"public","is_public":1,"expiration":%d,"account":"ownerownerow"}`, expires),
+		DomainNameHash("private"): fmt.Sprintf(`{"name":"private","is_public":0,"expiration":%d,"account":"ownerownerow"}`, expires),
+		DomainNameHash("expired"): `{"name":"expired","is_public":1,"expiration":1600000000,"account":"ownerownerow"}`,
+	}
+	node, api := newFakeNode(t)
+	node.table("domains", func(req eos.GetTableRowsRequest) (interface{}, bool) {
+		return json.RawMessage(`[` + domains[req.LowerBound] + `]`), false
+	})
+	// the owner has 1000 permissions on other domains before the one for private, and like nodeos byowner returns at
+	// most limit rows starting from the first for the owner
+	perms := make([]string, 0)
+	for id := 1; id <= 1000; id++ {
+		perms = append(perms, fmt.Sprintf(`{"id":%d,"object_type":"domain","object_name":"other%d","permission_name":"register_address_on_domain","owner_account":"ownerownerow"}`, id, id))
+	}
+	perms = append(perms, `{"id":1001,"object_type":"domain","object_name":"private","permission_name":"register_address_on_domain","owner_account":"ownerownerow"}`)
+	node.table("permissions", func(req eos.GetTableRowsRequest) (interface{}, bool) {
+		if req.Index != "6" || req.KeyType != "name" || req.LowerBound != "ownerownerow" || req.UpperBound != "ownerownerow" {
+			t.Errorf("permissions should be read from the byowner index: %+v", req)
+			return json.RawMessage(`[]`), false
+		}
+		if int(req.Limit) >= len(perms) {
+			return json.RawMessage(`[` + strings.Join(perms, ",") + `]`), false
+		}
+		return json.RawMessage(`[` + strings.Join(perms[:req.Limit], ",") + `]`), true
+	})
+	node.table("accesses", func(req eos.GetTableRowsRequest) (interface{}, bool) {
+		if req.Index == "3" && req.KeyType == "name" && req.LowerBound == "granteegrant" && req.UpperBound == "granteegrant" {
+			return json.RawMessage(`[{"id":5,"permission_id":1001,"grantee_account":"granteegrant"}]`), false
+		}
+		return json.RawMessage(`[]`), false
+	})
+
+	for _, test := range []struct {
+		actor   eos.AccountName
+		domain  string
+		allowed bool
+	}{
+		{"someoneelse1", "public", true},
+		{"someoneelse1", "expired", false},
+		{"ownerownerow", "private", true},
+		{"granteegrant", "PRIVATE", true},
+		{"someoneelse1", "private", false},
+	} {
+		access, err := api.CanRegisterOnDomain(test.actor, test.domain)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if access.Allowed != test.allowed {
+			t.Errorf("%s on %s: expected %v, got %+v", test.actor, test.domain, test.allowed, access)
+		}
+	}
+
+	if _, err := api.NewRegAddressChecked("someoneelse1", "name@private", "FIO7uRvrLVrZCbCM2DtCgUMospqUMnP3JUC1sKHA8zNoF835kJBvN"); err == nil {
+		t.Error("should not be able to register on a private domain")
+	}
+	act, err := api.NewRegAddressChecked("granteegrant", "name@private", "FIO7uRvrLVrZCbCM2DtCgUMospqUMnP3JUC1sKHA8zNoF835kJBvN")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if act.Data.(RegAddress).FioAddress != "name@private" {
+		t.Error("wrong address")
+	}
+	owned, err := api.GetPermissions("ownerownerow")
+	if err != nil || len(owned) != len(perms) {
+		t.Error("should page through the byowner index", len(owned), err)
+	}
+	if _, err = api.CanRegisterOnDomain("someoneelse1", "missing"); err == nil {
+		t.Error("missing domain should be an error")
+	}
+}
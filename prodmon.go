@@ -0,0 +1,549 @@
+package fio
+
+import (
+	"context"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// ProducerRepetitions is the number of consecutive blocks each producer signs in its slot
+	ProducerRepetitions = 12
+	// BlockInterval is the time between blocks
+	BlockInterval = 500 * time.Millisecond
+)
+
+// Kinds of MonitorAlert
+const (
+	AlertMissedBlocks   = "missed_blocks"
+	AlertMissedRound    = "missed_round"
+	AlertScheduleChange = "schedule_change"
+	AlertLibLag         = "lib_lag"
+	AlertClaimOverdue   = "claim_overdue"
+)
+
+// MonitorBlock is the information ProducerMonitor needs about a block. Lib is the last irreversible block when the
+// block was seen, and Head is the node's head block if it is ahead of Num, as when following irreversible blocks.
+// Schedule is only needed on the first block with a new schedule version.
+type MonitorBlock struct {
+	Num             uint32
+	Producer        eos.AccountName
+	Time            time.Time
+	ScheduleVersion uint32
+	Schedule        []eos.AccountName
+	Lib             uint32
+	Head            uint32
+}
+
+// MonitorAlert is sent to ProducerMonitor.OnAlert
+type MonitorAlert struct {
+	Kind     string
+	Producer eos.AccountName
+	BlockNum uint32
+	Time     time.Time
+	Message  string
+}
+
+// ProducerStats holds the counters for one producer. Expected blocks only count slots that were completely
+// observed, so the first slot after the monitor starts is not included.
+type ProducerStats struct {
+	Producer       eos.AccountName
+	InSchedule     bool
+	Produced       uint64
+	Expected       uint64
+	Missed         uint64
+	MissedRounds   uint64
+	LastRound      int
+	LastBlock      uint32
+	LastBlockTime  time.Time
+	UnpaidBlocks   uint64
+	LastClaimTime  time.Time
+	claimAlertSent bool
+}
+
+// ProducerMonitor follows blocks and tracks producer health: blocks produced vs expected, missed rounds, latency
+// to LIB, schedule changes, and unpaid blocks from the producers table.
+//
+// Blocks are passed to HandleBlock, either by Run which follows a node, or by a test using SyntheticFeed. Metrics
+// are available in the Prometheus text format from WriteMetrics, and ProducerMonitor is an http.Handler for them.
+type ProducerMonitor struct {
+	Api *API
+
+	// OnAlert is called for each alert, it is not called while holding the lock so it may use the monitor
+	OnAlert func(alert MonitorAlert)
+	// LibLagThreshold raises AlertLibLag when LIB is more than this many blocks behind head, 0 disables
+	LibLagThreshold uint32
+	// ClaimOverdue raises AlertClaimOverdue when a producer with unpaid blocks hasn't claimed for this long, 0 disables
+	ClaimOverdue time.Duration
+	// ProducersInterval is how often Run refreshes the producers table
+	ProducersInterval time.Duration
+
+	mux             sync.RWMutex
+	producers       map[eos.AccountName]*ProducerStats
+	schedule        []eos.AccountName
+	scheduleVersion uint32
+	scheduleChanges uint64
+	current         eos.AccountName
+	currentCount    int
+	countSlot       bool
+	head            uint32
+	nodeHead        uint32
+	lib             uint32
+	libLatency      time.Duration
+	lagging         bool
+	times           map[uint32]time.Time
+	alerts          []MonitorAlert
+}
+
+// NewProducerMonitor creates a monitor, api is only required for Run and RefreshProducers
+func NewProducerMonitor(api *API) *ProducerMonitor {
+	return &ProducerMonitor{
+		Api:               api,
+		LibLagThreshold:   360,
+		ProducersInterval: time.Minute,
+		producers:         make(map[eos.AccountName]*ProducerStats),
+		times:             make(map[uint32]time.Time),
+	}
+}
+
+// stats gets or creates the stats for a producer, the lock must be held
+func (pm *ProducerMonitor) stats(producer eos.AccountName) *ProducerStats {
+	if pm.producers[producer] == nil {
+		pm.producers[producer] = &ProducerStats{Producer: producer}
+	}
+	return pm.producers[producer]
+}
+
+func (pm *ProducerMonitor) alert(kind string, producer eos.AccountName, block uint32, t time.Time, format string, a ...interface{}) {
+	pm.alerts = append(pm.alerts, MonitorAlert{
+		Kind:     kind,
+		Producer: producer,
+		BlockNum: block,
+		Time:     t,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+// flush sends queued alerts, it must be called without holding the lock
+func (pm *ProducerMonitor) flush() {
+	pm.mux.Lock()
+	alerts := pm.alerts
+	pm.alerts = nil
+	pm.mux.Unlock()
+	if pm.OnAlert == nil {
+		return
+	}
+	for _, a := range alerts {
+		pm.OnAlert(a)
+	}
+}
+
+// SetSchedule sets the active schedule, without counting it as a change
+func (pm *ProducerMonitor) SetSchedule(version uint32, schedule []eos.AccountName) {
+	pm.mux.Lock()
+	defer pm.mux.Unlock()
+	pm.setSchedule(version, schedule)
+}
+
+func (pm *ProducerMonitor) setSchedule(version uint32, schedule []eos.AccountName) {
+	for _, s := range pm.producers {
+		s.InSchedule = false
+	}
+	for _, p := range schedule {
+		pm.stats(p).InSchedule = true
+	}
+	pm.schedule = append([]eos.AccountName{}, schedule...)
+	pm.scheduleVersion = version
+}
+
+// ScheduleVersion returns the version of the schedule being tracked
+func (pm *ProducerMonitor) ScheduleVersion() uint32 {
+	pm.mux.RLock()
+	defer pm.mux.RUnlock()
+	return pm.scheduleVersion
+}
+
+func (pm *ProducerMonitor) position(producer eos.AccountName) int {
+	for i := range pm.schedule {
+		if pm.schedule[i] == producer {
+			return i
+		}
+	}
+	return -1
+}
+
+// HandleBlock updates the monitor with a new block, blocks must be passed in order. Blocks should be irreversible,
+// since blocks from a fork that is later dropped can not be uncounted.
+func (pm *ProducerMonitor) HandleBlock(b MonitorBlock) {
+	defer pm.flush()
+	pm.mux.Lock()
+	defer pm.mux.Unlock()
+	if b.Num <= pm.head {
+		return
+	}
+
+	scheduleChanged := false
+	if b.ScheduleVersion != pm.scheduleVersion && b.Schedule != nil {
+		if pm.schedule != nil {
+			pm.scheduleChanges++
+			pm.alert(AlertScheduleChange, "", b.Num, b.Time, "producer schedule changed from version %d to %d", pm.scheduleVersion, b.ScheduleVersion)
+		}
+		pm.setSchedule(b.ScheduleVersion, b.Schedule)
+		scheduleChanged = true
+	}
+
+	s := pm.stats(b.Producer)
+	s.Produced++
+	s.LastBlock = b.Num
+	s.LastBlockTime = b.Time
+
+	if b.Producer == pm.current {
+		pm.currentCount++
+	} else {
+		if pm.current != "" {
+			pm.endSlot(b)
+			if !scheduleChanged {
+				pm.skipped(b)
+			}
+		}
+		// the first slot seen may be partial, so it isn't counted
+		pm.countSlot = pm.current != ""
+		pm.current = b.Producer
+		pm.currentCount = 1
+	}
+
+	pm.head = b.Num
+	if b.Head > b.Num {
+		pm.nodeHead = b.Head
+	} else {
+		pm.nodeHead = b.Num
+	}
+	pm.times[b.Num] = b.Time
+	if b.Lib > pm.lib {
+		pm.lib = b.Lib
+		for n := range pm.times {
+			if n < pm.lib {
+				delete(pm.times, n)
+			}
+		}
+	}
+	if t, ok := pm.times[pm.lib]; ok && pm.nodeHead == b.Num {
+		pm.libLatency = b.Time.Sub(t)
+	} else if pm.lib > 0 && pm.lib <= pm.nodeHead {
+		pm.libLatency = time.Duration(pm.nodeHead-pm.lib) * BlockInterval
+	}
+	if pm.LibLagThreshold > 0 && pm.lib > 0 && pm.lib <= pm.nodeHead {
+		lagging := pm.nodeHead-pm.lib > pm.LibLagThreshold
+		if lagging && !pm.lagging {
+			pm.alert(AlertLibLag, "", b.Num, b.Time, "last irreversible block is %d blocks behind head", pm.nodeHead-pm.lib)
+		}
+		pm.lagging = lagging
+	}
+}
+
+// endSlot counts the blocks for the producer whose slot just ended
+func (pm *ProducerMonitor) endSlot(b MonitorBlock) {
+	if !pm.countSlot {
+		return
+	}
+	s := pm.stats(pm.current)
+	s.Expected += ProducerRepetitions
+	s.LastRound = pm.currentCount
+	if pm.currentCount < ProducerRepetitions {
+		missed := ProducerRepetitions - pm.currentCount
+		s.Missed += uint64(missed)
+		pm.alert(AlertMissedBlocks, pm.current, b.Num, b.Time, "%s missed %d of %d blocks", pm.current, missed, ProducerRepetitions)
+	}
+}
+
+// skipped counts a missed round for each producer in the schedule between the last and current producer
+func (pm *ProducerMonitor) skipped(b MonitorBlock) {
+	from, to := pm.position(pm.current), pm.position(b.Producer)
+	if from < 0 || to < 0 {
+		return
+	}
+	for i := (from + 1) % len(pm.schedule); i != to; i = (i + 1) % len(pm.schedule) {
+		s := pm.stats(pm.schedule[i])
+		s.Expected += ProducerRepetitions
+		s.Missed += ProducerRepetitions
+		s.MissedRounds++
+		s.LastRound = 0
+		pm.alert(AlertMissedRound, s.Producer, b.Num, b.Time, "%s missed a round", s.Producer)
+	}
+}
+
+// UpdateProducers sets the unpaid blocks and last claim time from the producers table
+func (pm *ProducerMonitor) UpdateProducers(producers []Producer, now time.Time) {
+	defer pm.flush()
+	pm.mux.Lock()
+	defer pm.mux.Unlock()
+	for _, p := range producers {
+		s := pm.stats(p.Owner)
+		s.UnpaidBlocks = p.UnpaidBlocks
+		if t, ok := parseClaimTime(p.LastClaimTime); ok {
+			s.LastClaimTime = t
+		}
+		overdue := pm.ClaimOverdue > 0 && s.UnpaidBlocks > 0 && !s.LastClaimTime.IsZero() &&
+			now.Sub(s.LastClaimTime) > pm.ClaimOverdue
+		if overdue && !s.claimAlertSent {
+			pm.alert(AlertClaimOverdue, p.Owner, pm.head, now, "%s has %d unpaid blocks and last claimed at %s", p.Owner, s.UnpaidBlocks, p.LastClaimTime)
+		}
+		s.claimAlertSent = overdue
+	}
+}
+
+// parseClaimTime reads a producers table last_claim_time, a time_point such as "2021-03-10T18:03:49.500". The
+// epoch is used for producers that have never claimed, which is returned as the zero time.
+func parseClaimTime(s string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02T15:04:05.999", time.RFC3339Nano} {
+		if t, err := time.Parse(layout, s); err == nil {
+			if t.Unix() <= 0 {
+				return time.Time{}, true
+			}
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// RefreshProducers queries the producers table, see UpdateProducers
+func (pm *ProducerMonitor) RefreshProducers() error {
+	producers, err := pm.Api.GetFioProducers()
+	if err != nil {
+		return err
+	}
+	pm.UpdateProducers(producers.Producers, time.Now())
+	return nil
+}
+
+// Stats returns a copy of the stats for every producer that has been seen, sorted by name
+func (pm *ProducerMonitor) Stats() []ProducerStats {
+	pm.mux.RLock()
+	defer pm.mux.RUnlock()
+	stats := make([]ProducerStats, 0, len(pm.producers))
+	for _, s := range pm.producers {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Producer < stats[j].Producer
+	})
+	return stats
+}
+
+// LibLag returns how far the last irreversible block is behind head, in blocks and time
+func (pm *ProducerMonitor) LibLag() (blocks uint32, latency time.Duration) {
+	pm.mux.RLock()
+	defer pm.mux.RUnlock()
+	if pm.lib > pm.nodeHead {
+		return 0, 0
+	}
+	return pm.nodeHead - pm.lib, pm.libLatency
+}
+
+// WriteMetrics writes the current state in the Prometheus text exposition format
+func (pm *ProducerMonitor) WriteMetrics(w io.Writer) error {
+	stats := pm.Stats()
+	lag, latency := pm.LibLag()
+	pm.mux.RLock()
+	head, lib := pm.nodeHead, pm.lib
+	version, changes := pm.scheduleVersion, pm.scheduleChanges
+	pm.mux.RUnlock()
+
+	var err error
+	printf := func(format string, a ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, a...)
+		}
+	}
+	gauge := func(name, kind, help string, value func(s ProducerStats) float64) {
+		printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, s := range stats {
+			printf("%s{producer=%q} %g\n", name, s.Producer, value(s))
+		}
+	}
+	gauge("fio_producer_blocks_produced_total", "counter", "Blocks signed by the producer.", func(s ProducerStats) float64 { return float64(s.Produced) })
+	gauge("fio_producer_blocks_expected_total", "counter", "Blocks the producer was scheduled to sign.", func(s ProducerStats) float64 { return float64(s.Expected) })
+	gauge("fio_producer_blocks_missed_total", "counter", "Scheduled blocks that the producer did not sign.", func(s ProducerStats) float64 { return float64(s.Missed) })
+	gauge("fio_producer_rounds_missed_total", "counter", "Rounds where the producer did not sign any blocks.", func(s ProducerStats) float64 { return float64(s.MissedRounds) })
+	gauge("fio_producer_last_round_blocks", "gauge", "Blocks signed in the producer's last complete slot.", func(s ProducerStats) float64 { return float64(s.LastRound) })
+	gauge("fio_producer_in_schedule", "gauge", "1 if the producer is in the active schedule.", func(s ProducerStats) float64 {
+		if s.InSchedule {
+			return 1
+		}
+		return 0
+	})
+	gauge("fio_producer_unpaid_blocks", "gauge", "Unpaid blocks from the producers table.", func(s ProducerStats) float64 { return float64(s.UnpaidBlocks) })
+	gauge("fio_producer_last_claim_timestamp_seconds", "gauge", "Last bpclaim time from the producers table.", func(s ProducerStats) float64 {
+		if s.LastClaimTime.IsZero() {
+			return 0
+		}
+		return float64(s.LastClaimTime.Unix())
+	})
+	for _, m := range []struct {
+		name, kind, help string
+		value            float64
+	}{
+		{"fio_head_block_num", "gauge", "Head block number.", float64(head)},
+		{"fio_lib_block_num", "gauge", "Last irreversible block number.", float64(lib)},
+		{"fio_lib_lag_blocks", "gauge", "Blocks between head and the last irreversible block.", float64(lag)},
+		{"fio_lib_lag_seconds", "gauge", "Time between head and the last irreversible block.", latency.Seconds()},
+		{"fio_schedule_version", "gauge", "Active producer schedule version.", float64(version)},
+		{"fio_schedule_changes_total", "counter", "Producer schedule changes seen.", float64(changes)},
+	} {
+		printf("# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.name, m.help, m.name, m.kind, m.name, m.value)
+	}
+	return err
+}
+
+// ServeHTTP serves the metrics
+func (pm *ProducerMonitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_ = pm.WriteMetrics(w)
+}
+
+func scheduleNames(s Schedule) []eos.AccountName {
+	names := make([]eos.AccountName, len(s.Producers))
+	for i := range s.Producers {
+		names[i] = s.Producers[i].AccountName
+	}
+	return names
+}
+
+// Run follows irreversible blocks from the node with FollowBlocks, polling every interval, and refreshes the producers
+// table every ProducersInterval. Following LIB rather than head means blocks on a fork that is dropped are never
+// counted. It returns when the context is done or a query fails.
+//
+// Schedules are cached by version, from the pending and proposed schedules and the new_producers in block headers,
+// so the node is only asked for the schedule when a block has a version that hasn't been seen.
+func (pm *ProducerMonitor) Run(ctx context.Context, interval time.Duration) error {
+	sched, err := pm.Api.GetProducerSchedule()
+	if err != nil {
+		return err
+	}
+	pm.SetSchedule(sched.Active.Version, scheduleNames(sched.Active))
+	// a nil schedule is a version the node no longer had when it was requested
+	schedules := make(map[uint32][]eos.AccountName)
+	cacheSchedules(schedules, sched)
+
+	var refreshed time.Time
+	return pm.Api.followBlocks(ctx, 0, interval, func(block *eos.BlockResp, info *eos.InfoResp) error {
+		if time.Since(refreshed) > pm.ProducersInterval {
+			if err := pm.RefreshProducers(); err != nil {
+				return err
+			}
+			refreshed = time.Now()
+		}
+		if block.NewProducers != nil {
+			names := make([]eos.AccountName, len(block.NewProducers.Producers))
+			for i := range block.NewProducers.Producers {
+				names[i] = block.NewProducers.Producers[i].AccountName
+			}
+			schedules[block.NewProducers.Version] = names
+		}
+		mb := MonitorBlock{
+			Num:             block.BlockNum,
+			Producer:        block.Producer,
+			Time:            block.Timestamp.Time,
+			ScheduleVersion: block.ScheduleVersion,
+			Lib:             info.LastIrreversibleBlockNum,
+			Head:            info.HeadBlockNum,
+		}
+		if mb.ScheduleVersion != pm.ScheduleVersion() {
+			if _, seen := schedules[mb.ScheduleVersion]; !seen {
+				sched, err := pm.Api.GetProducerSchedule()
+				if err != nil {
+					return err
+				}
+				cacheSchedules(schedules, sched)
+				if _, seen = schedules[mb.ScheduleVersion]; !seen {
+					schedules[mb.ScheduleVersion] = nil
+				}
+			}
+			mb.Schedule = schedules[mb.ScheduleVersion]
+		}
+		pm.HandleBlock(mb)
+		return nil
+	})
+}
+
+// cacheSchedules adds the active, pending and proposed schedules to a cache keyed by version
+func cacheSchedules(schedules map[uint32][]eos.AccountName, sched *ProducerSchedule) {
+	for _, s := range []Schedule{sched.Active, sched.Pending, sched.Proposed} {
+		if len(s.Producers) > 0 {
+			schedules[s.Version] = scheduleNames(s)
+		}
+	}
+}
+
+// SyntheticFeed generates blocks for testing a ProducerMonitor without a node. Blocks are produced in schedule
+// order, ProducerRepetitions per slot, with LibLag blocks between head and LIB.
+type SyntheticFeed struct {
+	Schedule []eos.AccountName
+	Version  uint32
+	Next     uint32
+	Time     time.Time
+	LibLag   uint32
+
+	announce bool
+}
+
+// NewSyntheticFeed starts a feed at startBlock, the first block includes the schedule
+func NewSyntheticFeed(schedule []eos.AccountName, startBlock uint32, start time.Time) *SyntheticFeed {
+	return &SyntheticFeed{
+		Schedule: schedule,
+		Version:  1,
+		Next:     startBlock,
+		Time:     start,
+		LibLag:   uint32(len(schedule)*ProducerRepetitions*2/3 + 1),
+		announce: true,
+	}
+}
+
+// SetSchedule switches to a new schedule with the next version
+func (sf *SyntheticFeed) SetSchedule(schedule []eos.AccountName) {
+	sf.Schedule = schedule
+	sf.Version++
+	sf.announce = true
+}
+
+// Slot produces the given number of blocks for a producer, time still advances for the full slot
+func (sf *SyntheticFeed) Slot(producer eos.AccountName, blocks int) []MonitorBlock {
+	out := make([]MonitorBlock, 0, blocks)
+	for i := 0; i < ProducerRepetitions; i++ {
+		sf.Time = sf.Time.Add(BlockInterval)
+		if i >= blocks {
+			continue
+		}
+		b := MonitorBlock{
+			Num:             sf.Next,
+			Producer:        producer,
+			Time:            sf.Time,
+			ScheduleVersion: sf.Version,
+		}
+		if sf.Next > sf.LibLag {
+			b.Lib = sf.Next - sf.LibLag
+		}
+		if sf.announce {
+			b.Schedule = sf.Schedule
+			sf.announce = false
+		}
+		out = append(out, b)
+		sf.Next++
+	}
+	return out
+}
+
+// Round produces a slot for every producer in the schedule, missing holds the number of blocks each producer
+// should miss.
+func (sf *SyntheticFeed) Round(missing map[eos.AccountName]int) []MonitorBlock {
+	out := make([]MonitorBlock, 0, len(sf.Schedule)*ProducerRepetitions)
+	for _, p := range sf.Schedule {
+		out = append(out, sf.Slot(p, ProducerRepetitions-missing[p])...)
+	}
+	return out
+}
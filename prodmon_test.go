@@ -0,0 +1,276 @@
+package fio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProducerMonitor(t *testing.T) {
+	schedule := []eos.AccountName{"bp1", "bp2", "bp3", "bp4"}
+	feed := NewSyntheticFeed(schedule, 1000, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	pm := NewProducerMonitor(nil)
+	alerts := make(map[string][]MonitorAlert)
+	pm.OnAlert = func(a MonitorAlert) {
+		alerts[a.Kind] = append(alerts[a.Kind], a)
+	}
+	feedRound := func(missing map[eos.AccountName]int) {
+		for _, b := range feed.Round(missing) {
+			pm.HandleBlock(b)
+		}
+	}
+
+	feedRound(nil)
+	feedRound(map[eos.AccountName]int{"bp2": 3, "bp3": ProducerRepetitions})
+	feedRound(nil)
+
+	stats := make(map[eos.AccountName]ProducerStats)
+	for _, s := range pm.Stats() {
+		stats[s.Producer] = s
+	}
+	if stats["bp2"].Missed != 3 || stats["bp2"].MissedRounds != 0 {
+		t.Errorf("bp2 should have missed 3 blocks: %+v", stats["bp2"])
+	}
+	if stats["bp3"].Missed != ProducerRepetitions || stats["bp3"].MissedRounds != 1 {
+		t.Errorf("bp3 should have missed a round: %+v", stats["bp3"])
+	}
+	if stats["bp1"].Produced != 3*ProducerRepetitions || stats["bp1"].Missed != 0 {
+		t.Errorf("bp1 should not have missed blocks: %+v", stats["bp1"])
+	}
+	// the first slot (bp1) is not counted, and the current slot (bp4) has not ended
+	if stats["bp1"].Expected != 2*ProducerRepetitions || stats["bp4"].Expected != 2*ProducerRepetitions {
+		t.Errorf("wrong expected blocks: %+v %+v", stats["bp1"], stats["bp4"])
+	}
+	if len(alerts[AlertMissedRound]) != 1 || alerts[AlertMissedRound][0].Producer != "bp3" {
+		t.Error("expected a missed round alert for bp3", alerts)
+	}
+	if len(alerts[AlertMissedBlocks]) != 1 || alerts[AlertMissedBlocks][0].Producer != "bp2" {
+		t.Error("expected a missed blocks alert for bp2", alerts)
+	}
+
+	lag, latency := pm.LibLag()
+	if lag != feed.LibLag || latency != time.Duration(lag)*BlockInterval {
+		t.Error("wrong lib lag", lag, latency)
+	}
+
+	// a schedule change should not count the removed producer as missing a round
+	feed.SetSchedule([]eos.AccountName{"bp1", "bp2", "bp5"})
+	feedRound(nil)
+	feedRound(nil)
+	if len(alerts[AlertScheduleChange]) != 1 || pm.ScheduleVersion() != 2 {
+		t.Error("expected a schedule change")
+	}
+	for _, s := range pm.Stats() {
+		if s.Producer == "bp4" && (s.InSchedule || s.MissedRounds != 0) {
+			t.Errorf("bp4 should be out of the schedule without missed rounds: %+v", s)
+		}
+		if s.Producer == "bp5" && (!s.InSchedule || s.Produced != 2*ProducerRepetitions) {
+			t.Errorf("bp5 should be in the schedule: %+v", s)
+		}
+	}
+
+	// lib falling behind
+	pm.LibLagThreshold = 100
+	feed.LibLag = 200
+	feedRound(nil)
+	feedRound(nil)
+	if len(alerts[AlertLibLag]) != 1 {
+		t.Error("expected one lib lag alert, got", len(alerts[AlertLibLag]))
+	}
+
+	now := time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC)
+	pm.ClaimOverdue = 25 * time.Hour
+	producers := []Producer{
+		{Owner: "bp1", UnpaidBlocks: 500, LastClaimTime: "2021-01-01T00:00:00.000"},
+		{Owner: "bp2", UnpaidBlocks: 50, LastClaimTime: "2021-01-02T12:00:00.000"},
+	}
+	pm.UpdateProducers(producers, now)
+	pm.UpdateProducers(producers, now)
+	if len(alerts[AlertClaimOverdue]) != 1 || alerts[AlertClaimOverdue][0].Producer != "bp1" {
+		t.Error("expected one claim overdue alert for bp1", alerts[AlertClaimOverdue])
+	}
+
+	lag, _ = pm.LibLag()
+	buf := bytes.NewBuffer(nil)
+	if err := pm.WriteMetrics(buf); err != nil {
+		t.Error(err)
+	}
+	for _, line := range []string{
+		`fio_producer_rounds_missed_total{producer="bp3"} 1`,
+		`fio_producer_blocks_missed_total{producer="bp2"} 3`,
+		`fio_producer_unpaid_blocks{producer="bp1"} 500`,
+		`fio_producer_in_schedule{producer="bp4"} 0`,
+		fmt.Sprintf("fio_lib_lag_blocks %d", lag),
+		`fio_schedule_version 2`,
+		`fio_schedule_changes_total 1`,
+		`# TYPE fio_producer_blocks_produced_total counter`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Error("metrics missing", line)
+		}
+	}
+}
+
+func TestProducerMonitor_Run(t *testing.T) {
+	var mux sync.Mutex
+	var infos, lib, maxBlock uint32
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+		switch r.URL.Path {
+		case "/v1/chain/get_producer_schedule":
+			_, _ = w.Write([]byte(`{"active":{"version":1,"producers":[{"producer_name":"bp1"},{"producer_name":"bp2"}]}}`))
+		case "/v1/chain/get_producers":
+			// rows as returned by nodeos, the second producer has never claimed
+			_, _ = w.Write([]byte(`{"producers":[
+{"id":1,"owner":"bp1","fio_address":"bp@one","addresshash":"0x2f1dd6e0e0d8f3ff2b0cc5d0d0f4b5ff","total_votes":"113186532925149032.00000000000000000","producer_public_key":"FIO5oBUYbtGTxMS66pPkjC2p8pbA3zCtc8XD4dq9cMut867GRdh82","is_active":1,"url":"https://bp.one","unpaid_blocks":2052,"last_claim_time":"2021-03-10T18:03:49.500","last_bpclaim":1615399429,"location":80},
+{"id":2,"owner":"bp2","fio_address":"bp@two","addresshash":"0x7a4e8b2f5c4d1e0a9b8c7d6e5f4a3b2c","total_votes":"0.00000000000000000","producer_public_key":"FIO7isxEua78KPVbGzKemH4nj2bWE52gqj8Hkac3tc7jKNvpfWzYS","is_active":1,"url":"https://bp.two","unpaid_blocks":10,"last_claim_time":"1970-01-01T00:00:00.000","last_bpclaim":0,"location":80}],
+"total_producer_vote_weight":"113186532925149032.00000000000000000","more":""}`))
+		case "/v1/chain/get_info":
+			infos++
+			lib = 100 + 3*(infos-1)
+			if infos == 3 {
+				cancel()
+			}
+			_, _ = fmt.Fprintf(w, `{"head_block_num":%d,"last_irreversible_block_num":%d}`, lib+10, lib)
+		case "/v1/chain/get_block":
+			req := make(map[string]string)
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			var num uint32
+			_, _ = fmt.Sscanf(req["block_num_or_id"], "%d", &num)
+			if num > lib {
+				t.Errorf("block %d is past lib %d", num, lib)
+			}
+			if num > maxBlock {
+				maxBlock = num
+			}
+			_, _ = fmt.Fprintf(w, `{"block_num":%d,"producer":"bp%d","timestamp":"2021-03-11T00:00:00.000","schedule_version":1}`, num, num/12%2+1)
+		}
+	}))
+	defer server.Close()
+
+	pm := NewProducerMonitor(&API{*eos.New(server.URL)})
+	pm.ClaimOverdue = 24 * time.Hour
+	alerts := make([]MonitorAlert, 0)
+	pm.OnAlert = func(a MonitorAlert) {
+		alerts = append(alerts, a)
+	}
+	if err := pm.Run(ctx, time.Millisecond); err != context.Canceled {
+		t.Error("run should stop when cancelled, got", err)
+	}
+	// the context is cancelled on the third get_info, so the last lib followed is 103
+	if maxBlock != 103 {
+		t.Error("should have followed lib to block 103, got", maxBlock)
+	}
+	if lag, _ := pm.LibLag(); lag != 10 {
+		t.Error("lib lag should be measured from the node's head, got", lag)
+	}
+
+	stats := make(map[eos.AccountName]ProducerStats)
+	for _, s := range pm.Stats() {
+		stats[s.Producer] = s
+	}
+	if want := time.Date(2021, 3, 10, 18, 3, 49, 500000000, time.UTC); !stats["bp1"].LastClaimTime.Equal(want) {
+		t.Error("wrong claim time", stats["bp1"].LastClaimTime)
+	}
+	if !stats["bp2"].LastClaimTime.IsZero() {
+		t.Error("never claimed should be the zero time", stats["bp2"].LastClaimTime)
+	}
+	if len(alerts) != 1 || alerts[0].Kind != AlertClaimOverdue || alerts[0].Producer != "bp1" {
+		t.Errorf("expected a claim overdue alert for bp1 only: %+v", alerts)
+	}
+}
+
+func TestProducerMonitor_RunScheduleChange(t *testing.T) {
+	// the schedule changes to version 2 at block 110, LIB is followed from 100 to 120, and by the time the monitor
+	// reaches block 110 the node's active schedule is already version 2
+	for _, test := range []struct {
+		name     string
+		announce bool // the new schedule is in block 105's new_producers
+		started  bool // the node's active schedule was already version 2 when the monitor started
+		requests uint32
+		changes  int
+	}{
+		{name: "announced", announce: true, requests: 1, changes: 1},
+		{name: "not announced", requests: 2, changes: 1},
+		{name: "started after change", started: true, requests: 2},
+	} {
+		var mux sync.Mutex
+		var infos, schedules uint32
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mux.Lock()
+			defer mux.Unlock()
+			switch r.URL.Path {
+			case "/v1/chain/get_producer_schedule":
+				schedules++
+				if schedules == 1 && !test.started {
+					_, _ = w.Write([]byte(`{"active":{"version":1,"producers":[{"producer_name":"bp1"},{"producer_name":"bp2"}]}}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"active":{"version":2,"producers":[{"producer_name":"bp3"},{"producer_name":"bp4"}]}}`))
+			case "/v1/chain/get_producers":
+				_, _ = w.Write([]byte(`{"producers":[],"more":""}`))
+			case "/v1/chain/get_info":
+				infos++
+				lib := 100 + 20*(infos-1)
+				if infos == 3 {
+					cancel()
+				}
+				_, _ = fmt.Fprintf(w, `{"head_block_num":%d,"last_irreversible_block_num":%d}`, lib+10, lib)
+			case "/v1/chain/get_block":
+				req := make(map[string]string)
+				_ = json.NewDecoder(r.Body).Decode(&req)
+				var num uint32
+				_, _ = fmt.Sscanf(req["block_num_or_id"], "%d", &num)
+				version, producer, newProducers := 1, fmt.Sprintf("bp%d", num%2+1), "null"
+				if num >= 110 {
+					version, producer = 2, fmt.Sprintf("bp%d", num%2+3)
+				}
+				if num == 105 && test.announce {
+					newProducers = `{"version":2,"producers":[{"producer_name":"bp3"},{"producer_name":"bp4"}]}`
+				}
+				_, _ = fmt.Fprintf(w, `{"block_num":%d,"producer":"%s","timestamp":"2021-03-11T00:00:00.000","schedule_version":%d,"new_producers":%s}`,
+					num, producer, version, newProducers)
+			}
+		}))
+
+		pm := NewProducerMonitor(&API{*eos.New(server.URL)})
+		changes := 0
+		pm.OnAlert = func(a MonitorAlert) {
+			if a.Kind == AlertScheduleChange {
+				changes++
+			}
+		}
+		if err := pm.Run(ctx, time.Millisecond); err != context.Canceled {
+			t.Error("run should stop when cancelled, got", err)
+		}
+		cancel()
+		server.Close()
+
+		if pm.ScheduleVersion() != 2 || changes != test.changes {
+			t.Errorf("%s: expected %d changes to version 2, got version %d and %d changes", test.name, test.changes, pm.ScheduleVersion(), changes)
+		}
+		// the node is only asked once for each version that hasn't been seen
+		if schedules != test.requests {
+			t.Errorf("%s: expected %d get_producer_schedule requests, got %d", test.name, test.requests, schedules)
+		}
+		stats := make(map[eos.AccountName]ProducerStats)
+		for _, s := range pm.Stats() {
+			stats[s.Producer] = s
+		}
+		if stats["bp1"].InSchedule || !stats["bp3"].InSchedule {
+			t.Errorf("%s: bp3 should have replaced bp1 in the schedule", test.name)
+		}
+	}
+}
@@ -317,22 +317,9 @@ func (api *API) getBpJson(producer eos.AccountName, allowIp bool) (*BpJson, erro
 	}
 	// ensure this is 1) a hostname, and 2) does not resolve to a private IP range:
 	if !allowIp {
-		ip := net.ParseIP(u.Host)
-		if ip != nil {
-			return nil, errors.New("URL is an IP address, refusing to fetch")
-		}
-		addrs, err := net.LookupHost(u.Host)
-		if err != nil {
+		if err = checkPublicHost(u.Hostname()); err != nil {
 			return nil, err
 		}
-		if len(addrs) == 0 {
-			return nil, errors.New("could not resolve DNS for url")
-		}
-		for _, ip := range addrs {
-			if isPrivate(net.ParseIP(ip)) {
-				return nil, errors.New("url points to a private IP address, refusing to continue")
-			}
-		}
 	}
 
 	var regJson, chainJson string
@@ -384,6 +371,26 @@ func (api *API) getBpJson(producer eos.AccountName, allowIp bool) (*BpJson, erro
 	return bpj, nil
 }
 
+// checkPublicHost ensures a host is a hostname, not an IP address, and that it does not resolve to a private IP
+func checkPublicHost(host string) error {
+	if net.ParseIP(host) != nil {
+		return errors.New("URL is an IP address, refusing to fetch")
+	}
+	addrs, err := net.LookupHost(host)
+	if err != nil {
+		return err
+	}
+	if len(addrs) == 0 {
+		return errors.New("could not resolve DNS for url")
+	}
+	for _, ip := range addrs {
+		if isPrivate(net.ParseIP(ip)) {
+			return errors.New("url points to a private IP address, refusing to continue")
+		}
+	}
+	return nil
+}
+
 // adapted from https://github.com/emitter-io/address/blob/master/ipaddr.go
 // Copyright (c) 2018 Roman Atachiants
 var privateBlocks = [...]*net.IPNet{
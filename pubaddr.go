@@ -0,0 +1,510 @@
+package fio
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos/btcsuite/btcutil/base58"
+	"github.com/fioprotocol/fio-go/eos/ecc"
+	"golang.org/x/crypto/sha3"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxPubAddressLen is the longest public address accepted by the fio.address contract
+const maxPubAddressLen = 128
+
+// PubAddressValidator checks the format of a public address for a chain, returning an error describing why it is
+// invalid. Validators are registered by chain code with RegisterPubAddressValidator.
+type PubAddressValidator func(publicAddress string) error
+
+var pubAddressValidators = make(map[string]PubAddressValidator)
+var pubAddressValidatorMux sync.RWMutex
+
+func init() {
+	for chain, v := range map[string]PubAddressValidator{
+		"BTC":   ValidateBtcAddress,
+		"LTC":   ValidateLtcAddress,
+		"DOGE":  ValidateDogeAddress,
+		"BCH":   ValidateBchAddress,
+		"ETH":   ValidateEvmAddress,
+		"ETC":   ValidateEvmAddress,
+		"BSC":   ValidateEvmAddress,
+		"MATIC": ValidateEvmAddress,
+		"FIO":   ValidateFioPubKey,
+		"XRP":   ValidateXrpAddress,
+		"XLM":   ValidateXlmAddress,
+	} {
+		RegisterPubAddressValidator(chain, v)
+	}
+}
+
+// RegisterPubAddressValidator sets the validator for a chain code, replacing any existing validator. Passing a nil
+// validator removes it.
+func RegisterPubAddressValidator(chainCode string, validator PubAddressValidator) {
+	pubAddressValidatorMux.Lock()
+	defer pubAddressValidatorMux.Unlock()
+	if validator == nil {
+		delete(pubAddressValidators, strings.ToUpper(chainCode))
+		return
+	}
+	pubAddressValidators[strings.ToUpper(chainCode)] = validator
+}
+
+// GetPubAddressValidator returns the validator for a chain code, if one is registered
+func GetPubAddressValidator(chainCode string) (validator PubAddressValidator, ok bool) {
+	pubAddressValidatorMux.RLock()
+	defer pubAddressValidatorMux.RUnlock()
+	validator, ok = pubAddressValidators[strings.ToUpper(chainCode)]
+	return
+}
+
+// PubAddressError describes an invalid public address mapping, Index is the position in the list that was checked.
+type PubAddressError struct {
+	Index   int
+	Address TokenPubAddr
+	Err     error
+}
+
+func (e *PubAddressError) Error() string {
+	return fmt.Sprintf("public_addresses[%d] %s/%s %q: %s", e.Index, e.Address.ChainCode, e.Address.TokenCode, e.Address.PublicAddress, e.Err)
+}
+
+// PubAddressErrors holds every invalid mapping found by ValidatePubAddresses
+type PubAddressErrors []*PubAddressError
+
+func (e PubAddressErrors) Error() string {
+	s := make([]string, len(e))
+	for i := range e {
+		s[i] = e[i].Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+// Validate checks the chain and token codes, and uses the validator registered for the chain code to check the
+// public address. Chains without a validator only have their length checked.
+func (tpa TokenPubAddr) Validate() error {
+	if !ValidChainCode(tpa.ChainCode) {
+		return errors.New("chain code must be 1 to 10 alphanumeric characters")
+	}
+	if !ValidTokenCode(tpa.TokenCode) {
+		return errors.New("token code must be 1 to 10 alphanumeric characters or *")
+	}
+	if len(tpa.PublicAddress) == 0 || len(tpa.PublicAddress) > maxPubAddressLen {
+		return fmt.Errorf("public address must be 1 to %d characters", maxPubAddressLen)
+	}
+	if validator, ok := GetPubAddressValidator(tpa.ChainCode); ok {
+		return validator(tpa.PublicAddress)
+	}
+	return nil
+}
+
+// ValidatePubAddresses checks a list of mappings, returning PubAddressErrors if any are invalid
+func ValidatePubAddresses(addrs []TokenPubAddr) error {
+	errs := make(PubAddressErrors, 0)
+	for i := range addrs {
+		if err := addrs[i].Validate(); err != nil {
+			errs = append(errs, &PubAddressError{Index: i, Address: addrs[i], Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// base58Addr checks a base58check address, and that the version byte is one of versions with a 20 byte payload
+func base58Addr(publicAddress string, versions ...byte) error {
+	payload, version, err := base58.CheckDecode(publicAddress)
+	if err != nil {
+		return fmt.Errorf("invalid base58check encoding: %s", err)
+	}
+	if len(payload) != 20 {
+		return fmt.Errorf("expected a 20 byte hash, got %d bytes", len(payload))
+	}
+	for _, v := range versions {
+		if v == version {
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected version byte 0x%02x", version)
+}
+
+// segwitAddr checks a bech32 (witness v0) or bech32m (witness v1+) address with the expected human readable prefix
+func segwitAddr(publicAddress string, hrp string) error {
+	prefix, data, variant, err := bech32Decode(publicAddress)
+	if err != nil {
+		return err
+	}
+	if prefix != hrp {
+		return fmt.Errorf("expected prefix %s, got %s", hrp, prefix)
+	}
+	if len(data) < 1 || data[0] > 16 {
+		return errors.New("invalid witness version")
+	}
+	program, err := convertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return err
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return fmt.Errorf("invalid witness program length %d", len(program))
+	}
+	if data[0] == 0 {
+		if variant != bech32Const {
+			return errors.New("witness v0 address must use bech32, not bech32m")
+		}
+		if len(program) != 20 && len(program) != 32 {
+			return fmt.Errorf("invalid witness v0 program length %d", len(program))
+		}
+	} else if variant != bech32mConst {
+		return fmt.Errorf("witness v%d address must use bech32m, not bech32", data[0])
+	}
+	return nil
+}
+
+// isBech32 guesses if an address is bech32 encoded so that the more useful error can be returned
+func isBech32(publicAddress string, hrp string) bool {
+	return strings.HasPrefix(strings.ToLower(publicAddress), hrp+"1")
+}
+
+// ValidateBtcAddress accepts mainnet P2PKH and P2SH base58check addresses, and segwit addresses (bech32 for
+// witness v0, bech32m for taproot and later versions).
+func ValidateBtcAddress(publicAddress string) error {
+	if isBech32(publicAddress, "bc") {
+		return segwitAddr(publicAddress, "bc")
+	}
+	return base58Addr(publicAddress, 0x00, 0x05)
+}
+
+// ValidateLtcAddress accepts mainnet L, M and legacy 3 prefixed base58check addresses, and ltc1 segwit addresses.
+func ValidateLtcAddress(publicAddress string) error {
+	if isBech32(publicAddress, "ltc") {
+		return segwitAddr(publicAddress, "ltc")
+	}
+	return base58Addr(publicAddress, 0x30, 0x32, 0x05)
+}
+
+// ValidateDogeAddress accepts mainnet D (P2PKH) and 9 or A (P2SH) prefixed base58check addresses.
+func ValidateDogeAddress(publicAddress string) error {
+	return base58Addr(publicAddress, 0x1e, 0x16)
+}
+
+// ValidateBchAddress accepts cashaddr addresses, with or without the bitcoincash: prefix, and legacy base58check
+// addresses.
+func ValidateBchAddress(publicAddress string) error {
+	lower := strings.ToLower(publicAddress)
+	if strings.HasPrefix(lower, "bitcoincash:") || strings.HasPrefix(lower, "q") || strings.HasPrefix(lower, "p") {
+		return cashAddr(publicAddress)
+	}
+	return base58Addr(publicAddress, 0x00, 0x05)
+}
+
+// ValidateEvmAddress checks a 0x prefixed, 20 byte hex address. If the address uses mixed case it must have a
+// valid EIP-55 checksum, all lower or all upper case addresses are accepted without a checksum.
+func ValidateEvmAddress(publicAddress string) error {
+	if len(publicAddress) != 42 || !strings.HasPrefix(publicAddress, "0x") {
+		return errors.New("expected 0x followed by 40 hex characters")
+	}
+	h := publicAddress[2:]
+	if _, err := hex.DecodeString(h); err != nil {
+		return errors.New("expected 0x followed by 40 hex characters")
+	}
+	if h == strings.ToLower(h) || h == strings.ToUpper(h) {
+		return nil
+	}
+	if expected := eip55(h); expected != publicAddress {
+		return fmt.Errorf("invalid EIP-55 checksum, expected %s", expected)
+	}
+	return nil
+}
+
+// eip55 returns the checksummed form of a hex address without the 0x prefix
+func eip55(h string) string {
+	h = strings.ToLower(h)
+	k := sha3.NewLegacyKeccak256()
+	_, _ = k.Write([]byte(h))
+	sum := k.Sum(nil)
+	out := []byte(h)
+	for i := range out {
+		nibble := sum[i/2] >> 4
+		if i%2 == 1 {
+			nibble = sum[i/2] & 0x0f
+		}
+		if out[i] >= 'a' && nibble >= 8 {
+			out[i] -= 'a' - 'A'
+		}
+	}
+	return "0x" + string(out)
+}
+
+// ValidateFioPubKey checks for a FIO prefixed public key
+func ValidateFioPubKey(publicAddress string) error {
+	if !strings.HasPrefix(publicAddress, "FIO") {
+		return errors.New("expected a FIO public key")
+	}
+	if _, err := ecc.NewPublicKey(publicAddress); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseQuery splits an address with optional parameters, such as "rAddress?dt=123", and rejects unknown parameters.
+func parseQuery(publicAddress string, allowed ...string) (address string, params url.Values, err error) {
+	parts := strings.SplitN(publicAddress, "?", 2)
+	if len(parts) == 1 {
+		return parts[0], url.Values{}, nil
+	}
+	params, err = url.ParseQuery(parts[1])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid parameters: %s", err)
+	}
+	for k, v := range params {
+		known := false
+		for _, a := range allowed {
+			if k == a {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return "", nil, fmt.Errorf("unknown parameter %s", k)
+		}
+		if len(v) != 1 {
+			return "", nil, fmt.Errorf("parameter %s must only be set once", k)
+		}
+	}
+	return parts[0], params, nil
+}
+
+const (
+	rippleAlphabet  = "rpshnaf39wBUDNEGHJKLM4PQRST7VWXYZ2bcdeCg65jkm8oFqi1tuvAxyz"
+	bitcoinAlphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+)
+
+// ValidateXrpAddress checks a classic r-address, which may have a destination tag using the FIO convention of
+// "rAddress?dt=123".
+func ValidateXrpAddress(publicAddress string) error {
+	address, params, err := parseQuery(publicAddress, "dt")
+	if err != nil {
+		return err
+	}
+	if dt := params.Get("dt"); dt != "" {
+		if _, err = strconv.ParseUint(dt, 10, 32); err != nil {
+			return fmt.Errorf("destination tag must be a 32 bit unsigned integer")
+		}
+	}
+	if !strings.HasPrefix(address, "r") {
+		return errors.New("expected an address starting with r")
+	}
+	// translate to the bitcoin alphabet so the base58check decoder can be used
+	translated := make([]byte, len(address))
+	for i := range address {
+		pos := strings.IndexByte(rippleAlphabet, address[i])
+		if pos < 0 {
+			return fmt.Errorf("invalid character %q", address[i])
+		}
+		translated[i] = bitcoinAlphabet[pos]
+	}
+	return base58Addr(string(translated), 0x00)
+}
+
+// ValidateXlmAddress checks a G prefixed Stellar account id, which may have a memo using the FIO convention of
+// "GAddress?memo=text". The memo may be text of up to 28 bytes, which includes numeric memo ids, or a 32 byte hex
+// memo hash.
+func ValidateXlmAddress(publicAddress string) error {
+	address, params, err := parseQuery(publicAddress, "memo")
+	if err != nil {
+		return err
+	}
+	if memo, ok := params["memo"]; ok {
+		if err = xlmMemo(memo[0]); err != nil {
+			return err
+		}
+	}
+	if len(address) != 56 || address[0] != 'G' {
+		return errors.New("expected a 56 character account id starting with G")
+	}
+	raw, err := base32.StdEncoding.DecodeString(address)
+	if err != nil {
+		return fmt.Errorf("invalid base32 encoding: %s", err)
+	}
+	if raw[0] != 6<<3 {
+		return errors.New("not an account id")
+	}
+	sum := crc16(raw[:33])
+	if raw[33] != byte(sum) || raw[34] != byte(sum>>8) {
+		return errors.New("invalid checksum")
+	}
+	return nil
+}
+
+func xlmMemo(memo string) error {
+	switch {
+	case memo == "":
+		return errors.New("memo is empty")
+	case len(memo) <= 28:
+		return nil
+	case len(memo) == 64:
+		if _, err := hex.DecodeString(memo); err == nil {
+			return nil
+		}
+	}
+	return errors.New("memo must be at most 28 bytes, or a 32 byte hex hash")
+}
+
+// crc16 is the CRC16-XModem checksum used by Stellar
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+const (
+	bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32Const   = 1
+	bech32mConst  = 0x2bc830a3
+)
+
+func bech32Polymod(values []byte) uint32 {
+	gen := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+// bech32Decode returns the human readable part, the data without the checksum, and which constant the checksum
+// matched, bech32Const or bech32mConst.
+func bech32Decode(s string) (hrp string, data []byte, variant uint32, err error) {
+	if len(s) > 90 {
+		return "", nil, 0, errors.New("bech32 address is too long")
+	}
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, 0, errors.New("bech32 address can not be mixed case")
+	}
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, 0, errors.New("invalid bech32 separator position")
+	}
+	hrp = s[:sep]
+	values := make([]byte, 0, len(hrp)*2+1+len(s)-sep-1)
+	for i := range hrp {
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := range hrp {
+		values = append(values, hrp[i]&31)
+	}
+	data = make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		pos := strings.IndexByte(bech32Charset, s[i])
+		if pos < 0 {
+			return "", nil, 0, fmt.Errorf("invalid bech32 character %q", s[i])
+		}
+		data = append(data, byte(pos))
+	}
+	variant = bech32Polymod(append(values, data...))
+	if variant != bech32Const && variant != bech32mConst {
+		return "", nil, 0, errors.New("invalid bech32 checksum")
+	}
+	return hrp, data[:len(data)-6], variant, nil
+}
+
+// convertBits regroups bits, for example from 5 bit bech32 values to bytes
+func convertBits(data []byte, fromBits uint, toBits uint, pad bool) ([]byte, error) {
+	acc, bits := uint32(0), uint(0)
+	maxv := uint32(1)<<toBits - 1
+	out := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, v := range data {
+		if uint32(v)>>fromBits != 0 {
+			return nil, errors.New("invalid data range")
+		}
+		acc = acc<<fromBits | uint32(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(toBits-bits)&maxv))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxv != 0 {
+		return nil, errors.New("invalid padding")
+	}
+	return out, nil
+}
+
+func cashAddrPolymod(values []byte) uint64 {
+	gen := []uint64{0x98f2bc8e61, 0x79b76d99e2, 0xf33e5fb3c4, 0xae2eabe2a8, 0x1e4f43e470}
+	c := uint64(1)
+	for _, v := range values {
+		top := c >> 35
+		c = (c&0x07ffffffff)<<5 ^ uint64(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				c ^= gen[i]
+			}
+		}
+	}
+	return c ^ 1
+}
+
+// cashAddr checks a Bitcoin Cash cashaddr, the bitcoincash: prefix is optional
+func cashAddr(publicAddress string) error {
+	if strings.ToLower(publicAddress) != publicAddress && strings.ToUpper(publicAddress) != publicAddress {
+		return errors.New("cashaddr can not be mixed case")
+	}
+	s := strings.TrimPrefix(strings.ToLower(publicAddress), "bitcoincash:")
+	if len(s) < 9 {
+		return errors.New("cashaddr is too short")
+	}
+	values := make([]byte, 0, len("bitcoincash")+1+len(s))
+	for _, c := range []byte("bitcoincash") {
+		values = append(values, c&31)
+	}
+	values = append(values, 0)
+	for i := range s {
+		pos := strings.IndexByte(bech32Charset, s[i])
+		if pos < 0 {
+			return fmt.Errorf("invalid cashaddr character %q", s[i])
+		}
+		values = append(values, byte(pos))
+	}
+	if cashAddrPolymod(values) != 0 {
+		return errors.New("invalid cashaddr checksum")
+	}
+	payload, err := convertBits(values[len("bitcoincash")+1:len(values)-8], 5, 8, false)
+	if err != nil {
+		return err
+	}
+	if len(payload) == 0 || payload[0]&0x80 != 0 || payload[0]>>3 > 1 {
+		return errors.New("unsupported cashaddr type")
+	}
+	sizes := []int{20, 24, 28, 32, 40, 48, 56, 64}
+	if size := sizes[payload[0]&7]; len(payload)-1 != size {
+		return fmt.Errorf("expected a %d byte hash, got %d bytes", size, len(payload)-1)
+	}
+	return nil
+}
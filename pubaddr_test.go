@@ -0,0 +1,124 @@
+package fio
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTokenPubAddr_Validate(t *testing.T) {
+	account, _ := NewRandomAccount()
+	for _, test := range []struct {
+		chain   string
+		address string
+		valid   bool
+	}{
+		{"BTC", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", true},
+		{"BTC", "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", true},
+		{"BTC", "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", true},
+		{"BTC", "BC1QAR0SRRR7XFKVY5L643LYDNW9RE59GTZZWF5MDQ", true},
+		{"BTC", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", true},
+		{"BTC", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN3", false},                                         // checksum
+		{"BTC", "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdQ", false},                                 // mixed case
+		{"BTC", "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdp", false},                                 // checksum
+		{"BTC", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh", false},                                 // v0 with bech32m
+		{"BTC", "bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7k7grplx", false}, // v1 with bech32
+		{"BTC", "LaMT348PWRnrqeeWArpwQPbuanpXDZGEUz", false},                                         // litecoin
+		{"BTC", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", false},
+		{"LTC", "LaMT348PWRnrqeeWArpwQPbuanpXDZGEUz", true},
+		{"LTC", "MGxNPPB7eBoWPUaprtX9v9CXJZoD2465zN", true},
+		{"LTC", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", false},
+		{"DOGE", "DH5yaieqoZN36fDVciNyRueRGvGLR3mr7L", true},
+		{"DOGE", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", false},
+		{"BCH", "bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a", true},
+		{"BCH", "ppm2qsznhks23z7629mms6s4cwef74vcwvn0h829pq", true},
+		{"BCH", "1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu", true},
+		{"BCH", "bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6b", false},
+		{"ETH", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", true},
+		{"ETH", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true},
+		{"ETH", "0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED", true},
+		{"ETH", "0x5aaeb6053F3E94C9b9A09f33669435E7Ef1BeAed", false},
+		{"ETH", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAe", false},
+		{"MATIC", "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", true},
+		{"FIO", account.PubKey, true},
+		{"FIO", "EOS" + account.PubKey[3:], false},
+		{"FIO", account.PubKey[:len(account.PubKey)-1] + "x", false},
+		{"XRP", "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh", true},
+		{"XRP", "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh?dt=4294967295", true},
+		{"XRP", "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh?dt=4294967296", false},
+		{"XRP", "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh?tag=1", false},
+		{"XRP", "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTi", false},
+		{"XLM", "GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ", true},
+		{"XLM", "GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ?memo=12345", true},
+		{"XLM", "GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ?memo=hello%20world", true},
+		{"XLM", "GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ?memo=" + strings.Repeat("ab", 32), true},
+		{"XLM", "GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ?memo=" + strings.Repeat("a", 29), false},
+		{"XLM", "GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ?memo=", false},
+		{"XLM", "GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGA", false},
+		{"XLM", "SA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ", false},
+		{"NOTLISTED", "anything", true},
+		{"NOTLISTED", strings.Repeat("a", 129), false},
+		{"BAD-CODE", "anything", false},
+	} {
+		err := TokenPubAddr{ChainCode: test.chain, TokenCode: test.chain, PublicAddress: test.address}.Validate()
+		if test.valid && err != nil {
+			t.Errorf("%s %s should be valid: %s", test.chain, test.address, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%s %s should be invalid", test.chain, test.address)
+		}
+	}
+}
+
+func TestRegisterPubAddressValidator(t *testing.T) {
+	defer RegisterPubAddressValidator("TEST", nil)
+	RegisterPubAddressValidator("test", func(publicAddress string) error {
+		if publicAddress != "ok" {
+			return errors.New("not ok")
+		}
+		return nil
+	})
+	if _, ok := GetPubAddressValidator("TEST"); !ok {
+		t.Error("chain codes should be case-insensitive")
+	}
+	if err := (TokenPubAddr{ChainCode: "TEST", TokenCode: "*", PublicAddress: "ok"}).Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (TokenPubAddr{ChainCode: "TEST", TokenCode: "*", PublicAddress: "nope"}).Validate(); err == nil {
+		t.Error("custom validator was not used")
+	}
+}
+
+func TestNewAddAddressesChecked(t *testing.T) {
+	addrs := []TokenPubAddr{
+		{ChainCode: "BTC", PublicAddress: "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq"},
+		{ChainCode: "ETH", TokenCode: "USDT", PublicAddress: "0x5aaeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
+		{TokenCode: "XRP", PublicAddress: "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh?dt=x"},
+	}
+	_, err := NewAddAddressesChecked("aloha", "alice@fio", addrs)
+	errs, ok := err.(PubAddressErrors)
+	if !ok {
+		t.Error("expected PubAddressErrors, got", err)
+		return
+	}
+	if len(errs) != 2 || errs[0].Index != 1 || errs[1].Index != 2 || errs[1].Address.ChainCode != "XRP" {
+		t.Error("wrong errors reported:", err)
+	}
+	if !strings.Contains(err.Error(), "EIP-55") {
+		t.Error("error should explain the problem:", err)
+	}
+
+	addrs[1].PublicAddress = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	addrs[2].PublicAddress = "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh?dt=1"
+	action, err := NewAddAddressesChecked("aloha", "alice@fio", addrs)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	added := action.Data.(AddAddress).PublicAddresses
+	if added[0].TokenCode != "BTC" || added[2].ChainCode != "XRP" {
+		t.Error("missing chain or token code was not filled in", added)
+	}
+	if addrs[0].TokenCode != "" {
+		t.Error("should not modify the caller's slice")
+	}
+}
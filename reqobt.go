@@ -2,20 +2,12 @@ package fio
 
 import (
 	"bytes"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/hmac"
-	"crypto/rand"
-	"crypto/sha256"
 	"crypto/sha512"
-	"encoding/base64"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/fioprotocol/fio-go/ecies"
 	"github.com/fioprotocol/fio-go/eos"
-	"github.com/fioprotocol/fio-go/eos/btcsuite/btcutil"
 	"github.com/fioprotocol/fio-go/eos/ecc"
 	"io/ioutil"
 	"net/http"
@@ -52,44 +44,9 @@ type ObtRequestContent struct {
 	OfflineUrl         string `json:"offline_url"`
 }
 
-// ObtRequestContent holds details for requesting funds
-type obtRequestContentOmit struct {
-	PayeePublicAddress string `json:"payee_public_address"`
-	Amount             string `json:"amount"`
-	ChainCode          string `json:"chain_code"`
-	TokenCode          string `json:"token_code"`
-	Memo               string `json:"memo,omitempty"`
-	Hash               string `json:"hash,omitempty"`
-	OfflineUrl         string `json:"offline_url,omitempty"`
-}
-
-// Encrypt serializes and encrypts the 'content' field for OBT requests
+// Encrypt serializes and encrypts the 'content' field for OBT requests, using the default content version
 func (req ObtRequestContent) Encrypt(from *Account, toPubKey string) (content string, err error) {
-	reqOmit := obtRequestContentOmit{
-		PayeePublicAddress: req.PayeePublicAddress,
-		Amount:             req.Amount,
-		ChainCode:          req.ChainCode,
-		TokenCode:          req.TokenCode,
-		Memo:               req.Memo,
-		Hash:               req.Hash,
-		OfflineUrl:         req.OfflineUrl,
-	}
-	j, err := json.Marshal(reqOmit)
-	if err != nil {
-		return "", err
-	}
-	abiReader := bytes.NewReader([]byte(obtAbiJsonOmit))
-	abi, _ := eos.NewABI(abiReader)
-	//bin, err := abi.DecodeTableRowTyped("record_send_content", j)
-	bin, err := abi.EncodeAction("new_funds_content", j)
-	if err != nil {
-		return "", err
-	}
-	encrypted, err := EciesEncrypt(from, toPubKey, bin, nil)
-	if err != nil {
-		return "", err
-	}
-	return encrypted, nil
+	return req.EncryptVersion(from, toPubKey, ObtContentV1)
 }
 
 type ObtRecordContent struct {
@@ -105,55 +62,20 @@ type ObtRecordContent struct {
 	OfflineUrl         string `json:"offline_url"`
 }
 
-type obtRecordContentOmit struct {
-	PayerPublicAddress string `json:"payer_public_address"`
-	PayeePublicAddress string `json:"payee_public_address"`
-	Amount             string `json:"amount"`
-	ChainCode          string `json:"chain_code"`
-	TokenCode          string `json:"token_code"`
-	Status             string `json:"status"`
-	ObtId              string `json:"obt_id"`
-	Memo               string `json:"memo,omitempty"`
-	Hash               string `json:"hash,omitempty"`
-	OfflineUrl         string `json:"offline_url,omitempty"`
-}
-
-// Encrypt serializes and encrypts the 'content' field for OBT requests
+// Encrypt serializes and encrypts the 'content' field for OBT requests, using the default content version
 func (rec ObtRecordContent) Encrypt(from *Account, toPubKey string) (content string, err error) {
-	recOmit := obtRecordContentOmit{
-		rec.PayerPublicAddress,
-		rec.PayeePublicAddress,
-		rec.Amount,
-		rec.ChainCode,
-		rec.TokenCode,
-		rec.Status,
-		rec.ObtId,
-		rec.Memo,
-		rec.Hash,
-		rec.OfflineUrl,
-	}
-	j, err := json.Marshal(recOmit)
-	if err != nil {
-		return "", err
-	}
-	abiReader := bytes.NewReader([]byte(obtAbiJsonOmit))
-	abi, _ := eos.NewABI(abiReader)
-	//bin, err := abi.DecodeTableRowTyped("record_send_content", j)
-	bin, err := abi.EncodeAction("record_send_content", j)
-	if err != nil {
-		return "", err
-	}
-	encrypted, err := EciesEncrypt(from, toPubKey, bin, nil)
-	if err != nil {
-		return "", err
-	}
-	return encrypted, nil
+	return rec.EncryptVersion(from, toPubKey, ObtContentV1)
 }
 
 type ObtContentResult struct {
 	Type    ObtType
 	Request *ObtRequestContent
 	Record  *ObtRecordContent
+
+	// Version is the registered content version that matched, and Content holds all of the decoded fields,
+	// including any that are not in Request or Record.
+	Version string
+	Content json.RawMessage
 }
 
 func (c ObtContentResult) ToJson() ([]byte, error) {
@@ -174,34 +96,36 @@ func (c ObtContentResult) ToJson() ([]byte, error) {
 	return nil, errors.New("unknown request type")
 }
 
-// DecryptContent provides a new populated ObtContentResult struct given an encrypted content payload
+// DecryptContent provides a new populated ObtContentResult struct given an encrypted content payload. Each registered
+// content version is tried, see RegisterObtContent.
 func DecryptContent(to *Account, fromPubKey string, encrypted string, obtType ObtType) (*ObtContentResult, error) {
 	result := &ObtContentResult{
 		Type: obtType,
 	}
+	if obtType != ObtRequestType && obtType != ObtResponseType {
+		return nil, errors.New("unknown obtType: expecting fio.ObtResponseType or fio.ObtRequestType")
+	}
 
 	bin, err := EciesDecrypt(to, fromPubKey, encrypted)
 	if err != nil {
 		return nil, err
 	}
+	result.Content, result.Version, err = decodeObtContent(bin, obtType.String())
+	if err != nil {
+		return nil, err
+	}
 	switch obtType {
 	case ObtRequestType:
-		content, err := tryDecryptRequest(bin, obtType)
-		if err != nil {
-			return nil, err
-		}
-		result.Request = content
-		return result, nil
-
+		result.Request = &ObtRequestContent{}
+		err = json.Unmarshal(result.Content, result.Request)
 	case ObtResponseType:
-		content, err := tryDecryptRecord(bin, obtType)
-		if err != nil {
-			return nil, err
-		}
-		result.Record = content
-		return result, nil
+		result.Record = &ObtRecordContent{}
+		err = json.Unmarshal(result.Content, result.Record)
+	}
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.New("unknown obtType: expecting fio.ObtResponseType or fio.ObtRequestType")
+	return result, nil
 }
 
 type RecordSend struct {
@@ -230,6 +154,13 @@ func NewRecordSend(actor eos.AccountName, reqId string, payer string, payee stri
 	)
 }
 
+// NewRecordObt builds the action for recording an off-chain transaction that was not in response to a request, the
+// record is stored in the recordobts table instead of being tied to a request. The content should be an encrypted
+// ObtRecordContent.
+func NewRecordObt(actor eos.AccountName, payer string, payee string, content string) *Action {
+	return NewRecordSend(actor, "", payer, payee, content)
+}
+
 // FundsReq is a request sent from one user to another requesting funds
 type FundsReq struct {
 	PayerFioAddress string `json:"payer_fio_address"`
@@ -341,191 +272,34 @@ func NewRejectFndReq(actor eos.AccountName, requestId string) *Action {
 	)
 }
 
-// EciesEncrypt implements the encryption format used in the content field of OBT requests.
-//
-// The plaintext is PKCS#7 padded before being encrypted -- returned output is base64.
-//
-// Key derivation, and message format:
-//
-// A DH shared secret is created using ECIES (derives a key based on the curves of the public and private keys.)
-// This secret is hashed *twice* using sha512, and the first 32 bytes of the hash is used to encrypt the message using
-// AES-256 cbc, and the second half is used to create an outer sha256 hmac.
-//
-// The 16 byte IV is prepended to the output, resulting in the message format of:
-//  IV + Ciphertext + HMAC
-// See https://github.com/fioprotocol/fiojs/blob/master/docs/message_encryption.md for more information.
+// EciesEncrypt implements the encryption format used in the content field of OBT requests, returned output is
+// base64. If iv is not 16 bytes, or is all zeros, a random IV is used. See the ecies package for details on the format.
 func EciesEncrypt(sender *Account, recipentPub string, plainText []byte, iv []byte) (content string, err error) {
-
-	// Get the shared-secret
-	_, secretHash, err := EciesSecret(sender, recipentPub)
-	if err != nil {
-		return "", err
-	}
-	hashAgain := sha512.New()
-	_, err = hashAgain.Write(secretHash[:])
-	if err != nil {
-		return "", err
-	}
-	keys := hashAgain.Sum(nil)
-	key := append(keys[:32])    // first half of sha512 hash of secret is used as key
-	macKey := append(keys[32:]) // second half as hmac key
-
-	// Generate IV
-	var contentBuffer bytes.Buffer
-	if len(iv) != 16 || bytes.Equal(iv, make([]byte, 16)) {
-		iv = make([]byte, 16)
-		_, err = rand.Read(iv)
-		if err != nil {
-			return "", err
-		}
-	}
-	contentBuffer.Write(iv)
-
-	// AES CBC for encryption,
-	block, err := aes.NewCipher(key)
+	keys, err := eciesKeys(sender, recipentPub)
 	if err != nil {
 		return "", err
 	}
-	cbc := cipher.NewCBCEncrypter(block, iv)
-
-	//// create pkcs#7 padding
-	plainText = append(plainText, func() []byte {
-		padLen := block.BlockSize() - (len(plainText) % block.BlockSize())
-		pad := make([]byte, padLen)
-		for i := range pad {
-			pad[i] = uint8(padLen)
-		}
-		return pad
-	}()...)
-
-	// encrypt the plaintext
-	cipherText := make([]byte, len(plainText))
-	cbc.CryptBlocks(cipherText, plainText)
-	contentBuffer.Write(cipherText)
-
-	// Sign the message using sha256 hmac, *second* half of sha512 hash used as key
-	signer := hmac.New(sha256.New, macKey)
-	_, err = signer.Write(contentBuffer.Bytes())
-	if err != nil {
-		return "", err
+	if len(iv) != ecies.IVSize || bytes.Equal(iv, make([]byte, ecies.IVSize)) {
+		iv = nil
 	}
-	signature := signer.Sum(nil)
-	contentBuffer.Write(signature)
-
-	// base64 encode the message, and it's ready to be embedded in our FundsReq.Content or RecordSend.Content fields
-	b64Buffer := bytes.NewBuffer([]byte{})
-	encoded := base64.NewEncoder(base64.StdEncoding, b64Buffer)
-	_, err = encoded.Write(contentBuffer.Bytes())
-	_ = encoded.Close()
-	return string(b64Buffer.Bytes()), nil
+	return keys.EncryptBase64(plainText, iv)
 }
 
 // EciesDecrypt is the inverse of EciesEncrypt, using the recipient's private key and sender's public instead.
 func EciesDecrypt(recipient *Account, senderPub string, message string) (decrypted []byte, err error) {
-	const (
-		sigLen = 32
-	)
-
-	var msg []byte
-	// convert base64 string to []byte
-	b64Reader := bytes.NewReader([]byte(message))
-	b64Decoder := base64.NewDecoder(base64.StdEncoding, b64Reader)
-	msg, err = ioutil.ReadAll(b64Decoder)
-	if err != nil {
-		return nil, err
-	}
-
-	// Get the shared-secret
-	_, secretHash, err := EciesSecret(recipient, senderPub)
-	if err != nil {
-		return nil, err
-	}
-
-	// Other SDK's hash it TWICE, so we will too ...
-	hashTwice := sha512.New()
-	_, err = hashTwice.Write(secretHash[:])
-	if err != nil {
-		return nil, err
-	}
-	secret := hashTwice.Sum(nil)
-
-	// check the signature
-	verifier := hmac.New(sha256.New, secret[32:])
-	_, err = verifier.Write(msg[:len(msg)-sigLen])
+	keys, err := eciesKeys(recipient, senderPub)
 	if err != nil {
 		return nil, err
 	}
-	verified := verifier.Sum(nil)
-	if hex.EncodeToString(msg[len(msg)-sigLen:]) != hex.EncodeToString(verified) {
-		return nil,
-			errors.New(
-				fmt.Sprintf("hmac signature %s is invalid, expected %s",
-					hex.EncodeToString(verified),
-					hex.EncodeToString(msg[len(msg)-sigLen:]),
-				),
-			)
-	}
-
-	// decrypt the message
-	block, err := aes.NewCipher(secret[:32])
-	if err != nil {
-		return nil, err
-	}
-	cbc := cipher.NewCBCDecrypter(block, msg[:block.BlockSize()])
-	plainText := make([]byte, len(msg[block.BlockSize():len(msg)-sigLen]))
-	cbc.CryptBlocks(plainText, msg[block.BlockSize():len(msg)-sigLen])
-	if len(plainText) == 0 {
-		return nil, errors.New("could not decrypt message")
-	}
-
-	padLen := int(plainText[len(plainText)-1])
-	if padLen > block.BlockSize() || padLen >= len(plainText) {
-		return nil, errors.New("invalid padding in message")
-	}
-
-	return plainText[:len(plainText)-padLen], nil
+	return keys.DecryptBase64(message)
 }
 
-// depending on how the request was built it's possible to get a slightly different abi encoding,
-// this will try three different ways of decoding the request ...
-func tryDecryptRequest(bin []byte, obtType ObtType) (content *ObtRequestContent, err error) {
-	content = &ObtRequestContent{}
-	abiReader := bytes.NewReader([]byte(obtAbiJsonOmit))
-	abi, _ := eos.NewABI(abiReader)
-	decode, err := abi.DecodeTableRowTyped(obtType.String(), bin)
+func eciesKeys(private *Account, public string) (*ecies.Keys, error) {
+	_, secretHash, err := EciesSecret(private, public)
 	if err != nil {
-		abiReader = bytes.NewReader([]byte(ObtAbiJson))
-		abi, _ = eos.NewABI(abiReader)
-		decode, err = abi.DecodeTableRowTyped(obtType.String(), bin)
-		if err != nil {
-			err := eos.UnmarshalBinary(bin, content)
-			if err != nil {
-				return nil, err
-			}
-		}
-	}
-	err = json.Unmarshal(decode, content)
-	return
-}
-
-func tryDecryptRecord(bin []byte, obtType ObtType) (content *ObtRecordContent, err error) {
-	content = &ObtRecordContent{}
-	abiReader := bytes.NewReader([]byte(obtAbiJsonOmit))
-	abi, _ := eos.NewABI(abiReader)
-	decode, err := abi.DecodeTableRowTyped(obtType.String(), bin)
-	if err != nil {
-		abiReader = bytes.NewReader([]byte(ObtAbiJson))
-		abi, _ = eos.NewABI(abiReader)
-		decode, err = abi.DecodeTableRowTyped(obtType.String(), bin)
-		if err != nil {
-			err := eos.UnmarshalBinary(bin, content)
-			if err != nil {
-				return nil, err
-			}
-		}
+		return nil, err
 	}
-	err = json.Unmarshal(decode, content)
-	return
+	return ecies.NewKeys(*secretHash), nil
 }
 
 // EciesSecret derives the ecies pre-shared key from a private and public key.
@@ -533,32 +307,19 @@ func tryDecryptRecord(bin []byte, obtType ObtType) (content *ObtRecordContent, e
 // in the OBT implementation, allowing the secret to be stretched into two keys, one for
 // encryption and one for message authentication.
 func EciesSecret(private *Account, public string) (secret []byte, hash *[64]byte, err error) {
-	// convert key to ecies private key type
-	wif, err := btcutil.DecodeWIF(private.KeyBag.Keys[0].String())
-	if err != nil {
-		return nil, nil, err
-	}
-	priv := ecies.ImportECDSA(wif.PrivKey.ToECDSA())
-
-	// convert public key string into an ecies public key struct
-	eosPub, err := ecc.NewPublicKey(public)
-	if err != nil {
-		return nil, nil, err
+	if private == nil || private.KeyBag == nil || len(private.KeyBag.Keys) == 0 {
+		return nil, nil, errors.New("account does not have a private key")
 	}
-	epk, err := eosPub.Key()
+	pub, err := ecc.NewPublicKey(public)
 	if err != nil {
 		return nil, nil, err
 	}
-	pub := ecies.ImportECDSAPublic(epk.ToECDSA())
-
-	// derive the shared secret and hash it
-	sharedKey, err := priv.GenerateShared(pub, 32, 0)
+	secret, err = ecies.SharedKey(private.KeyBag.Keys[0], pub)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	ss := sha512.Sum512(sharedKey)
-	return sharedKey, &ss, nil
+	ss := sha512.Sum512(secret)
+	return secret, &ss, nil
 }
 
 type getPendingFioNamesRequest struct {
@@ -593,6 +354,17 @@ func (api API) GetSentFioRequests(pubKey string, limit int, offset int) (sentReq
 	return api.getFioRequests("sent", pubKey, limit, offset)
 }
 
+// GetReceivedFioRequests looks for all requests sent to a public key, regardless of status
+func (api *API) GetReceivedFioRequests(pubKey string, limit int, offset int) (receivedRequests PendingFioRequestsResponse, hasReceived bool, err error) {
+	return api.getFioRequests("received", pubKey, limit, offset)
+}
+
+// GetCancelledFioRequests looks for cancelled requests, unlike GetCancelledRequests it returns the same struct as the
+// other request queries, which includes the public keys needed to decrypt the content.
+func (api *API) GetCancelledFioRequests(pubKey string, limit int, offset int) (cancelledRequests PendingFioRequestsResponse, hasCancelled bool, err error) {
+	return api.getFioRequests("cancelled", pubKey, limit, offset)
+}
+
 func (api API) getFioRequests(requestType string, pubKey string, limit int, offset int) (pendingRequests PendingFioRequestsResponse, hasPending bool, err error) {
 	query := getPendingFioNamesRequest{
 		FioPublicKey: pubKey,
@@ -609,6 +381,12 @@ func (api API) getFioRequests(requestType string, pubKey string, limit int, offs
 		req, err = http.NewRequest("POST", api.BaseURL+`/v1/chain/get_pending_fio_requests`, bytes.NewBuffer(j))
 	case "sent":
 		req, err = http.NewRequest("POST", api.BaseURL+`/v1/chain/get_sent_fio_requests`, bytes.NewBuffer(j))
+	case "received":
+		req, err = http.NewRequest("POST", api.BaseURL+`/v1/chain/get_received_fio_requests`, bytes.NewBuffer(j))
+	case "cancelled":
+		req, err = http.NewRequest("POST", api.BaseURL+`/v1/chain/get_cancelled_fio_requests`, bytes.NewBuffer(j))
+	default:
+		return PendingFioRequestsResponse{}, false, errors.New("unknown request type: " + requestType)
 	}
 	if err != nil {
 		return PendingFioRequestsResponse{}, false, err
@@ -633,6 +411,76 @@ func (api API) getFioRequests(requestType string, pubKey string, limit int, offs
 	return
 }
 
+// ObtDataRecord is a record returned by the get_obt_data endpoint, these include both records sent in response to a
+// request, and records that were sent without a request.
+type ObtDataRecord struct {
+	PayerFioAddress   string       `json:"payer_fio_address"`
+	PayeeFioAddress   string       `json:"payee_fio_address"`
+	PayerFioPublicKey string       `json:"payer_fio_public_key"`
+	PayeeFioPublicKey string       `json:"payee_fio_public_key"`
+	Content           string       `json:"content"`
+	FioRequestId      eos.Uint64   `json:"fio_request_id"`
+	Status            string       `json:"status"`
+	TimeStamp         eos.JSONTime `json:"time_stamp"`
+}
+
+type ObtDataResponse struct {
+	Records []ObtDataRecord `json:"obt_data_records"`
+	More    int             `json:"more"`
+}
+
+// GetObtData gets the OBT records sent or received by a public key, the content is still encrypted, see
+// ObtDataRecord.Decrypt. A key without any records is not an error, and returns an empty response.
+func (api *API) GetObtData(pubKey string, limit int, offset int) (records *ObtDataResponse, hasRecords bool, err error) {
+	j, err := json.Marshal(getPendingFioNamesRequest{
+		FioPublicKey: pubKey,
+		Limit:        limit,
+		Offset:       offset,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	resp, err := api.HttpClient.Post(api.BaseURL+"/v1/chain/get_obt_data", "application/json", bytes.NewReader(j))
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		// the endpoint returns 404 when the key has no records
+		return &ObtDataResponse{Records: make([]ObtDataRecord, 0)}, false, nil
+	case resp.StatusCode > 299:
+		var apiErr eos.APIError
+		if err = json.Unmarshal(body, &apiErr); err != nil {
+			return nil, false, fmt.Errorf("get_obt_data: status code=%d, body=%s", resp.StatusCode, string(body))
+		}
+		return nil, false, apiErr
+	}
+	records = &ObtDataResponse{}
+	err = json.Unmarshal(body, records)
+	if err != nil {
+		return nil, false, err
+	}
+	return records, len(records.Records) > 0, nil
+}
+
+// Decrypt decrypts the content of a record, the account can be either the payer or the payee.
+func (rec ObtDataRecord) Decrypt(account *Account) (*ObtRecordContent, error) {
+	counterparty := rec.PayerFioPublicKey
+	if account.PubKey == rec.PayerFioPublicKey {
+		counterparty = rec.PayeeFioPublicKey
+	}
+	content, err := DecryptContent(account, counterparty, rec.Content, ObtResponseType)
+	if err != nil {
+		return nil, err
+	}
+	return content.Record, nil
+}
+
 // FundsReqTableResp has the most useful fields of what is stored in the fioreqctxts table. It is slightly different
 // than what is sent from the API endpoint, but is useful when a specific request needs to be retrieved.
 type FundsReqTableResp struct {
@@ -721,7 +569,7 @@ type FundsRequestStatusResp struct {
 
 // GetFioRequestStatus gets a record from the fioreqstss, which is useful for getting the recordobt response to a request.
 // This only applies to recordobt that was in response to a request, the recordobts table stores records not tied to an
-// existing request.
+// existing request, use GetObtData to get both.
 func (api *API) GetFioRequestStatus(requestId uint64) (hasResponse bool, request *FundsRequestStatusResp, err error) {
 	resp, err := api.GetTableRows(eos.GetTableRowsRequest{
 		Code:       "fio.reqobt",
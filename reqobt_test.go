@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
 	"math/rand"
+	"net/http"
 	"testing"
 	"time"
 )
@@ -321,3 +323,34 @@ func TestEncryptDecrypt(t *testing.T) {
 		}
 	}
 }
+
+func TestAPI_GetObtData(t *testing.T) {
+	node, api := newFakeNode(t)
+	status, body := http.StatusOK, `{"obt_data_records":[{"payer_fio_address":"alice@fio","status":"sent_to_blockchain"}],"more":0}`
+	node.handle("/v1/chain/get_obt_data", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	})
+
+	records, hasRecords, err := api.GetObtData("FIO7uRvrLVrZCbCM2DtCgUMospqUMnP3JUC1sKHA8zNoF835kJBvN", 10, 0)
+	if err != nil || !hasRecords || records.Records[0].PayerFioAddress != "alice@fio" {
+		t.Error("expected a record", records, err)
+	}
+
+	status, body = http.StatusNotFound, `{"type":"invalid_input","message":"No FIO Requests","fields":[]}`
+	records, hasRecords, err = api.GetObtData("FIO7uRvrLVrZCbCM2DtCgUMospqUMnP3JUC1sKHA8zNoF835kJBvN", 10, 0)
+	if err != nil || hasRecords || records == nil || len(records.Records) != 0 {
+		t.Error("404 should be an empty result", records, err)
+	}
+
+	status, body = http.StatusInternalServerError, `{"code":500,"message":"Internal Service Error","error":{"code":3010000,"name":"chain_type_exception","what":"chain type exception"}}`
+	_, _, err = api.GetObtData("FIO7uRvrLVrZCbCM2DtCgUMospqUMnP3JUC1sKHA8zNoF835kJBvN", 10, 0)
+	if apiErr, ok := err.(eos.APIError); !ok || apiErr.Code != 500 {
+		t.Error("expected an APIError, got", err)
+	}
+
+	status, body = http.StatusBadRequest, `bad request`
+	if _, _, err = api.GetObtData("FIO7uRvrLVrZCbCM2DtCgUMospqUMnP3JUC1sKHA8zNoF835kJBvN", 10, 0); err == nil {
+		t.Error("400 should be an error")
+	}
+}
@@ -0,0 +1,259 @@
+package fio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChainCodes is the list of chain codes from the FIO standard list that are recognized by Resolver when
+// StrictCodes is set. Additional codes can be added before creating a Resolver.
+var ChainCodes = map[string]bool{
+	"ADA": true, "ALGO": true, "ATOM": true, "AVAX": true, "BCH": true, "BNB": true, "BSC": true, "BSV": true,
+	"BTC": true, "DASH": true, "DGB": true, "DOGE": true, "DOT": true, "EOS": true, "ETC": true, "ETH": true,
+	"FIL": true, "FIO": true, "HBAR": true, "ICX": true, "IOTA": true, "KSM": true, "LTC": true, "MATIC": true,
+	"NEAR": true, "NEO": true, "ONE": true, "QTUM": true, "RVN": true, "SOL": true, "TRX": true, "VET": true,
+	"WAVES": true, "XLM": true, "XMR": true, "XRP": true, "XTZ": true, "ZEC": true, "ZIL": true,
+}
+
+// caseInsensitiveChains have public addresses where case is not significant, such as EVM addresses which may or may
+// not carry an EIP-55 checksum. They are lowercased in the reverse index.
+var caseInsensitiveChains = map[string]bool{"AVAX": true, "BSC": true, "ETC": true, "ETH": true, "MATIC": true}
+
+var chainCodeRe = regexp.MustCompile(`^[a-zA-Z0-9]{1,10}$`)
+
+// ValidChainCode checks the chain code format enforced by the fio.address contract, 1 to 10 alphanumeric characters
+func ValidChainCode(code string) bool {
+	return chainCodeRe.MatchString(code)
+}
+
+// ValidTokenCode checks the token code format enforced by the fio.address contract, it has the same format as a
+// chain code, or can be "*" to map all tokens on a chain to the same address.
+func ValidTokenCode(code string) bool {
+	return code == "*" || chainCodeRe.MatchString(code)
+}
+
+// GetPubAddresses gets every public address mapped to a FIO address using a table lookup
+func (api *API) GetPubAddresses(fioAddress Address) ([]TokenPubAddr, error) {
+	if !fioAddress.Valid() {
+		return nil, errors.New("invalid fio address")
+	}
+	hash := AddressHash(strings.ToLower(string(fioAddress)))
+	resp, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:       "fio.address",
+		Scope:      "fio.address",
+		Table:      "fionames",
+		LowerBound: hash,
+		UpperBound: hash,
+		Limit:      1,
+		KeyType:    "i128",
+		Index:      "5",
+		JSON:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]struct {
+		Name      string         `json:"name"`
+		Addresses []TokenPubAddr `json:"addresses"`
+	}, 0)
+	if err = json.Unmarshal(resp.Rows, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s is not registered", fioAddress)
+	}
+	return rows[0].Addresses, nil
+}
+
+// Resolver finds the public addresses mapped to FIO addresses. Results are cached for TTL, and the cache can be kept
+// current by following blocks with WatchBlocks. Every resolved address is added to a local index, allowing reverse
+// lookups from a chain address to FIO addresses.
+type Resolver struct {
+	Api *API
+	TTL time.Duration
+
+	// StrictCodes refuses to resolve chain codes that are not in ChainCodes
+	StrictCodes bool
+
+	mux   sync.RWMutex
+	cache map[Address]*resolved
+	index map[string]map[Address]bool
+	// generations is bumped by Invalidate, so a query that was started before it isn't cached
+	generations map[Address]uint64
+}
+
+type resolved struct {
+	addresses []TokenPubAddr
+	expires   time.Time
+}
+
+// NewResolver returns a Resolver that caches results for ttl
+func NewResolver(api *API, ttl time.Duration) *Resolver {
+	return &Resolver{
+		Api:         api,
+		TTL:         ttl,
+		cache:       make(map[Address]*resolved),
+		index:       make(map[string]map[Address]bool),
+		generations: make(map[Address]uint64),
+	}
+}
+
+func normalizeAddress(fioAddress Address) Address {
+	return Address(strings.ToLower(string(fioAddress)))
+}
+
+func indexKey(chainCode string, publicAddress string) string {
+	chainCode = strings.ToUpper(chainCode)
+	if caseInsensitiveChains[chainCode] {
+		publicAddress = strings.ToLower(publicAddress)
+	}
+	return chainCode + "|" + publicAddress
+}
+
+// Resolve returns all public addresses mapped to a FIO address
+func (r *Resolver) Resolve(fioAddress Address) ([]TokenPubAddr, error) {
+	fioAddress = normalizeAddress(fioAddress)
+	r.mux.RLock()
+	cached := r.cache[fioAddress]
+	generation := r.generations[fioAddress]
+	r.mux.RUnlock()
+	if cached != nil && time.Now().Before(cached.expires) {
+		return cached.addresses, nil
+	}
+
+	addresses, err := r.Api.GetPubAddresses(fioAddress)
+	if err != nil {
+		return nil, err
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	// the address was invalidated during the query, so the result may be older than the change
+	if r.generations[fioAddress] != generation {
+		return addresses, nil
+	}
+	r.unindex(fioAddress)
+	r.cache[fioAddress] = &resolved{addresses: addresses, expires: time.Now().Add(r.TTL)}
+	for _, a := range addresses {
+		key := indexKey(a.ChainCode, a.PublicAddress)
+		if r.index[key] == nil {
+			r.index[key] = make(map[Address]bool)
+		}
+		r.index[key][fioAddress] = true
+	}
+	return addresses, nil
+}
+
+// ResolveChain finds the public address for a chain and token, falling back to a "*" token mapping for the chain.
+// If token is empty the chain code is used.
+func (r *Resolver) ResolveChain(fioAddress Address, chainCode string, tokenCode string) (address TokenPubAddr, found bool, err error) {
+	if tokenCode == "" {
+		tokenCode = chainCode
+	}
+	if !ValidChainCode(chainCode) {
+		return TokenPubAddr{}, false, fmt.Errorf("invalid chain code %q", chainCode)
+	}
+	if !ValidTokenCode(tokenCode) {
+		return TokenPubAddr{}, false, fmt.Errorf("invalid token code %q", tokenCode)
+	}
+	if r.StrictCodes && !ChainCodes[strings.ToUpper(chainCode)] {
+		return TokenPubAddr{}, false, fmt.Errorf("chain code %s is not in the FIO standard list", chainCode)
+	}
+	addresses, err := r.Resolve(fioAddress)
+	if err != nil {
+		return TokenPubAddr{}, false, err
+	}
+	var wildcard *TokenPubAddr
+	for i, a := range addresses {
+		if !strings.EqualFold(a.ChainCode, chainCode) {
+			continue
+		}
+		if strings.EqualFold(a.TokenCode, tokenCode) {
+			return a, true, nil
+		}
+		if a.TokenCode == "*" {
+			wildcard = &addresses[i]
+		}
+	}
+	if wildcard != nil {
+		return *wildcard, true, nil
+	}
+	return TokenPubAddr{}, false, nil
+}
+
+// ReverseLookup returns the FIO addresses that map to a public address on a chain. Only addresses that have been
+// resolved are in the index, use Resolve to add them. Public addresses on EVM chains are matched without regard to
+// case, so a checksummed address matches its lowercase form.
+func (r *Resolver) ReverseLookup(chainCode string, publicAddress string) []Address {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	result := make([]Address, 0)
+	for a := range r.index[indexKey(chainCode, publicAddress)] {
+		result = append(result, a)
+	}
+	return result
+}
+
+// Invalidate removes a FIO address from the cache and the reverse index, the next Resolve will query the chain
+func (r *Resolver) Invalidate(fioAddress Address) {
+	fioAddress = normalizeAddress(fioAddress)
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.unindex(fioAddress)
+	delete(r.cache, fioAddress)
+	r.generations[fioAddress]++
+}
+
+// unindex removes a FIO address from the reverse index, the lock must be held
+func (r *Resolver) unindex(fioAddress Address) {
+	cached := r.cache[fioAddress]
+	if cached == nil {
+		return
+	}
+	for _, a := range cached.addresses {
+		key := indexKey(a.ChainCode, a.PublicAddress)
+		delete(r.index[key], fioAddress)
+		if len(r.index[key]) == 0 {
+			delete(r.index, key)
+		}
+	}
+}
+
+// addressActions are the fio.address actions that change the public addresses for a FIO address, all of them
+// have fio_address as the first field.
+var addressActions = map[eos.ActionName]bool{
+	"addaddress":  true,
+	"remaddress":  true,
+	"remalladdr":  true,
+	"xferaddress": true,
+}
+
+// HandleAction invalidates the cache if the action changes the public addresses of a FIO address, it returns true
+// if an address was invalidated.
+func (r *Resolver) HandleAction(action *eos.Action) bool {
+	if action == nil || action.Account != "fio.address" || !addressActions[action.Name] {
+		return false
+	}
+	fioAddress, err := eos.NewDecoder(action.HexData).ReadString()
+	if err != nil {
+		return false
+	}
+	r.Invalidate(Address(fioAddress))
+	return true
+}
+
+// WatchBlocks follows irreversible blocks, invalidating cached addresses as they are changed, see FollowBlocks.
+func (r *Resolver) WatchBlocks(ctx context.Context, startBlock uint32) error {
+	return r.Api.FollowBlocks(ctx, startBlock, 500*time.Millisecond, func(block *eos.BlockResp) error {
+		for _, action := range BlockActions(block) {
+			r.HandleAction(action)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,163 @@
+package fio
+
+import (
+	"github.com/fioprotocol/fio-go/eos"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResolver(t *testing.T) {
+	var queries int32
+	rows := atomic.Value{}
+	rows.Store(`[{"name":"alice@fio","addresses":[
+		{"token_code":"BTC","chain_code":"BTC","public_address":"bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh"},
+		{"token_code":"*","chain_code":"ETH","public_address":"0x6DB9a4C4eC6d9ee1B39e3D9b5D8B6d3f9fA7B5d8"},
+		{"token_code":"USDT","chain_code":"TRX","public_address":"TQn9Y2khEsLJW1ChVWFMSMeRDow5KcbLSE"}
+	]}]`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chain/get_table_rows" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		atomic.AddInt32(&queries, 1)
+		_, _ = w.Write([]byte(`{"more":false,"rows":` + rows.Load().(string) + `}`))
+	}))
+	defer server.Close()
+	resolver := NewResolver(&API{*eos.New(server.URL)}, time.Hour)
+
+	addrs, err := resolver.Resolve("Alice@FIO")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(addrs) != 3 {
+		t.Error("expected 3 addresses")
+	}
+	usdc, found, err := resolver.ResolveChain("alice@fio", "ETH", "USDC")
+	if err != nil || !found || usdc.PublicAddress != "0x6DB9a4C4eC6d9ee1B39e3D9b5D8B6d3f9fA7B5d8" {
+		t.Error("should have used the wildcard token mapping", err)
+	}
+	if _, found, _ = resolver.ResolveChain("alice@fio", "TRX", "TRX"); found {
+		t.Error("TRX token should not match a USDT mapping")
+	}
+	if atomic.LoadInt32(&queries) != 1 {
+		t.Error("results should be cached, got queries:", queries)
+	}
+
+	// reverse lookup uses the local index
+	if a := resolver.ReverseLookup("btc", "bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh"); len(a) != 1 || a[0] != "alice@fio" {
+		t.Error("reverse lookup failed", a)
+	}
+	if a := resolver.ReverseLookup("ETH", "0x6db9a4c4ec6d9ee1b39e3d9b5d8b6d3f9fa7b5d8"); len(a) != 1 {
+		t.Error("evm addresses should match regardless of case", a)
+	}
+	if a := resolver.ReverseLookup("TRX", "tqn9y2khesljw1chvwfmsmerdow5kcblse"); len(a) != 0 {
+		t.Error("base58 addresses are case sensitive", a)
+	}
+
+	// an addaddress action invalidates the cache, and the index is updated on the next lookup
+	action := packedAddAddress(t, "alice@fio")
+	if !resolver.HandleAction(action) {
+		t.Error("addaddress should invalidate the cache")
+	}
+	rows.Store(`[{"name":"alice@fio","addresses":[{"token_code":"LTC","chain_code":"LTC","public_address":"ltc1qexample"}]}]`)
+	if _, err = resolver.Resolve("alice@fio"); err != nil {
+		t.Error(err)
+	}
+	if atomic.LoadInt32(&queries) != 2 {
+		t.Error("should have queried after invalidation")
+	}
+	if a := resolver.ReverseLookup("BTC", "bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh"); len(a) != 0 {
+		t.Error("removed mapping should be removed from the index")
+	}
+	if a := resolver.ReverseLookup("LTC", "ltc1qexample"); len(a) != 1 {
+		t.Error("new mapping should be indexed")
+	}
+
+	if _, _, err = resolver.ResolveChain("alice@fio", "not-valid!", ""); err == nil {
+		t.Error("should reject an invalid chain code")
+	}
+	resolver.StrictCodes = true
+	if _, _, err = resolver.ResolveChain("alice@fio", "NOTACHAIN", ""); err == nil {
+		t.Error("should reject an unknown chain code when strict")
+	}
+	if _, _, err = resolver.ResolveChain("alice@fio", "LTC", "*"); err != nil {
+		t.Error(err)
+	}
+
+	rows.Store(`[]`)
+	resolver.Invalidate("alice@fio")
+	if _, err = resolver.Resolve("alice@fio"); err == nil {
+		t.Error("should return an error for an unregistered address")
+	}
+}
+
+func TestResolver_InvalidateDuringResolve(t *testing.T) {
+	var queries int32
+	started, release := make(chan bool), make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first query is held until the address has been invalidated
+		if atomic.AddInt32(&queries, 1) == 1 {
+			started <- true
+			<-release
+		}
+		_, _ = w.Write([]byte(`{"more":false,"rows":[{"name":"alice@fio","addresses":[{"token_code":"BTC","chain_code":"BTC","public_address":"bc1qold"}]}]}`))
+	}))
+	defer server.Close()
+	resolver := NewResolver(&API{*eos.New(server.URL)}, time.Hour)
+
+	done := make(chan error)
+	go func() {
+		_, err := resolver.Resolve("alice@fio")
+		done <- err
+	}()
+	<-started
+	resolver.Invalidate("alice@fio")
+	close(release)
+	if err := <-done; err != nil {
+		t.Error(err)
+		return
+	}
+	if a := resolver.ReverseLookup("BTC", "bc1qold"); len(a) != 0 {
+		t.Error("a result from before the invalidation should not be indexed", a)
+	}
+	if _, err := resolver.Resolve("alice@fio"); err != nil {
+		t.Error(err)
+	}
+	if atomic.LoadInt32(&queries) != 2 {
+		t.Error("a result from before the invalidation should not be cached, got queries:", queries)
+	}
+}
+
+// packedAddAddress builds an addaddress action with HexData set, as it would be read from a block
+func packedAddAddress(t *testing.T, address Address) *eos.Action {
+	action, ok := NewAddAddress("aloha", address, "BTC", "BTC", "bc1qnew")
+	if !ok {
+		t.Fatal("could not build addaddress")
+	}
+	data, err := eos.MarshalBinary(action.ActionData.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	action.ActionData.HexData = data
+	return action.ToEos()
+}
+
+func TestValidCodes(t *testing.T) {
+	for _, good := range []string{"BTC", "eth", "MATIC", "A", "ABCDEFGHIJ"} {
+		if !ValidChainCode(good) {
+			t.Error("should be valid:", good)
+		}
+	}
+	for _, bad := range []string{"", "ABCDEFGHIJK", "B-TC", "*", "BTC "} {
+		if ValidChainCode(bad) {
+			t.Error("should be invalid:", bad)
+		}
+	}
+	if !ValidTokenCode("*") || ValidTokenCode("**") {
+		t.Error("only a single * is a valid token code")
+	}
+}
@@ -0,0 +1,447 @@
+package fio
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// PayScheduleInterval is how often fio.treasury rebuilds the producer pay schedule during a bpclaim
+	PayScheduleInterval = 86401 * time.Second
+	// TpidClaimInterval is the minimum time between tpidclaim calls
+	TpidClaimInterval = 60 * time.Second
+)
+
+// Status of a RewardClaim
+const (
+	ClaimPaid    = "paid"
+	ClaimSkipped = "skipped"
+	ClaimFailed  = "failed"
+)
+
+// TreasuryState is the clockstate table in fio.treasury, times are unix seconds
+type TreasuryState struct {
+	LastTpidPayout      uint64 `json:"lasttpidpayout"`
+	PayschedTimer       uint64 `json:"payschedtimer"`
+	RewardsPaid         uint64 `json:"rewardspaid"`
+	ReserveTokensMinted uint64 `json:"reservetokensminted"`
+}
+
+// VoteShare is a row in the fio.treasury voteshares table, the current pay schedule. A producer can only claim if it
+// has a row, or the schedule is due to be rebuilt.
+type VoteShare struct {
+	Owner      eos.AccountName `json:"owner"`
+	AbPayShare uint64          `json:"abpayshare"`
+	SbPayShare uint64          `json:"sbpayshare"`
+}
+
+// GetTreasuryState reads the fio.treasury clockstate table
+func (api *API) GetTreasuryState() (*TreasuryState, error) {
+	gtr, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:  "fio.treasury",
+		Scope: "fio.treasury",
+		Table: "clockstate",
+		Limit: 1,
+		JSON:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	state := make([]*TreasuryState, 0)
+	err = json.Unmarshal(gtr.Rows, &state)
+	if err != nil {
+		return nil, err
+	}
+	if len(state) == 0 {
+		return nil, errors.New("clockstate table is empty")
+	}
+	return state[0], nil
+}
+
+// GetVoteShare returns a producer's row in the voteshares table, or nil if it has nothing payable in the current
+// schedule
+func (api *API) GetVoteShare(producer eos.AccountName) (*VoteShare, error) {
+	gtr, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:       "fio.treasury",
+		Scope:      "fio.treasury",
+		Table:      "voteshares",
+		LowerBound: string(producer),
+		UpperBound: string(producer),
+		Limit:      1,
+		KeyType:    "name",
+		Index:      "2",
+		JSON:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	shares := make([]*VoteShare, 0)
+	err = json.Unmarshal(gtr.Rows, &shares)
+	if err != nil {
+		return nil, err
+	}
+	if len(shares) == 0 || shares[0].Owner != producer {
+		return nil, nil
+	}
+	return shares[0], nil
+}
+
+// RewardPayout is a token transfer found in the traces of a claim. For a trnsfiopubky From is the actor, and
+// PayeePublicKey is set instead of To.
+type RewardPayout struct {
+	From           eos.AccountName `json:"from"`
+	To             eos.AccountName `json:"to,omitempty"`
+	PayeePublicKey string          `json:"payee_public_key,omitempty"`
+	Amount         uint64          `json:"amount"`
+	Memo           string          `json:"memo,omitempty"`
+}
+
+// RewardClaim is an entry in the RewardsLedger
+type RewardClaim struct {
+	Time    time.Time       `json:"time"`
+	Action  string          `json:"action"`
+	Actor   eos.AccountName `json:"actor"`
+	Status  string          `json:"status"`
+	Reason  string          `json:"reason,omitempty"`
+	TxId    string          `json:"tx_id,omitempty"`
+	Block   uint32          `json:"block,omitempty"`
+	Payouts []RewardPayout  `json:"payouts"`
+}
+
+// Received is the total paid to an account by the claim
+func (rc RewardClaim) Received(account eos.AccountName) uint64 {
+	var total uint64
+	for _, p := range rc.Payouts {
+		if p.To == account {
+			total += p.Amount
+		}
+	}
+	return total
+}
+
+type rewardTrace struct {
+	Receipt struct {
+		Receiver       eos.AccountName `json:"receiver"`
+		GlobalSequence eos.Uint64      `json:"global_sequence"`
+	} `json:"receipt"`
+	Act struct {
+		Account eos.AccountName `json:"account"`
+		Name    eos.ActionName  `json:"name"`
+		Data    json.RawMessage `json:"data"`
+	} `json:"act"`
+	InlineTraces []*rewardTrace `json:"inline_traces"`
+}
+
+// ParseRewardPayouts finds the fio.token transfer and trnsfiopubky actions in a push_transaction response. Traces
+// may be nested (nodeos 1.8) or flattened (nodeos 2.0), notifications and duplicates are ignored.
+func ParseRewardPayouts(pushResponse json.RawMessage) ([]RewardPayout, error) {
+	resp := struct {
+		Processed struct {
+			ActionTraces []*rewardTrace `json:"action_traces"`
+		} `json:"processed"`
+	}{}
+	if err := json.Unmarshal(pushResponse, &resp); err != nil {
+		return nil, err
+	}
+	payouts := make([]RewardPayout, 0)
+	seen := make(map[eos.Uint64]bool)
+	var walk func(traces []*rewardTrace) error
+	walk = func(traces []*rewardTrace) error {
+		for _, t := range traces {
+			if t == nil {
+				continue
+			}
+			if t.Receipt.Receiver == t.Act.Account && t.Act.Account == "fio.token" && !seen[t.Receipt.GlobalSequence] {
+				seen[t.Receipt.GlobalSequence] = true
+				switch t.Act.Name {
+				case "transfer":
+					xfer := Transfer{}
+					if err := json.Unmarshal(t.Act.Data, &xfer); err != nil {
+						return fmt.Errorf("could not parse transfer: %s", err)
+					}
+					if xfer.Quantity.Amount < 0 {
+						return errors.New("transfer has a negative quantity")
+					}
+					payouts = append(payouts, RewardPayout{From: xfer.From, To: xfer.To, Amount: uint64(xfer.Quantity.Amount), Memo: xfer.Memo})
+				case "trnsfiopubky":
+					xfer := TransferTokensPubKey{}
+					if err := json.Unmarshal(t.Act.Data, &xfer); err != nil {
+						return fmt.Errorf("could not parse trnsfiopubky: %s", err)
+					}
+					payouts = append(payouts, RewardPayout{From: xfer.Actor, PayeePublicKey: xfer.PayeePublicKey, Amount: xfer.Amount})
+				}
+			}
+			if err := walk(t.InlineTraces); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return payouts, walk(resp.Processed.ActionTraces)
+}
+
+// RewardsLedger is an append only record of claims, it is safe for concurrent use
+type RewardsLedger struct {
+	mux    sync.Mutex
+	Claims []*RewardClaim `json:"claims"`
+}
+
+// LoadRewardsLedger reads a ledger saved with RewardsLedger.WriteJSON
+func LoadRewardsLedger(r io.Reader) (*RewardsLedger, error) {
+	ledger := &RewardsLedger{}
+	if err := json.NewDecoder(r).Decode(ledger); err != nil {
+		return nil, err
+	}
+	return ledger, nil
+}
+
+// Add appends a claim
+func (rl *RewardsLedger) Add(claim *RewardClaim) {
+	rl.mux.Lock()
+	rl.Claims = append(rl.Claims, claim)
+	rl.mux.Unlock()
+}
+
+// Last returns the most recent claim with a status for an action, or nil
+func (rl *RewardsLedger) Last(action string, status string) *RewardClaim {
+	rl.mux.Lock()
+	defer rl.mux.Unlock()
+	for i := len(rl.Claims) - 1; i >= 0; i-- {
+		if rl.Claims[i].Action == action && rl.Claims[i].Status == status {
+			return rl.Claims[i]
+		}
+	}
+	return nil
+}
+
+// Received totals the payouts to an account from paid claims
+func (rl *RewardsLedger) Received(account eos.AccountName) uint64 {
+	rl.mux.Lock()
+	defer rl.mux.Unlock()
+	var total uint64
+	for _, c := range rl.Claims {
+		if c.Status == ClaimPaid {
+			total += c.Received(account)
+		}
+	}
+	return total
+}
+
+// WriteJSON writes the ledger as JSON
+func (rl *RewardsLedger) WriteJSON(w io.Writer) error {
+	rl.mux.Lock()
+	defer rl.mux.Unlock()
+	j, err := json.MarshalIndent(rl, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(j)
+	return err
+}
+
+// WriteCSV writes one row per payout, claims without a payout (skipped, failed, or nothing paid) have a single row
+// with empty payout columns. Amounts are in SUF.
+func (rl *RewardsLedger) WriteCSV(w io.Writer) error {
+	rl.mux.Lock()
+	defer rl.mux.Unlock()
+	c := csv.NewWriter(w)
+	err := c.Write([]string{"time", "action", "actor", "status", "reason", "tx_id", "block", "from", "to", "payee_public_key", "amount", "memo"})
+	if err != nil {
+		return err
+	}
+	for _, claim := range rl.Claims {
+		row := []string{
+			claim.Time.UTC().Format(time.RFC3339),
+			claim.Action,
+			string(claim.Actor),
+			claim.Status,
+			claim.Reason,
+			claim.TxId,
+			strconv.FormatUint(uint64(claim.Block), 10),
+		}
+		if len(claim.Payouts) == 0 {
+			if err = c.Write(append(row, "", "", "", "", "")); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, p := range claim.Payouts {
+			err = c.Write(append(row, string(p.From), string(p.To), p.PayeePublicKey, strconv.FormatUint(p.Amount, 10), p.Memo))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// RewardsAgent claims producer and TPID rewards when the fio.treasury contract will accept the claim, and records
+// every attempt in a RewardsLedger.
+type RewardsAgent struct {
+	Api   *API
+	Actor eos.AccountName
+	// FioAddress is the producer's address for bpclaim, if empty bpclaim is not called
+	FioAddress string
+	// TpidEnabled enables tpidclaim, which pays every TPID that is owed rewards
+	TpidEnabled bool
+	// RecordSkipped adds skipped claims to the ledger
+	RecordSkipped bool
+	Ledger        *RewardsLedger
+
+	// Push signs and sends a transaction, returning the raw response so the traces can be parsed
+	Push func(actions ...*Action) (json.RawMessage, error)
+	Now  func() time.Time
+}
+
+// NewRewardsAgent creates an agent with an empty ledger, fioAddress may be empty for a TPID-only agent
+func NewRewardsAgent(api *API, actor eos.AccountName, fioAddress string) *RewardsAgent {
+	ra := &RewardsAgent{
+		Api:        api,
+		Actor:      actor,
+		FioAddress: fioAddress,
+		Ledger:     &RewardsLedger{Claims: make([]*RewardClaim, 0)},
+		Now:        time.Now,
+	}
+	ra.Push = ra.push
+	return ra
+}
+
+func (ra *RewardsAgent) push(actions ...*Action) (json.RawMessage, error) {
+	opts := &TxOptions{}
+	if err := opts.FillFromChain(&ra.Api.API); err != nil {
+		return nil, err
+	}
+	_, packed, err := ra.Api.SignTransaction(NewTransaction(actions, opts), opts.ChainID, CompressionNone)
+	if err != nil {
+		return nil, err
+	}
+	return ra.Api.PushTransactionRaw(packed)
+}
+
+// CanClaimBp checks if a bpclaim would be accepted: the producer must have a row in voteshares, or the pay schedule
+// must be due to be rebuilt. If not, the reason is returned.
+func (ra *RewardsAgent) CanClaimBp() (ok bool, reason string, err error) {
+	state, err := ra.Api.GetTreasuryState()
+	if err != nil {
+		return false, "", err
+	}
+	rebuild := time.Unix(int64(state.PayschedTimer), 0).Add(PayScheduleInterval)
+	if !ra.Now().Before(rebuild) {
+		return true, "", nil
+	}
+	share, err := ra.Api.GetVoteShare(ra.Actor)
+	if err != nil {
+		return false, "", err
+	}
+	if share == nil {
+		return false, "already claimed, pay schedule is rebuilt at " + rebuild.UTC().Format(time.RFC3339), nil
+	}
+	return true, "", nil
+}
+
+// CanClaimTpid checks that tpidclaim was not called too recently
+func (ra *RewardsAgent) CanClaimTpid() (ok bool, reason string, err error) {
+	state, err := ra.Api.GetTreasuryState()
+	if err != nil {
+		return false, "", err
+	}
+	next := time.Unix(int64(state.LastTpidPayout), 0).Add(TpidClaimInterval)
+	if ra.Now().Before(next) {
+		return false, "claimed too recently, next claim at " + next.UTC().Format(time.RFC3339), nil
+	}
+	return true, "", nil
+}
+
+// claim pushes an action and records the result, err is only returned if the check could not be made
+func (ra *RewardsAgent) claim(act *Action, check func() (bool, string, error)) (*RewardClaim, error) {
+	claim := &RewardClaim{Time: ra.Now(), Action: string(act.Name), Actor: ra.Actor, Payouts: make([]RewardPayout, 0)}
+	ok, reason, err := check()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		claim.Status, claim.Reason = ClaimSkipped, reason
+		if ra.RecordSkipped {
+			ra.Ledger.Add(claim)
+		}
+		return claim, nil
+	}
+
+	defer ra.Ledger.Add(claim)
+	raw, err := ra.Push(act)
+	if err != nil {
+		claim.Status, claim.Reason = ClaimFailed, err.Error()
+		return claim, nil
+	}
+	resp := eos.PushTransactionFullResp{}
+	if err = json.Unmarshal(raw, &resp); err != nil {
+		claim.Status, claim.Reason = ClaimFailed, err.Error()
+		return claim, nil
+	}
+	claim.TxId, claim.Block = resp.TransactionID, resp.BlockNum
+	claim.Status = ClaimPaid
+	if claim.Payouts, err = ParseRewardPayouts(raw); err != nil {
+		// the claim succeeded, but what was paid is unknown
+		claim.Reason = err.Error()
+	}
+	return claim, nil
+}
+
+// ClaimBp calls bpclaim if it would be accepted
+func (ra *RewardsAgent) ClaimBp() (*RewardClaim, error) {
+	return ra.claim(NewBpClaim(ra.FioAddress, ra.Actor), ra.CanClaimBp)
+}
+
+// ClaimTpid calls tpidclaim if it would be accepted
+func (ra *RewardsAgent) ClaimTpid() (*RewardClaim, error) {
+	return ra.claim(NewPayTpidRewards(ra.Actor), ra.CanClaimTpid)
+}
+
+// Tick runs each enabled claim once
+func (ra *RewardsAgent) Tick() ([]*RewardClaim, error) {
+	claims := make([]*RewardClaim, 0)
+	if ra.FioAddress != "" {
+		c, err := ra.ClaimBp()
+		if err != nil {
+			return claims, err
+		}
+		claims = append(claims, c)
+	}
+	if ra.TpidEnabled {
+		c, err := ra.ClaimTpid()
+		if err != nil {
+			return claims, err
+		}
+		claims = append(claims, c)
+	}
+	return claims, nil
+}
+
+// Run calls Tick every interval until the context is cancelled. Errors checking the treasury state are passed to
+// onError, if it is not nil, and do not stop the agent.
+func (ra *RewardsAgent) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	if ra.FioAddress == "" && !ra.TpidEnabled {
+		return errors.New("nothing to claim, set FioAddress or TpidEnabled")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := ra.Tick(); err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
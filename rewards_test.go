@@ -0,0 +1,129 @@
+package fio
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"strings"
+	"testing"
+	"time"
+)
+
+// nested traces, with a notification to the recipient that should not be counted twice
+const testBpClaimResponse = `{"transaction_id":"abc123","block_num":100,"processed":{"action_traces":[
+{"receipt":{"receiver":"fio.treasury","global_sequence":10},"act":{"account":"fio.treasury","name":"bpclaim","data":{}},
+"inline_traces":[
+  {"receipt":{"receiver":"fio.token","global_sequence":11},"act":{"account":"fio.token","name":"transfer","data":{"from":"fio.treasury","to":"bp1","quantity":"12.500000000 FIO","memo":"Paying producer from treasury."}},
+  "inline_traces":[{"receipt":{"receiver":"bp1","global_sequence":12},"act":{"account":"fio.token","name":"transfer","data":{"from":"fio.treasury","to":"bp1","quantity":"12.500000000 FIO","memo":"Paying producer from treasury."}}}]},
+  {"receipt":{"receiver":"fio.token","global_sequence":13},"act":{"account":"fio.token","name":"transfer","data":{"from":"fio.treasury","to":"fio.foundatn","quantity":"1.000000000 FIO","memo":"Paying foundation from treasury."}}}
+]}]}}`
+
+func TestParseRewardPayouts(t *testing.T) {
+	payouts, err := ParseRewardPayouts(json.RawMessage(testBpClaimResponse))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(payouts) != 2 || payouts[0].To != "bp1" || payouts[0].Amount != 12500000000 || payouts[1].To != "fio.foundatn" {
+		t.Errorf("wrong payouts: %+v", payouts)
+	}
+
+	// nodeos 2.0 flattens the traces
+	flat := `{"processed":{"action_traces":[
+{"receipt":{"receiver":"fio.token","global_sequence":1},"act":{"account":"fio.token","name":"trnsfiopubky","data":{"payee_public_key":"FIO7uRvrLVrZCbCM2DtCgUMospqUMnP3JUC1sKHA8zNoF835kJBvN","amount":5,"max_fee":0,"actor":"fio.treasury","tpid":""}}},
+{"receipt":{"receiver":"fio.token","global_sequence":1},"act":{"account":"fio.token","name":"trnsfiopubky","data":{"payee_public_key":"FIO7uRvrLVrZCbCM2DtCgUMospqUMnP3JUC1sKHA8zNoF835kJBvN","amount":5,"max_fee":0,"actor":"fio.treasury","tpid":""}}}]}}`
+	payouts, err = ParseRewardPayouts(json.RawMessage(flat))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(payouts) != 1 || payouts[0].Amount != 5 || payouts[0].PayeePublicKey == "" {
+		t.Errorf("wrong payouts: %+v", payouts)
+	}
+}
+
+func TestRewardsAgent(t *testing.T) {
+	start := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+	now := start
+	var hasShare bool
+	node, api := newFakeNode(t)
+	// schedule built an hour ago, tpids paid 30 seconds ago
+	node.rows("clockstate", fmt.Sprintf(`[{"lasttpidpayout":%d,"payschedtimer":%d,"rewardspaid":0}]`,
+		start.Add(-30*time.Second).Unix(), start.Add(-time.Hour).Unix()))
+	node.table("voteshares", func(eos.GetTableRowsRequest) (interface{}, bool) {
+		if hasShare {
+			return json.RawMessage(`[{"owner":"bp1","abpayshare":1,"sbpayshare":2}]`), false
+		}
+		return json.RawMessage(`[]`), false
+	})
+
+	ra := NewRewardsAgent(api, "bp1", "bp1@fio")
+	ra.TpidEnabled, ra.RecordSkipped = true, true
+	ra.Now = func() time.Time { return now }
+	var pushed int
+	ra.Push = func(actions ...*Action) (json.RawMessage, error) {
+		pushed++
+		if actions[0].Name == "tpidclaim" {
+			return nil, errors.New("should not be called")
+		}
+		return json.RawMessage(testBpClaimResponse), nil
+	}
+
+	claims, err := ra.Tick()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if pushed != 0 || len(claims) != 2 || claims[0].Status != ClaimSkipped || claims[1].Status != ClaimSkipped {
+		t.Errorf("both claims should be skipped: %+v %+v", claims[0], claims[1])
+	}
+
+	hasShare = true
+	now = now.Add(time.Minute)
+	claims, err = ra.Tick()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if claims[0].Status != ClaimPaid || claims[0].TxId != "abc123" || claims[0].Received("bp1") != 12500000000 {
+		t.Errorf("bpclaim should be paid: %+v", claims[0])
+	}
+	if claims[1].Status != ClaimFailed || !strings.Contains(claims[1].Reason, "should not be called") {
+		t.Errorf("tpidclaim should have failed: %+v", claims[1])
+	}
+	if ra.Ledger.Received("bp1") != 12500000000 || ra.Ledger.Last("bpclaim", ClaimPaid) == nil {
+		t.Error("ledger did not record the payout")
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err = ra.Ledger.WriteCSV(buf); err != nil {
+		t.Error(err)
+		return
+	}
+	rows, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// header, two skips, two payouts, one failure
+	if len(rows) != 6 || rows[3][8] != "bp1" || rows[3][10] != "12500000000" {
+		t.Errorf("wrong csv: %v", rows)
+	}
+
+	buf.Reset()
+	if err = ra.Ledger.WriteJSON(buf); err != nil {
+		t.Error(err)
+		return
+	}
+	loaded, err := LoadRewardsLedger(buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(loaded.Claims) != 4 || loaded.Received("bp1") != 12500000000 {
+		t.Error("ledger did not load")
+	}
+}
@@ -0,0 +1,396 @@
+package fio
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"io/ioutil"
+	"math"
+	"sort"
+	"time"
+)
+
+// StakeFio stakes tokens in the fio.staking contract, FioAddress is optional and allows the fee to be paid with a
+// bundled transaction.
+type StakeFio struct {
+	FioAddress string          `json:"fio_address"`
+	Amount     uint64          `json:"amount"`
+	MaxFee     uint64          `json:"max_fee"`
+	Actor      eos.AccountName `json:"actor"`
+	Tpid       string          `json:"tpid"`
+}
+
+// NewStakeFio builds a stakefio action, amount is in SUF
+func NewStakeFio(actor eos.AccountName, fioAddress string, amount uint64) *Action {
+	return NewAction(
+		"fio.staking", "stakefio", actor,
+		StakeFio{
+			FioAddress: fioAddress,
+			Amount:     amount,
+			MaxFee:     Tokens(GetMaxFee(FeeStakeFioTokens)),
+			Actor:      actor,
+			Tpid:       CurrentTpid(),
+		},
+	)
+}
+
+// NewStakeFioAmount is NewStakeFio using an exact Amount
+func NewStakeFioAmount(actor eos.AccountName, fioAddress string, amount Amount) *Action {
+	return NewAction(
+		"fio.staking", "stakefio", actor,
+		StakeFio{
+			FioAddress: fioAddress,
+			Amount:     amount.Suf(),
+			MaxFee:     GetMaxFeeAmount(FeeStakeFioTokens).Suf(),
+			Actor:      actor,
+			Tpid:       CurrentTpid(),
+		},
+	)
+}
+
+// UnStakeFio has the same fields as StakeFio, unstaked tokens are locked for UnstakeLockDuration
+type UnStakeFio StakeFio
+
+// UnstakeLockDuration is how long unstaked tokens remain locked
+const UnstakeLockDuration = 7 * 24 * time.Hour
+
+// NewUnStakeFio builds an unstakefio action, amount is in SUF
+func NewUnStakeFio(actor eos.AccountName, fioAddress string, amount uint64) *Action {
+	return NewAction(
+		"fio.staking", "unstakefio", actor,
+		UnStakeFio{
+			FioAddress: fioAddress,
+			Amount:     amount,
+			MaxFee:     Tokens(GetMaxFee(FeeUnStakeFioTokens)),
+			Actor:      actor,
+			Tpid:       CurrentTpid(),
+		},
+	)
+}
+
+// NewUnStakeFioAmount is NewUnStakeFio using an exact Amount
+func NewUnStakeFioAmount(actor eos.AccountName, fioAddress string, amount Amount) *Action {
+	return NewAction(
+		"fio.staking", "unstakefio", actor,
+		UnStakeFio{
+			FioAddress: fioAddress,
+			Amount:     amount.Suf(),
+			MaxFee:     GetMaxFeeAmount(FeeUnStakeFioTokens).Suf(),
+			Actor:      actor,
+			Tpid:       CurrentTpid(),
+		},
+	)
+}
+
+// LockPeriod is a single unlock in a general lock, Duration is seconds after the lock was created
+type LockPeriod struct {
+	Duration int64 `json:"duration"`
+	Amount   int64 `json:"amount"`
+}
+
+// TransferLockedTokens sends tokens to a new account, with a lock that releases the amount in each period
+type TransferLockedTokens struct {
+	PayeePublicKey string          `json:"payee_public_key"`
+	CanVote        int32           `json:"can_vote"`
+	Periods        []LockPeriod    `json:"periods"`
+	Amount         int64           `json:"amount"`
+	MaxFee         uint64          `json:"max_fee"`
+	Actor          eos.AccountName `json:"actor"`
+	Tpid           string          `json:"tpid"`
+}
+
+// NewTransferLockedTokens builds a trnsloctoks action. The period amounts must add up to amount, and durations
+// must be increasing. The payee must not already exist.
+func NewTransferLockedTokens(actor eos.AccountName, recipientPubKey string, canVote bool, periods []LockPeriod, amount uint64) (*Action, error) {
+	return newTransferLockedTokens(actor, recipientPubKey, canVote, periods, amount, Tokens(GetMaxFee(FeeTransferLockedTokens)))
+}
+
+// NewTransferLockedTokensAmount is NewTransferLockedTokens using an exact Amount
+func NewTransferLockedTokensAmount(actor eos.AccountName, recipientPubKey string, canVote bool, periods []LockPeriod, amount Amount) (*Action, error) {
+	if amount > math.MaxInt64 {
+		return nil, ErrAmountOverflow
+	}
+	return newTransferLockedTokens(actor, recipientPubKey, canVote, periods, amount.Suf(), GetMaxFeeAmount(FeeTransferLockedTokens).Suf())
+}
+
+func newTransferLockedTokens(actor eos.AccountName, recipientPubKey string, canVote bool, periods []LockPeriod, amount uint64, maxFee uint64) (*Action, error) {
+	if len(periods) == 0 {
+		return nil, errors.New("at least one lock period is required")
+	}
+	var total int64
+	var last int64
+	for i, p := range periods {
+		if p.Duration <= last {
+			return nil, fmt.Errorf("period %d: duration must be greater than the previous period", i)
+		}
+		if p.Amount <= 0 {
+			return nil, fmt.Errorf("period %d: amount must be positive", i)
+		}
+		last = p.Duration
+		total += p.Amount
+	}
+	if total != int64(amount) {
+		return nil, fmt.Errorf("period amounts total %d, expected %d", total, amount)
+	}
+	var vote int32
+	if canVote {
+		vote = 1
+	}
+	return NewAction(
+		"fio.token", "trnsloctoks", actor,
+		TransferLockedTokens{
+			PayeePublicKey: recipientPubKey,
+			CanVote:        vote,
+			Periods:        periods,
+			Amount:         int64(amount),
+			MaxFee:         maxFee,
+			Actor:          actor,
+			Tpid:           CurrentTpid(),
+		},
+	), nil
+}
+
+// StakingState is the global fio.staking staking table, amounts are in SUF
+type StakingState struct {
+	StakedTokenPool              uint64 `json:"staked_token_pool"`
+	CombinedTokenPool            uint64 `json:"combined_token_pool"`
+	RewardsTokenPool             uint64 `json:"rewards_token_pool"`
+	GlobalSrpCount               uint64 `json:"global_srp_count"`
+	DailyStakingRewards          uint64 `json:"daily_staking_rewards"`
+	StakingRewardsReservesMinted uint64 `json:"staking_rewards_reserves_minted"`
+}
+
+// GetStakingState reads the fio.staking staking table
+func (api *API) GetStakingState() (*StakingState, error) {
+	gtr, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:  "fio.staking",
+		Scope: "fio.staking",
+		Table: "staking",
+		Limit: 1,
+		JSON:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	state := make([]*StakingState, 0)
+	err = json.Unmarshal(gtr.Rows, &state)
+	if err != nil {
+		return nil, err
+	}
+	if len(state) == 0 {
+		return nil, errors.New("staking table is empty")
+	}
+	return state[0], nil
+}
+
+// AccountStake is a row in the fio.staking accountstake table
+type AccountStake struct {
+	Id             uint64          `json:"id"`
+	Account        eos.AccountName `json:"account"`
+	TotalStakedFio uint64          `json:"total_staked_fio"`
+	TotalSrp       uint64          `json:"total_srp"`
+}
+
+// GetAccountStake gets the staked tokens for an account, it returns nil if the account has never staked
+func (api *API) GetAccountStake(account eos.AccountName) (*AccountStake, error) {
+	gtr, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:       "fio.staking",
+		Scope:      "fio.staking",
+		Table:      "accountstake",
+		LowerBound: string(account),
+		UpperBound: string(account),
+		Limit:      1,
+		KeyType:    "name",
+		Index:      "2",
+		JSON:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	stakes := make([]*AccountStake, 0)
+	err = json.Unmarshal(gtr.Rows, &stakes)
+	if err != nil {
+		return nil, err
+	}
+	if len(stakes) == 0 {
+		return nil, nil
+	}
+	return stakes[0], nil
+}
+
+// GeneralLock is a row in the eosio locktokensv2 table, used by trnsloctoks and unstakefio. Timestamp is when the
+// lock was created, in unix seconds.
+type GeneralLock struct {
+	Id                  uint64          `json:"id"`
+	OwnerAccount        eos.AccountName `json:"owner_account"`
+	LockAmount          int64           `json:"lock_amount"`
+	PayoutsPerformed    int32           `json:"payouts_performed"`
+	CanVote             int32           `json:"can_vote"`
+	Periods             []LockPeriod    `json:"periods"`
+	RemainingLockAmount int64           `json:"remaining_lock_amount"`
+	Timestamp           uint32          `json:"timestamp"`
+}
+
+// GetGeneralLock gets an account's general lock, it returns nil if the account does not have one
+func (api *API) GetGeneralLock(account eos.AccountName) (*GeneralLock, error) {
+	gtr, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:       "eosio",
+		Scope:      "eosio",
+		Table:      "locktokensv2",
+		LowerBound: string(account),
+		UpperBound: string(account),
+		Limit:      1,
+		KeyType:    "name",
+		Index:      "2",
+		JSON:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	locks := make([]*GeneralLock, 0)
+	err = json.Unmarshal(gtr.Rows, &locks)
+	if err != nil {
+		return nil, err
+	}
+	if len(locks) == 0 {
+		return nil, nil
+	}
+	return locks[0], nil
+}
+
+// GenesisLock is a row in the eosio lockedtokens table, for tokens locked at the network launch
+type GenesisLock struct {
+	Owner                 eos.AccountName `json:"owner"`
+	TotalGrantAmount      uint64          `json:"total_grant_amount"`
+	UnlockedPeriodCount   uint32          `json:"unlocked_period_count"`
+	GrantType             uint32          `json:"grant_type"`
+	InhibitUnlocking      uint32          `json:"inhibit_unlocking"`
+	RemainingLockedAmount uint64          `json:"remaining_locked_amount"`
+	Timestamp             uint32          `json:"timestamp"`
+}
+
+// GetGenesisLock gets an account's genesis lock, it returns nil if the account does not have one
+func (api *API) GetGenesisLock(account eos.AccountName) (*GenesisLock, error) {
+	gtr, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:       "eosio",
+		Scope:      "eosio",
+		Table:      "lockedtokens",
+		LowerBound: string(account),
+		UpperBound: string(account),
+		Limit:      1,
+		KeyType:    "name",
+		JSON:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	locks := make([]*GenesisLock, 0)
+	err = json.Unmarshal(gtr.Rows, &locks)
+	if err != nil {
+		return nil, err
+	}
+	if len(locks) == 0 || locks[0].Owner != account {
+		return nil, nil
+	}
+	return locks[0], nil
+}
+
+// FioBalance is the response from /v1/chain/get_fio_balance, amounts are in SUF. Roe is the rate of exchange for
+// staking reward points (SRPs) as a decimal string.
+type FioBalance struct {
+	Balance   uint64 `json:"balance"`
+	Available uint64 `json:"available"`
+	Staked    uint64 `json:"staked"`
+	Srps      uint64 `json:"srps"`
+	Roe       string `json:"roe"`
+}
+
+// GetFioBalance calls /v1/chain/get_fio_balance for a public key
+func (api *API) GetFioBalance(pubKey string) (*FioBalance, error) {
+	j, err := json.Marshal(map[string]string{"fio_public_key": pubKey})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := api.HttpClient.Post(api.BaseURL+"/v1/chain/get_fio_balance", "application/json", bytes.NewReader(j))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("get_fio_balance: %s", string(body))
+	}
+	balance := &FioBalance{}
+	err = json.Unmarshal(body, balance)
+	if err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
+// Unlock is a future release of locked tokens
+type Unlock struct {
+	Time   time.Time `json:"time"`
+	Amount uint64    `json:"amount"`
+}
+
+// DetailedBalance adds locked tokens to FioBalance. Locked is the total still locked in a general lock, which
+// includes unstaked tokens, and a genesis lock. Unlocks only lists the general lock schedule, the genesis schedule
+// depends on the grant type and is not calculated.
+type DetailedBalance struct {
+	FioBalance
+	Locked      uint64       `json:"locked"`
+	GeneralLock *GeneralLock `json:"general_lock,omitempty"`
+	GenesisLock *GenesisLock `json:"genesis_lock,omitempty"`
+	Unlocks     []Unlock     `json:"unlocks"`
+}
+
+// UnlockSchedule returns the periods in a general lock that have not been paid out yet
+func (gl GeneralLock) UnlockSchedule() []Unlock {
+	unlocks := make([]Unlock, 0)
+	for i, p := range gl.Periods {
+		if i < int(gl.PayoutsPerformed) || p.Amount <= 0 {
+			continue
+		}
+		unlocks = append(unlocks, Unlock{
+			Time:   time.Unix(int64(gl.Timestamp)+p.Duration, 0).UTC(),
+			Amount: uint64(p.Amount),
+		})
+	}
+	return unlocks
+}
+
+// GetDetailedBalance gets the available, staked and locked tokens for a public key, with the unlock schedule
+func (api *API) GetDetailedBalance(pubKey string) (*DetailedBalance, error) {
+	balance, err := api.GetFioBalance(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	actor, err := ActorFromPub(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	detailed := &DetailedBalance{FioBalance: *balance, Unlocks: make([]Unlock, 0)}
+	detailed.GeneralLock, err = api.GetGeneralLock(actor)
+	if err != nil {
+		return nil, err
+	}
+	if detailed.GeneralLock != nil && detailed.GeneralLock.RemainingLockAmount > 0 {
+		detailed.Locked += uint64(detailed.GeneralLock.RemainingLockAmount)
+		detailed.Unlocks = append(detailed.Unlocks, detailed.GeneralLock.UnlockSchedule()...)
+	}
+	detailed.GenesisLock, err = api.GetGenesisLock(actor)
+	if err != nil {
+		return nil, err
+	}
+	if detailed.GenesisLock != nil {
+		detailed.Locked += detailed.GenesisLock.RemainingLockedAmount
+	}
+	sort.Slice(detailed.Unlocks, func(i, j int) bool { return detailed.Unlocks[i].Time.Before(detailed.Unlocks[j].Time) })
+	return detailed, nil
+}
@@ -0,0 +1,62 @@
+package fio
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNewTransferLockedTokens(t *testing.T) {
+	pubKey := "FIO7uRvrLVrZCbCM2DtCgUMospqUMnP3JUC1sKHA8zNoF835kJBvN"
+	for _, test := range []struct {
+		name    string
+		periods []LockPeriod
+		amount  uint64
+		valid   bool
+	}{
+		{"ok", []LockPeriod{{Duration: 60, Amount: 40}, {Duration: 120, Amount: 60}}, 100, true},
+		{"empty", nil, 100, false},
+		{"total", []LockPeriod{{Duration: 60, Amount: 40}}, 100, false},
+		{"order", []LockPeriod{{Duration: 120, Amount: 40}, {Duration: 60, Amount: 60}}, 100, false},
+		{"zero", []LockPeriod{{Duration: 60, Amount: 100}, {Duration: 120, Amount: 0}}, 100, false},
+	} {
+		act, err := NewTransferLockedTokens("aloha", pubKey, true, test.periods, test.amount)
+		if test.valid && err != nil {
+			t.Errorf("%s: %s", test.name, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%s: should be invalid", test.name)
+		}
+		if act != nil && act.Data.(TransferLockedTokens).CanVote != 1 {
+			t.Error("can_vote was not set")
+		}
+	}
+}
+
+func TestAPI_GetDetailedBalance(t *testing.T) {
+	account, err := NewRandomAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, api := newFakeNode(t)
+	node.handle("/v1/chain/get_fio_balance", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"balance":1000,"available":100,"staked":300,"srps":600,"roe":"0.500000000000000"}`))
+	})
+	// unstaked 300, one payout has already been made
+	node.rows("locktokensv2", `[{"id":1,"owner_account":"`+string(account.Actor)+`","lock_amount":600,"payouts_performed":1,
+"can_vote":1,"periods":[{"duration":100,"amount":300},{"duration":604800,"amount":300}],"remaining_lock_amount":300,"timestamp":1600000000}]`)
+	node.rows("lockedtokens", `[{"owner":"`+string(account.Actor)+`","total_grant_amount":500,"remaining_locked_amount":200,"grant_type":1}]`)
+
+	balance, err := api.GetDetailedBalance(account.PubKey)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if balance.Available != 100 || balance.Staked != 300 || balance.Roe != "0.500000000000000" {
+		t.Errorf("wrong balance: %+v", balance.FioBalance)
+	}
+	if balance.Locked != 500 || balance.GenesisLock == nil {
+		t.Error("locked should include general and genesis locks, got", balance.Locked)
+	}
+	if len(balance.Unlocks) != 1 || balance.Unlocks[0].Amount != 300 || balance.Unlocks[0].Time.Unix() != 1600604800 {
+		t.Errorf("wrong unlocks: %+v", balance.Unlocks)
+	}
+}
@@ -0,0 +1,241 @@
+package fio
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AbiCacheCheckInterval is how often a cached ABI is checked against the contract's code hash. Set to 0 to check on
+// every call.
+var AbiCacheCheckInterval = time.Minute
+
+// abiCache holds contract ABIs, keyed by node URL and account, used when decoding binary table rows.
+var abiCache = make(map[string]*cachedAbi)
+var abiCacheMux sync.Mutex
+
+type cachedAbi struct {
+	abi      *eos.ABI
+	codeHash string
+	checked  time.Time
+}
+
+// GetCachedAbi returns the ABI for a contract, only fetching it from the node if the contract's code hash has changed
+// since it was last requested. The code hash is checked at most once per AbiCacheCheckInterval.
+//
+// Note: a setabi without a setcode will not change the code hash, use InvalidateAbiCache if the ABI was updated alone.
+func (api *API) GetCachedAbi(account eos.AccountName) (*eos.ABI, error) {
+	key := api.BaseURL + "/" + string(account)
+	abiCacheMux.Lock()
+	cached := abiCache[key]
+	abiCacheMux.Unlock()
+	if cached != nil && time.Since(cached.checked) < AbiCacheCheckInterval {
+		return cached.abi, nil
+	}
+
+	hash, err := api.GetCodeHash(account)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && cached.codeHash == hash.String() {
+		abiCacheMux.Lock()
+		cached.checked = time.Now()
+		abiCacheMux.Unlock()
+		return cached.abi, nil
+	}
+
+	resp, err := api.GetABI(account)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ABI.Version == "" {
+		return nil, fmt.Errorf("no abi found for %s", account)
+	}
+	abiCacheMux.Lock()
+	abiCache[key] = &cachedAbi{abi: &resp.ABI, codeHash: hash.String(), checked: time.Now()}
+	abiCacheMux.Unlock()
+	return &resp.ABI, nil
+}
+
+// InvalidateAbiCache removes a contract's ABI from the cache, if account is empty all ABIs for the node are removed.
+func (api *API) InvalidateAbiCache(account eos.AccountName) {
+	prefix := api.BaseURL + "/"
+	abiCacheMux.Lock()
+	defer abiCacheMux.Unlock()
+	if account != "" {
+		delete(abiCache, prefix+string(account))
+		return
+	}
+	for k := range abiCache {
+		if strings.HasPrefix(k, prefix) {
+			delete(abiCache, k)
+		}
+	}
+}
+
+// GetTableRowsBinary requests the rows in binary form, and decodes them locally using the contract's ABI. This uses
+// less bandwidth than JSON: true, and works for tables that nodeos has trouble converting to JSON. The rows in the
+// response are JSON, in the same format as if they had been requested as JSON, so the result can be used anywhere
+// GetTableRows is.
+func (api *API) GetTableRowsBinary(req eos.GetTableRowsRequest) (*eos.GetTableRowsResp, error) {
+	abi, err := api.GetCachedAbi(eos.AccountName(req.Code))
+	if err != nil {
+		return nil, err
+	}
+	if abi.TableForName(eos.TableName(req.Table)) == nil {
+		return nil, fmt.Errorf("table %s not found in abi for %s", req.Table, req.Code)
+	}
+
+	req.JSON = false
+	resp, err := api.GetTableRows(req)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := DecodeTableRows(abi, eos.TableName(req.Table), resp.Rows)
+	if err != nil {
+		return nil, err
+	}
+	return &eos.GetTableRowsResp{More: resp.More, Rows: rows}, nil
+}
+
+// GetTableRowsTyped is a convenience wrapper for GetTableRowsBinary that unmarshals the rows into a slice, which
+// can be a pointer to a slice of structs, or *[]map[string]interface{}
+func (api *API) GetTableRowsTyped(req eos.GetTableRowsRequest, rows interface{}) (more bool, err error) {
+	if rows == nil {
+		return false, errors.New("rows must be a pointer to a slice")
+	}
+	resp, err := api.GetTableRowsBinary(req)
+	if err != nil {
+		return false, err
+	}
+	return resp.More, json.Unmarshal(resp.Rows, rows)
+}
+
+// getAllTableRows pages through a table by its primary key with GetTableRowsTyped, appending every row to rows, which
+// must be a pointer to a slice. lastId returns the primary key of the last row in rows, the next page starts after it.
+// req.Limit is the page size, and defaults to 500.
+func (api *API) getAllTableRows(req eos.GetTableRowsRequest, rows interface{}, lastId func() uint64) error {
+	all := reflect.ValueOf(rows)
+	if all.Kind() != reflect.Ptr || all.Elem().Kind() != reflect.Slice {
+		return errors.New("rows must be a pointer to a slice")
+	}
+	if req.Limit == 0 {
+		req.Limit = 500
+	}
+	req.KeyType, req.Index = "i64", "1"
+	var lower uint64
+	for {
+		req.LowerBound = strconv.FormatUint(lower, 10)
+		page := reflect.New(all.Elem().Type())
+		more, err := api.GetTableRowsTyped(req, page.Interface())
+		if err != nil {
+			return err
+		}
+		all.Elem().Set(reflect.AppendSlice(all.Elem(), page.Elem()))
+		if !more || page.Elem().Len() == 0 {
+			return nil
+		}
+		next := lastId() + 1
+		if next <= lower {
+			return fmt.Errorf("%s table did not advance while paging", req.Table)
+		}
+		lower = next
+	}
+}
+
+// getAllIndexRows reads every row for one key of a secondary index with GetTableRows, appending them to rows, which
+// must be a pointer to a slice. req.LowerBound and req.UpperBound are the key, and id returns the primary key of row
+// i in rows. req.Limit is the first page size, and defaults to 500.
+//
+// For a secondary index nodeos only returns the secondary key as next_key, and a lower bound starts at the first row
+// with that key, so a page can't start part way through the rows for one key. Instead the limit is doubled until
+// every row fits, and rows that were already read are skipped.
+func (api *API) getAllIndexRows(req eos.GetTableRowsRequest, rows interface{}, id func(i int) uint64) error {
+	all := reflect.ValueOf(rows)
+	if all.Kind() != reflect.Ptr || all.Elem().Kind() != reflect.Slice {
+		return errors.New("rows must be a pointer to a slice")
+	}
+	if req.Limit == 0 {
+		req.Limit = 500
+	}
+	seen := make(map[uint64]bool)
+	for {
+		gtr, err := api.GetTableRows(req)
+		if err != nil {
+			return err
+		}
+		page := reflect.New(all.Elem().Type())
+		if err = json.Unmarshal(gtr.Rows, page.Interface()); err != nil {
+			return err
+		}
+		added := 0
+		for i := 0; i < page.Elem().Len(); i++ {
+			all.Elem().Set(reflect.Append(all.Elem(), page.Elem().Index(i)))
+			last := all.Elem().Len() - 1
+			if seen[id(last)] {
+				all.Elem().SetLen(last)
+				continue
+			}
+			seen[id(last)] = true
+			added++
+		}
+		if !gtr.More {
+			return nil
+		}
+		if added == 0 {
+			return fmt.Errorf("%s table did not advance while paging", req.Table)
+		}
+		req.Limit *= 2
+	}
+}
+
+// DecodeTableRows converts binary rows, a JSON array of hex strings as returned by get_table_rows, into a JSON array
+// of objects.
+func DecodeTableRows(abi *eos.ABI, table eos.TableName, binaryRows json.RawMessage) (json.RawMessage, error) {
+	if abi == nil {
+		return nil, errors.New("abi is nil")
+	}
+	tbl := abi.TableForName(table)
+	if tbl == nil {
+		return nil, fmt.Errorf("table %s not found in abi", table)
+	}
+	return DecodeTableRowsTyped(abi, tbl.Type, binaryRows)
+}
+
+// DecodeTableRowsTyped is the same as DecodeTableRows, but uses the struct name for the row instead of the table
+// name, which is useful if the table is missing from the ABI.
+func DecodeTableRowsTyped(abi *eos.ABI, rowType string, binaryRows json.RawMessage) (json.RawMessage, error) {
+	if abi == nil {
+		return nil, errors.New("abi is nil")
+	}
+	hexRows := make([]string, 0)
+	if err := json.Unmarshal(binaryRows, &hexRows); err != nil {
+		return nil, fmt.Errorf("rows are not binary: %s", err)
+	}
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("[")
+	for i, row := range hexRows {
+		bin, err := hex.DecodeString(row)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %s", i, err)
+		}
+		decoded, err := abi.DecodeTableRowTyped(rowType, bin)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %s", i, err)
+		}
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.Write(decoded)
+	}
+	buf.WriteString("]")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,230 @@
+package fio
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"github.com/fioprotocol/fio-go/eos"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const tableRowsTestAbi = `{
+	"version": "eosio::abi/1.1",
+	"types": [],
+	"structs": [
+		{"name": "tokenpubaddr", "base": "", "fields": [
+			{"name": "token_code", "type": "string"},
+			{"name": "chain_code", "type": "string"},
+			{"name": "public_address", "type": "string"}
+		]},
+		{"name": "fioname", "base": "", "fields": [
+			{"name": "id", "type": "uint64"},
+			{"name": "name", "type": "string"},
+			{"name": "namehash", "type": "uint128"},
+			{"name": "domain", "type": "string"},
+			{"name": "expiration", "type": "uint64"},
+			{"name": "owner_account", "type": "name"},
+			{"name": "addresses", "type": "tokenpubaddr[]"},
+			{"name": "bundleeligiblecountdown", "type": "uint64"}
+		]}
+	],
+	"actions": [],
+	"tables": [{"name": "fionames", "index_type": "i64", "key_names": [], "key_types": [], "type": "fioname"}]
+}`
+
+type testTokenPubAddr struct {
+	TokenCode     string `json:"token_code"`
+	ChainCode     string `json:"chain_code"`
+	PublicAddress string `json:"public_address"`
+}
+
+type testFioName struct {
+	Id                      eos.Uint64         `json:"id"`
+	Name                    string             `json:"name"`
+	NameHash                eos.Uint128        `json:"namehash"`
+	Domain                  string             `json:"domain"`
+	Expiration              eos.Uint64         `json:"expiration"`
+	OwnerAccount            eos.AccountName    `json:"owner_account"`
+	Addresses               []testTokenPubAddr `json:"addresses"`
+	BundleEligibleCountdown eos.Uint64         `json:"bundleeligiblecountdown"`
+}
+
+// tableRowsServer is a fake node that serves a table as either JSON or binary
+type tableRowsServer struct {
+	*httptest.Server
+	codeHash   atomic.Value
+	abiFetches int32
+	jsonRows   []byte
+	binRows    []byte
+}
+
+func newTableRowsServer(rowCount int) (*tableRowsServer, error) {
+	s := &tableRowsServer{}
+	s.codeHash.Store(strings.Repeat("01", 32))
+	rows := make([]testFioName, rowCount)
+	hexRows := make([]string, rowCount)
+	for i := range rows {
+		rows[i] = testFioName{
+			Id:           eos.Uint64(i),
+			Name:         "test@fiotestnet",
+			NameHash:     eos.Uint128{Lo: uint64(i) << 40, Hi: 1},
+			Domain:       "fiotestnet",
+			Expiration:   1700000000,
+			OwnerAccount: "hzhqyv5n2ut5",
+			Addresses: []testTokenPubAddr{
+				{"FIO", "FIO", "FIO6G9pXXM92Gy5eMwNquGULoCj3ZStwPLPdEb9mVXyEHqWN7HSuA"},
+				{"ETH", "ETH", "0x6DB9a4C4eC6d9ee1B39e3D9b5D8B6d3f9fA7B5d8"},
+			},
+			BundleEligibleCountdown: 100,
+		}
+		bin, err := eos.MarshalBinary(rows[i])
+		if err != nil {
+			return nil, err
+		}
+		hexRows[i] = hex.EncodeToString(bin)
+	}
+	var err error
+	if s.jsonRows, err = json.Marshal(rows); err != nil {
+		return nil, err
+	}
+	if s.binRows, err = json.Marshal(hexRows); err != nil {
+		return nil, err
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/v1/chain/get_code_hash":
+			_, _ = w.Write([]byte(`{"account_name":"fio.address","code_hash":"` + s.codeHash.Load().(string) + `"}`))
+		case "/v1/chain/get_abi":
+			atomic.AddInt32(&s.abiFetches, 1)
+			_, _ = w.Write([]byte(`{"account_name":"fio.address","abi":` + tableRowsTestAbi + `}`))
+		case "/v1/chain/get_table_rows":
+			req := eos.GetTableRowsRequest{}
+			_ = json.Unmarshal(body, &req)
+			rows := s.binRows
+			if req.JSON {
+				rows = s.jsonRows
+			}
+			_, _ = w.Write([]byte(`{"more":false,"rows":` + string(rows) + `}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return s, nil
+}
+
+func TestAPI_GetTableRowsBinary(t *testing.T) {
+	server, err := newTableRowsServer(3)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer server.Close()
+	api := &API{*eos.New(server.URL)}
+	defer api.InvalidateAbiCache("")
+
+	req := eos.GetTableRowsRequest{Code: "fio.address", Scope: "fio.address", Table: "fionames"}
+	typed := make([]testFioName, 0)
+	more, err := api.GetTableRowsTyped(req, &typed)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := make([]testFioName, 0)
+	_ = json.Unmarshal(server.jsonRows, &expected)
+	if more || len(typed) != 3 {
+		t.Error("expected 3 rows")
+		return
+	}
+	a, _ := json.Marshal(typed)
+	b, _ := json.Marshal(expected)
+	if string(a) != string(b) {
+		t.Errorf("binary rows did not match json rows:\n%s\n%s", string(a), string(b))
+	}
+
+	generic := make([]map[string]interface{}, 0)
+	if _, err = api.GetTableRowsTyped(req, &generic); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(generic) != 3 || generic[1]["owner_account"] != "hzhqyv5n2ut5" {
+		t.Error("did not decode into maps")
+	}
+
+	// cache should not fetch the abi again unless the code hash changes
+	prev := AbiCacheCheckInterval
+	AbiCacheCheckInterval = 0
+	defer func() { AbiCacheCheckInterval = prev }()
+	if _, err = api.GetTableRowsBinary(req); err != nil {
+		t.Error(err)
+	}
+	if atomic.LoadInt32(&server.abiFetches) != 1 {
+		t.Error("abi should have been cached")
+	}
+	server.codeHash.Store(strings.Repeat("02", 32))
+	if _, err = api.GetTableRowsBinary(req); err != nil {
+		t.Error(err)
+	}
+	if atomic.LoadInt32(&server.abiFetches) != 2 {
+		t.Error("abi should have been fetched after code hash changed")
+	}
+	api.InvalidateAbiCache("fio.address")
+	if _, err = api.GetCachedAbi("fio.address"); err != nil {
+		t.Error(err)
+	}
+	if atomic.LoadInt32(&server.abiFetches) != 3 {
+		t.Error("abi should have been fetched after invalidating")
+	}
+
+	req.Table = "missing"
+	if _, err = api.GetTableRowsBinary(req); err == nil {
+		t.Error("should not decode a table missing from the abi")
+	}
+}
+
+func benchmarkGetTableRows(b *testing.B, binary bool) {
+	server, err := newTableRowsServer(100)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer server.Close()
+	api := &API{*eos.New(server.URL)}
+	defer api.InvalidateAbiCache("")
+	req := eos.GetTableRowsRequest{Code: "fio.address", Scope: "fio.address", Table: "fionames", Limit: 100, JSON: true}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows := make([]testFioName, 0)
+		if binary {
+			_, err = api.GetTableRowsTyped(req, &rows)
+		} else {
+			var resp *eos.GetTableRowsResp
+			if resp, err = api.GetTableRows(req); err == nil {
+				err = json.Unmarshal(resp.Rows, &rows)
+			}
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	// the binary path trades local decoding time for smaller responses
+	if binary {
+		b.ReportMetric(float64(len(server.binRows)), "rows-bytes/op")
+	} else {
+		b.ReportMetric(float64(len(server.jsonRows)), "rows-bytes/op")
+	}
+}
+
+func BenchmarkAPI_GetTableRows_JSON(b *testing.B) {
+	benchmarkGetTableRows(b, false)
+}
+
+func BenchmarkAPI_GetTableRowsBinary(b *testing.B) {
+	benchmarkGetTableRows(b, true)
+}
@@ -35,6 +35,20 @@ func NewTransferTokensPubKey(actor eos.AccountName, recipientPubKey string, amou
 	)
 }
 
+// NewTransferTokensPubKeyAmount is NewTransferTokensPubKey using an exact Amount
+func NewTransferTokensPubKeyAmount(actor eos.AccountName, recipientPubKey string, amount Amount) *Action {
+	return NewAction(
+		"fio.token", "trnsfiopubky", actor,
+		TransferTokensPubKey{
+			PayeePublicKey: recipientPubKey,
+			Amount:         amount.Suf(),
+			MaxFee:         GetMaxFeeAmount(FeeTransferTokensPubKey).Suf(),
+			Actor:          actor,
+			Tpid:           CurrentTpid(),
+		},
+	)
+}
+
 // Transfer is a privileged call, and not normally used for sending tokens, use TransferTokensPubKey instead
 type Transfer struct {
 	From     eos.AccountName `json:"from"`
@@ -63,6 +77,24 @@ func NewTransfer(actor eos.AccountName, recipient eos.AccountName, amount uint64
 	)
 }
 
+// NewTransferAmount is NewTransfer using an exact Amount, it fails if the amount does not fit in an eos.Asset
+//
+// deprecated: internal action, user cannot call.
+func NewTransferAmount(actor eos.AccountName, recipient eos.AccountName, amount Amount) (*Action, error) {
+	quantity, err := amount.Asset()
+	if err != nil {
+		return nil, err
+	}
+	return NewAction(
+		eos.AccountName("fio.token"), "transfer", actor,
+		Transfer{
+			From:     actor,
+			To:       recipient,
+			Quantity: quantity,
+		},
+	), nil
+}
+
 // GetBalance gets an account's balance
 func (api *API) GetBalance(account eos.AccountName) (float64, error) {
 	a, err := api.GetCurrencyBalance(account, "FIO", eos.AccountName("fio.token"))
@@ -76,3 +108,15 @@ func (api *API) GetBalance(account eos.AccountName) (float64, error) {
 	}
 	return 0.0, nil
 }
+
+// GetBalanceAmount gets an account's balance as an exact Amount
+func (api *API) GetBalanceAmount(account eos.AccountName) (Amount, error) {
+	a, err := api.GetCurrencyBalance(account, "FIO", eos.AccountName("fio.token"))
+	if err != nil {
+		return 0, err
+	}
+	if len(a) == 0 {
+		return 0, nil
+	}
+	return AmountFromAsset(a[0])
+}
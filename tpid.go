@@ -1,7 +1,11 @@
 package fio
 
 import (
+	"encoding/json"
 	"github.com/fioprotocol/fio-go/eos"
+	"reflect"
+	"sort"
+	"strconv"
 	"sync"
 )
 
@@ -12,7 +16,8 @@ var tpidMux sync.RWMutex
 
 // SetTpid will set a package variable that will include the provided TPID in all of the calls that support it.
 // This only needs to be called once. By default it is empty, and is recommended for wallet providers or other
-// service providers to set at initialization via SetTpid to get rewards.
+// service providers to set at initialization via SetTpid to get rewards. Use TxOptions.SetTpid or Action.WithTpid to
+// send a different tpid for a transaction or a single action.
 func SetTpid(walletAddress string) (ok bool) {
 	tpidMux.Lock()
 	defer tpidMux.Unlock()
@@ -86,3 +91,301 @@ func NewUpdateBounty(actor eos.AccountName, amount uint64) *Action {
 		UpdateBounty{Amount: amount},
 	)
 }
+
+// WithTpid returns a copy of the action with its tpid set, overriding CurrentTpid for a single call. It returns
+// false if the tpid is not a valid FIO address, or the action's data does not have a tpid.
+func (act Action) WithTpid(tpid string) (*Action, bool) {
+	if !Address(tpid).Valid() || act.Data == nil {
+		return nil, false
+	}
+	data, ok := setTpid(act.Data, tpid)
+	if !ok {
+		return nil, false
+	}
+	act.Authorization = append([]eos.PermissionLevel{}, act.Authorization...)
+	act.Data = data
+	return &act, true
+}
+
+// setTpid copies a struct, or pointer to a struct, with a string Tpid field and sets the field
+func setTpid(data interface{}, tpid string) (interface{}, bool) {
+	v := reflect.ValueOf(data)
+	isPtr := v.Kind() == reflect.Ptr
+	if isPtr {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+	if f := v.FieldByName("Tpid"); !f.IsValid() || f.Kind() != reflect.String {
+		return nil, false
+	}
+	cp := reflect.New(v.Type())
+	cp.Elem().Set(v)
+	cp.Elem().FieldByName("Tpid").SetString(tpid)
+	if isPtr {
+		return cp.Interface(), true
+	}
+	return cp.Elem().Interface(), true
+}
+
+// ActionTpid gets the tpid from an action's data, which may be a struct built by this package, decoded JSON, or
+// HexData. HexData can only be read if an ABI is provided.
+func ActionTpid(act *eos.Action, abi *eos.ABI) (tpid string, ok bool) {
+	if act == nil {
+		return "", false
+	}
+	data := act.Data
+	if data == nil && len(act.HexData) > 0 && abi != nil {
+		j, err := abi.DecodeAction(act.HexData, act.Name)
+		if err != nil {
+			return "", false
+		}
+		m := make(map[string]interface{})
+		if err = json.Unmarshal(j, &m); err != nil {
+			return "", false
+		}
+		data = m
+	}
+	switch d := data.(type) {
+	case map[string]interface{}:
+		tpid, ok = d["tpid"].(string)
+		return
+	case nil:
+		return "", false
+	}
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if v.Kind() != reflect.Struct {
+		return "", false
+	}
+	if f := v.FieldByName("Tpid"); f.IsValid() && f.Kind() == reflect.String {
+		return f.String(), true
+	}
+	return "", false
+}
+
+// TpidInfo is a row in the fio.tpid tpids table, Rewards are in SUF and are paid by tpidclaim
+type TpidInfo struct {
+	Id             uint64      `json:"id"`
+	FioAddress     string      `json:"fioaddress"`
+	FioAddressHash eos.Uint128 `json:"fioaddhash"`
+	Rewards        eos.Uint64  `json:"rewards"`
+}
+
+// GetTpid gets the accumulated rewards for a tpid, it returns nil if the tpid has never been used
+func (api *API) GetTpid(tpid string) (*TpidInfo, error) {
+	gtr, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:       "fio.tpid",
+		Scope:      "fio.tpid",
+		Table:      "tpids",
+		LowerBound: AddressHash(tpid),
+		UpperBound: AddressHash(tpid),
+		Limit:      1,
+		KeyType:    "i128",
+		Index:      "2",
+		JSON:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tpids := make([]*TpidInfo, 0)
+	err = json.Unmarshal(gtr.Rows, &tpids)
+	if err != nil {
+		return nil, err
+	}
+	if len(tpids) == 0 {
+		return nil, nil
+	}
+	return tpids[0], nil
+}
+
+// GetTpids gets a page of the tpids table, starting at the primary key lowerBound
+func (api *API) GetTpids(lowerBound uint64, limit uint32) (tpids []*TpidInfo, more bool, err error) {
+	gtr, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:       "fio.tpid",
+		Scope:      "fio.tpid",
+		Table:      "tpids",
+		LowerBound: strconv.FormatUint(lowerBound, 10),
+		Limit:      limit,
+		KeyType:    "i64",
+		Index:      "1",
+		JSON:       true,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	tpids = make([]*TpidInfo, 0)
+	err = json.Unmarshal(gtr.Rows, &tpids)
+	if err != nil {
+		return nil, false, err
+	}
+	return tpids, gtr.More, nil
+}
+
+// GetAllTpids pages through the entire tpids table, pageSize defaults to 500
+func (api *API) GetAllTpids(pageSize uint32) ([]*TpidInfo, error) {
+	tpids := make([]*TpidInfo, 0)
+	err := api.getAllTableRows(
+		eos.GetTableRowsRequest{Code: "fio.tpid", Scope: "fio.tpid", Table: "tpids", Limit: pageSize},
+		&tpids, func() uint64 { return tpids[len(tpids)-1].Id },
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tpids, nil
+}
+
+// GetBounty returns the total tokens minted for TPID bounties from the fio.tpid bounties table, in SUF
+func (api *API) GetBounty() (uint64, error) {
+	gtr, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:  "fio.tpid",
+		Scope: "fio.tpid",
+		Table: "bounties",
+		Limit: 1,
+		JSON:  true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	bounties := make([]struct {
+		TokensMinted uint64 `json:"tokensminted"`
+	}, 0)
+	err = json.Unmarshal(gtr.Rows, &bounties)
+	if err != nil {
+		return 0, err
+	}
+	if len(bounties) == 0 {
+		return 0, nil
+	}
+	return bounties[0].TokensMinted, nil
+}
+
+// TpidRecord is an action sent with a tpid, Tpid is empty if the action did not include one
+type TpidRecord struct {
+	TxId    string          `json:"tx_id"`
+	Block   uint32          `json:"block,omitempty"`
+	Account eos.AccountName `json:"account"`
+	Name    eos.ActionName  `json:"name"`
+	Actor   eos.AccountName `json:"actor"`
+	Tpid    string          `json:"tpid"`
+}
+
+// TpidSummary totals the records for one tpid, OnChain is nil if the tpid has no row in the tpids table
+type TpidSummary struct {
+	Tpid         string    `json:"tpid"`
+	Transactions int       `json:"transactions"`
+	Actions      int       `json:"actions"`
+	OnChain      *TpidInfo `json:"on_chain,omitempty"`
+}
+
+// TpidLedger records which tpid was sent with each of our transactions so it can be compared with the tpids table.
+// It is safe for concurrent use.
+type TpidLedger struct {
+	mux     sync.Mutex
+	Records []TpidRecord `json:"records"`
+}
+
+// Record adds the actions from a pushed transaction, only actions with a tpid field are recorded
+func (tl *TpidLedger) Record(txId string, block uint32, actions ...*Action) {
+	tl.mux.Lock()
+	defer tl.mux.Unlock()
+	for _, a := range actions {
+		tpid, ok := ActionTpid(a.ToEos(), nil)
+		if !ok {
+			continue
+		}
+		r := TpidRecord{TxId: txId, Block: block, Account: a.Account, Name: a.Name, Tpid: tpid}
+		if len(a.Authorization) > 0 {
+			r.Actor = a.Authorization[0].Actor
+		}
+		tl.Records = append(tl.Records, r)
+	}
+}
+
+// RecordBlock adds actions from a block that were authorized by one of the actors. ABIs are needed to read the tpid,
+// so this uses the API's ABI cache.
+func (tl *TpidLedger) RecordBlock(api *API, block *eos.BlockResp, actors ...eos.AccountName) error {
+	ours := make(map[eos.AccountName]bool)
+	for _, a := range actors {
+		ours[a] = true
+	}
+	for _, receipt := range block.Transactions {
+		if receipt.Transaction.Packed == nil {
+			continue
+		}
+		trx, err := receipt.Transaction.Packed.UnpackBare()
+		if err != nil {
+			continue
+		}
+		for _, a := range trx.Actions {
+			if len(a.Authorization) == 0 || !ours[a.Authorization[0].Actor] {
+				continue
+			}
+			abi, err := api.GetCachedAbi(a.Account)
+			if err != nil {
+				return err
+			}
+			tpid, ok := ActionTpid(a, abi)
+			if !ok {
+				continue
+			}
+			tl.mux.Lock()
+			tl.Records = append(tl.Records, TpidRecord{
+				TxId:    receipt.Transaction.ID.String(),
+				Block:   block.BlockNum,
+				Account: a.Account,
+				Name:    a.Name,
+				Actor:   a.Authorization[0].Actor,
+				Tpid:    tpid,
+			})
+			tl.mux.Unlock()
+		}
+	}
+	return nil
+}
+
+// Summarize groups the records by tpid, sorted by tpid, records without a tpid are grouped under an empty tpid
+func (tl *TpidLedger) Summarize() []*TpidSummary {
+	tl.mux.Lock()
+	defer tl.mux.Unlock()
+	byTpid := make(map[string]*TpidSummary)
+	txs := make(map[string]map[string]bool)
+	for _, r := range tl.Records {
+		s := byTpid[r.Tpid]
+		if s == nil {
+			s = &TpidSummary{Tpid: r.Tpid}
+			byTpid[r.Tpid] = s
+			txs[r.Tpid] = make(map[string]bool)
+		}
+		s.Actions++
+		if !txs[r.Tpid][r.TxId] {
+			txs[r.Tpid][r.TxId] = true
+			s.Transactions++
+		}
+	}
+	summaries := make([]*TpidSummary, 0, len(byTpid))
+	for _, s := range byTpid {
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Tpid < summaries[j].Tpid })
+	return summaries
+}
+
+// Reconcile summarizes the records and adds the current row from the tpids table for each tpid
+func (tl *TpidLedger) Reconcile(api *API) ([]*TpidSummary, error) {
+	summaries := tl.Summarize()
+	for _, s := range summaries {
+		if s.Tpid == "" {
+			continue
+		}
+		info, err := api.GetTpid(s.Tpid)
+		if err != nil {
+			return nil, err
+		}
+		s.OnChain = info
+	}
+	return summaries, nil
+}
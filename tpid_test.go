@@ -1,7 +1,10 @@
 package fio
 
 import (
+	"encoding/json"
+	"github.com/fioprotocol/fio-go/eos"
 	"math/rand"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -73,3 +76,116 @@ func TestTpid(t *testing.T) {
 		t.Error("expected tpid payout: " + string(j))
 	}
 }
+
+func TestAction_WithTpid(t *testing.T) {
+	act := NewTransferTokensPubKey("aloha", "FIO7uRvrLVrZCbCM2DtCgUMospqUMnP3JUC1sKHA8zNoF835kJBvN", 1)
+	withTpid, ok := act.WithTpid("tenant@wallet")
+	if !ok {
+		t.Error("could not set tpid")
+		return
+	}
+	if tpid, _ := ActionTpid(withTpid.ToEos(), nil); tpid != "tenant@wallet" {
+		t.Error("tpid was not set:", tpid)
+	}
+	if tpid, _ := ActionTpid(act.ToEos(), nil); tpid == "tenant@wallet" {
+		t.Error("original action should not be modified")
+	}
+	if _, ok = act.WithTpid("not valid"); ok {
+		t.Error("invalid tpid should be rejected")
+	}
+	if _, ok = NewPayTpidRewards("aloha").WithTpid("tenant@wallet"); ok {
+		t.Error("action without a tpid should be rejected")
+	}
+
+	opts := &TxOptions{}
+	if err := opts.SetTpid("other@wallet"); err != nil {
+		t.Error(err)
+		return
+	}
+	tx := NewTransaction([]*Action{act}, opts)
+	if tpid, _ := ActionTpid(tx.Actions[0], nil); tpid != "other@wallet" {
+		t.Error("TxOptions tpid was not used:", tpid)
+	}
+	if err := opts.SetTpid("not valid"); err == nil {
+		t.Error("invalid TxOptions tpid should be rejected")
+	}
+	if opts.Tpid() != "other@wallet" {
+		t.Error("invalid tpid should not replace the override, got", opts.Tpid())
+	}
+}
+
+func TestTpidLedger(t *testing.T) {
+	node, api := newFakeNode(t)
+	node.table("tpids", func(req eos.GetTableRowsRequest) (interface{}, bool) {
+		if req.LowerBound == AddressHash("a@wallet") {
+			return json.RawMessage(`[{"id":1,"fioaddress":"a@wallet","fioaddhash":"0x00000000000000000000000000000000","rewards":2000000000}]`), false
+		}
+		return json.RawMessage(`[]`), false
+	})
+
+	pubKey := "FIO7uRvrLVrZCbCM2DtCgUMospqUMnP3JUC1sKHA8zNoF835kJBvN"
+	a, _ := NewTransferTokensPubKey("aloha", pubKey, 1).WithTpid("a@wallet")
+	b, _ := NewTransferTokensPubKey("aloha", pubKey, 2).WithTpid("b@wallet")
+	tl := &TpidLedger{}
+	tl.Record("tx1", 10, a, a, NewPayTpidRewards("aloha"))
+	tl.Record("tx2", 11, b)
+	if len(tl.Records) != 3 || tl.Records[0].Actor != "aloha" {
+		t.Error("wrong records", tl.Records)
+	}
+
+	summaries, err := tl.Reconcile(api)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(summaries) != 2 || summaries[0].Tpid != "a@wallet" || summaries[0].Actions != 2 || summaries[0].Transactions != 1 {
+		t.Errorf("wrong summary: %+v", summaries[0])
+	}
+	if summaries[0].OnChain == nil || summaries[0].OnChain.Rewards != 2000000000 || summaries[1].OnChain != nil {
+		t.Error("on chain rewards were not added")
+	}
+}
+
+const tpidsTestAbi = `{
+	"version": "eosio::abi/1.1",
+	"structs": [{"name": "tpid", "base": "", "fields": [
+		{"name": "id", "type": "uint64"},
+		{"name": "fioaddress", "type": "string"},
+		{"name": "fioaddhash", "type": "uint128"},
+		{"name": "rewards", "type": "uint64"}
+	]}],
+	"tables": [{"name": "tpids", "index_type": "i64", "key_names": [], "key_types": [], "type": "tpid"}]
+}`
+
+func TestAPI_GetAllTpids(t *testing.T) {
+	tpids := []*TpidInfo{
+		{Id: 0, FioAddress: "a@wallet", Rewards: 1},
+		{Id: 2, FioAddress: "b@wallet", Rewards: 20_000_000_000},
+		{Id: 5, FioAddress: "c@wallet", Rewards: 3},
+	}
+	node, api := newFakeNode(t)
+	node.abi("fio.tpid", tpidsTestAbi)
+	node.table("tpids", func(req eos.GetTableRowsRequest) (interface{}, bool) {
+		lower, _ := strconv.ParseUint(req.LowerBound, 10, 64)
+		page := make([]*TpidInfo, 0)
+		for _, tpid := range tpids {
+			if tpid.Id >= lower && len(page) < int(req.Limit) {
+				page = append(page, tpid)
+			}
+		}
+		return page, len(page) > 0 && page[len(page)-1] != tpids[len(tpids)-1]
+	})
+
+	all, err := api.GetAllTpids(2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(all) != 3 || all[2].FioAddress != "c@wallet" {
+		t.Errorf("expected every tpid, got %+v", all)
+		return
+	}
+	if all[1].Rewards != 20_000_000_000 {
+		t.Error("large rewards were not decoded", all[1].Rewards)
+	}
+}
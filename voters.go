@@ -0,0 +1,400 @@
+package fio
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/fioprotocol/fio-go/eos"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// VoterInfo is a row in the eosio voters table. LastVoteWeight includes ProxiedVoteWeight when the voter is a proxy.
+type VoterInfo struct {
+	Id                uint64            `json:"id"`
+	FioAddress        string            `json:"fioaddress"`
+	AddressHash       eos.Uint128       `json:"addresshash"`
+	Owner             eos.AccountName   `json:"owner"`
+	Proxy             eos.AccountName   `json:"proxy"`
+	Producers         []eos.AccountName `json:"producers"`
+	LastVoteWeight    eos.JSONFloat64   `json:"last_vote_weight"`
+	ProxiedVoteWeight eos.JSONFloat64   `json:"proxied_vote_weight"`
+	IsProxy           eos.Bool          `json:"is_proxy"`
+	IsAutoProxy       eos.Bool          `json:"is_auto_proxy"`
+}
+
+// OwnWeight is the voter's weight without any weight proxied to it
+func (v VoterInfo) OwnWeight() float64 {
+	return float64(v.LastVoteWeight - v.ProxiedVoteWeight)
+}
+
+// GetVoters gets a page of the voters table, starting at the primary key lowerBound
+func (api *API) GetVoters(lowerBound uint64, limit uint32) (voters []*VoterInfo, more bool, err error) {
+	gtr, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:       "eosio",
+		Scope:      "eosio",
+		Table:      "voters",
+		LowerBound: strconv.FormatUint(lowerBound, 10),
+		Limit:      limit,
+		KeyType:    "i64",
+		Index:      "1",
+		JSON:       true,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	voters = make([]*VoterInfo, 0)
+	err = json.Unmarshal(gtr.Rows, &voters)
+	if err != nil {
+		return nil, false, err
+	}
+	return voters, gtr.More, nil
+}
+
+// GetAllVoters pages through the entire voters table, pageSize defaults to 500
+func (api *API) GetAllVoters(pageSize uint32) ([]*VoterInfo, error) {
+	voters := make([]*VoterInfo, 0)
+	err := api.getAllTableRows(
+		eos.GetTableRowsRequest{Code: "eosio", Scope: "eosio", Table: "voters", Limit: pageSize},
+		&voters, func() uint64 { return voters[len(voters)-1].Id },
+	)
+	if err != nil {
+		return nil, err
+	}
+	return voters, nil
+}
+
+// GetVoter gets the voters table row for an account, it returns nil if the account has never voted
+func (api *API) GetVoter(account eos.AccountName) (*VoterInfo, error) {
+	gtr, err := api.GetTableRows(eos.GetTableRowsRequest{
+		Code:       "eosio",
+		Scope:      "eosio",
+		Table:      "voters",
+		LowerBound: string(account),
+		UpperBound: string(account),
+		Limit:      1,
+		KeyType:    "name",
+		Index:      "3",
+		JSON:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	voters := make([]*VoterInfo, 0)
+	err = json.Unmarshal(gtr.Rows, &voters)
+	if err != nil {
+		return nil, err
+	}
+	if len(voters) == 0 {
+		return nil, nil
+	}
+	return voters[0], nil
+}
+
+// VoterWeight is the effective weight of an account in the proxy graph. Effective is the weight that actually
+// reaches producers: a voter's own weight if it votes directly or through a proxy that votes, plus any weight
+// proxied to it if it is a proxy that votes.
+type VoterWeight struct {
+	Owner     eos.AccountName   `json:"owner"`
+	Proxy     eos.AccountName   `json:"proxy,omitempty"`
+	IsProxy   bool              `json:"is_proxy"`
+	Own       float64           `json:"own"`
+	Proxied   float64           `json:"proxied"`
+	Effective float64           `json:"effective"`
+	Producers []eos.AccountName `json:"producers"`
+	Delegates int               `json:"delegates"`
+}
+
+// VoteGraph is the proxy graph rebuilt from the voters table. Proxies can not vote through another proxy, so the
+// graph is at most one level deep.
+type VoteGraph struct {
+	Voters map[eos.AccountName]*VoterInfo
+	// Delegates lists the accounts voting through each proxy
+	Delegates map[eos.AccountName][]eos.AccountName
+}
+
+// NewVoteGraph builds a VoteGraph, see GetAllVoters
+func NewVoteGraph(voters []*VoterInfo) *VoteGraph {
+	g := &VoteGraph{
+		Voters:    make(map[eos.AccountName]*VoterInfo),
+		Delegates: make(map[eos.AccountName][]eos.AccountName),
+	}
+	for _, v := range voters {
+		g.Voters[v.Owner] = v
+		if v.Proxy != "" {
+			g.Delegates[v.Proxy] = append(g.Delegates[v.Proxy], v.Owner)
+		}
+	}
+	for proxy := range g.Delegates {
+		sort.Slice(g.Delegates[proxy], func(i, j int) bool { return g.Delegates[proxy][i] < g.Delegates[proxy][j] })
+	}
+	return g
+}
+
+// producersFor returns the producers that receive an account's weight, following its proxy
+func (g *VoteGraph) producersFor(account eos.AccountName) []eos.AccountName {
+	v := g.Voters[account]
+	if v == nil {
+		return nil
+	}
+	if v.Proxy != "" {
+		if p := g.Voters[v.Proxy]; p != nil && bool(p.IsProxy) {
+			return p.Producers
+		}
+		return nil
+	}
+	return v.Producers
+}
+
+// Weight returns the effective weight of an account, it returns false if the account is not in the voters table
+func (g *VoteGraph) Weight(account eos.AccountName) (VoterWeight, bool) {
+	v := g.Voters[account]
+	if v == nil {
+		return VoterWeight{}, false
+	}
+	w := VoterWeight{
+		Owner:     v.Owner,
+		Proxy:     v.Proxy,
+		IsProxy:   bool(v.IsProxy),
+		Own:       v.OwnWeight(),
+		Proxied:   float64(v.ProxiedVoteWeight),
+		Producers: g.producersFor(account),
+		Delegates: len(g.Delegates[account]),
+	}
+	if len(w.Producers) > 0 {
+		w.Effective = w.Own
+		// weight proxied to an account only counts if it votes directly
+		if v.Proxy == "" {
+			w.Effective += w.Proxied
+		}
+	}
+	return w, true
+}
+
+// Weights returns the effective weight of every voter, sorted from highest to lowest
+func (g *VoteGraph) Weights() []VoterWeight {
+	weights := make([]VoterWeight, 0, len(g.Voters))
+	for owner := range g.Voters {
+		w, _ := g.Weight(owner)
+		weights = append(weights, w)
+	}
+	sort.Slice(weights, func(i, j int) bool {
+		if weights[i].Effective == weights[j].Effective {
+			return weights[i].Owner < weights[j].Owner
+		}
+		return weights[i].Effective > weights[j].Effective
+	})
+	return weights
+}
+
+// ProducerVotes totals the weight of direct votes for each producer. This should match total_votes in the producers
+// table, except where voters' weights have not been updated since their balance changed.
+func (g *VoteGraph) ProducerVotes() map[eos.AccountName]float64 {
+	votes := make(map[eos.AccountName]float64)
+	for _, v := range g.Voters {
+		if v.Proxy != "" {
+			continue
+		}
+		for _, p := range v.Producers {
+			votes[p] += float64(v.LastVoteWeight)
+		}
+	}
+	return votes
+}
+
+// ProducerRank is a producer's position when ordered by votes, starting at 1
+type ProducerRank struct {
+	Producer eos.AccountName `json:"producer"`
+	Votes    float64         `json:"votes"`
+	Rank     int             `json:"rank"`
+}
+
+// rankVotes orders producers by votes, ties are ordered by name
+func rankVotes(votes map[eos.AccountName]float64) []ProducerRank {
+	ranks := make([]ProducerRank, 0, len(votes))
+	for p, v := range votes {
+		ranks = append(ranks, ProducerRank{Producer: p, Votes: v})
+	}
+	sort.Slice(ranks, func(i, j int) bool {
+		if ranks[i].Votes == ranks[j].Votes {
+			return ranks[i].Producer < ranks[j].Producer
+		}
+		return ranks[i].Votes > ranks[j].Votes
+	})
+	for i := range ranks {
+		ranks[i].Rank = i + 1
+	}
+	return ranks
+}
+
+// Ranks returns every producer that has received a vote, ordered by ProducerVotes
+func (g *VoteGraph) Ranks() []ProducerRank {
+	return rankVotes(g.ProducerVotes())
+}
+
+// RankChange is the result of a hypothetical vote for one producer
+type RankChange struct {
+	Producer eos.AccountName `json:"producer"`
+	OldVotes float64         `json:"old_votes"`
+	NewVotes float64         `json:"new_votes"`
+	OldRank  int             `json:"old_rank"`
+	NewRank  int             `json:"new_rank"`
+}
+
+// WhatIf calculates how ranks would change if voter voted directly for producers. The voter's current weight is
+// removed from the producers it votes for, directly or through a proxy, and added to the new producers. If weight
+// is 0, the voter's current LastVoteWeight is used, which fails if the account has never voted. Only producers
+// whose votes or rank change are returned, a producer without any votes has an OldRank of 0.
+func (g *VoteGraph) WhatIf(voter eos.AccountName, producers []eos.AccountName, weight float64) ([]RankChange, error) {
+	current := g.Voters[voter]
+	if weight == 0 {
+		if current == nil {
+			return nil, errors.New("voter not found, a weight is required")
+		}
+		weight = float64(current.LastVoteWeight)
+	}
+	before := g.ProducerVotes()
+	after := make(map[eos.AccountName]float64, len(before))
+	for p, v := range before {
+		after[p] = v
+	}
+	if current != nil {
+		// a proxy's votes already include its proxied weight, a delegate only contributes its own
+		old := float64(current.LastVoteWeight)
+		for _, p := range g.producersFor(voter) {
+			after[p] -= old
+		}
+	}
+	for _, p := range producers {
+		after[p] += weight
+	}
+
+	oldRanks := make(map[eos.AccountName]ProducerRank)
+	for _, r := range rankVotes(before) {
+		oldRanks[r.Producer] = r
+	}
+	changes := make([]RankChange, 0)
+	for _, r := range rankVotes(after) {
+		old := oldRanks[r.Producer]
+		if old.Votes == r.Votes && old.Rank == r.Rank {
+			continue
+		}
+		changes = append(changes, RankChange{
+			Producer: r.Producer,
+			OldVotes: old.Votes,
+			NewVotes: r.Votes,
+			OldRank:  old.Rank,
+			NewRank:  r.Rank,
+		})
+	}
+	return changes, nil
+}
+
+// VoteSnapshot is a point in time copy of the voters table, it can be saved and compared with DiffVoteSnapshots
+type VoteSnapshot struct {
+	Time      time.Time    `json:"time"`
+	HeadBlock uint32       `json:"head_block"`
+	Voters    []*VoterInfo `json:"voters"`
+}
+
+// NewVoteSnapshot loads every voter, recording the head block it was taken at
+func (api *API) NewVoteSnapshot() (*VoteSnapshot, error) {
+	info, err := api.GetInfo()
+	if err != nil {
+		return nil, err
+	}
+	voters, err := api.GetAllVoters(0)
+	if err != nil {
+		return nil, err
+	}
+	return &VoteSnapshot{Time: info.HeadBlockTime.Time, HeadBlock: info.HeadBlockNum, Voters: voters}, nil
+}
+
+// LoadVoteSnapshot reads a snapshot saved with VoteSnapshot.Save
+func LoadVoteSnapshot(r io.Reader) (*VoteSnapshot, error) {
+	snap := &VoteSnapshot{}
+	if err := json.NewDecoder(r).Decode(snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Save writes the snapshot as JSON
+func (vs *VoteSnapshot) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(vs)
+}
+
+// Graph builds the VoteGraph for the snapshot
+func (vs *VoteSnapshot) Graph() *VoteGraph {
+	return NewVoteGraph(vs.Voters)
+}
+
+// VoterChange describes how a voter changed between two snapshots, Old or New is nil if the voter was added or
+// removed.
+type VoterChange struct {
+	Owner eos.AccountName `json:"owner"`
+	Old   *VoterInfo      `json:"old,omitempty"`
+	New   *VoterInfo      `json:"new,omitempty"`
+}
+
+// VoteDiff is the difference between two snapshots
+type VoteDiff struct {
+	From   uint32        `json:"from"`
+	To     uint32        `json:"to"`
+	Voters []VoterChange `json:"voters"`
+	Ranks  []RankChange  `json:"ranks"`
+}
+
+func sameVote(a *VoterInfo, b *VoterInfo) bool {
+	if a.Proxy != b.Proxy || a.IsProxy != b.IsProxy || a.LastVoteWeight != b.LastVoteWeight ||
+		a.ProxiedVoteWeight != b.ProxiedVoteWeight || len(a.Producers) != len(b.Producers) {
+		return false
+	}
+	for i := range a.Producers {
+		if a.Producers[i] != b.Producers[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// DiffVoteSnapshots lists voters that changed their votes, proxy or weight, and producers whose votes or rank changed
+func DiffVoteSnapshots(from *VoteSnapshot, to *VoteSnapshot) *VoteDiff {
+	diff := &VoteDiff{From: from.HeadBlock, To: to.HeadBlock, Voters: make([]VoterChange, 0)}
+	oldGraph, newGraph := from.Graph(), to.Graph()
+	for owner, n := range newGraph.Voters {
+		o := oldGraph.Voters[owner]
+		if o == nil || !sameVote(o, n) {
+			diff.Voters = append(diff.Voters, VoterChange{Owner: owner, Old: o, New: n})
+		}
+	}
+	for owner, o := range oldGraph.Voters {
+		if newGraph.Voters[owner] == nil {
+			diff.Voters = append(diff.Voters, VoterChange{Owner: owner, Old: o})
+		}
+	}
+	sort.Slice(diff.Voters, func(i, j int) bool { return diff.Voters[i].Owner < diff.Voters[j].Owner })
+
+	oldRanks := make(map[eos.AccountName]ProducerRank)
+	for _, r := range oldGraph.Ranks() {
+		oldRanks[r.Producer] = r
+	}
+	diff.Ranks = make([]RankChange, 0)
+	for _, r := range newGraph.Ranks() {
+		old := oldRanks[r.Producer]
+		delete(oldRanks, r.Producer)
+		if old.Votes == r.Votes && old.Rank == r.Rank {
+			continue
+		}
+		diff.Ranks = append(diff.Ranks, RankChange{Producer: r.Producer, OldVotes: old.Votes, NewVotes: r.Votes, OldRank: old.Rank, NewRank: r.Rank})
+	}
+	// producers that no longer have any votes
+	removed := make([]RankChange, 0)
+	for p, old := range oldRanks {
+		removed = append(removed, RankChange{Producer: p, OldVotes: old.Votes, OldRank: old.Rank})
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].OldRank < removed[j].OldRank })
+	diff.Ranks = append(diff.Ranks, removed...)
+	return diff
+}
@@ -0,0 +1,150 @@
+package fio
+
+import (
+	"bytes"
+	"github.com/fioprotocol/fio-go/eos"
+	"strconv"
+	"testing"
+)
+
+func testVoters() []*VoterInfo {
+	return []*VoterInfo{
+		{Id: 0, Owner: "alice", Producers: []eos.AccountName{"bp1", "bp2"}, LastVoteWeight: 100},
+		{Id: 1, Owner: "proxy", Producers: []eos.AccountName{"bp2", "bp3"}, LastVoteWeight: 250, ProxiedVoteWeight: 200, IsProxy: true},
+		{Id: 2, Owner: "bob", Producers: []eos.AccountName{}, Proxy: "proxy", LastVoteWeight: 150},
+		{Id: 3, Owner: "carol", Producers: []eos.AccountName{}, Proxy: "proxy", LastVoteWeight: 50},
+		{Id: 4, Owner: "dave", Producers: []eos.AccountName{}, Proxy: "nobody", LastVoteWeight: 75},
+		{Id: 5, Owner: "erin", Producers: []eos.AccountName{"bp4"}, LastVoteWeight: 10},
+	}
+}
+
+const votersTestAbi = `{
+	"version": "eosio::abi/1.1",
+	"structs": [{"name": "voter_info", "base": "", "fields": [
+		{"name": "id", "type": "uint64"},
+		{"name": "fioaddress", "type": "string"},
+		{"name": "addresshash", "type": "uint128"},
+		{"name": "owner", "type": "name"},
+		{"name": "proxy", "type": "name"},
+		{"name": "producers", "type": "name[]"},
+		{"name": "last_vote_weight", "type": "float64"},
+		{"name": "proxied_vote_weight", "type": "float64"},
+		{"name": "is_proxy", "type": "bool"},
+		{"name": "is_auto_proxy", "type": "bool"}
+	]}],
+	"tables": [{"name": "voters", "index_type": "i64", "key_names": [], "key_types": [], "type": "voter_info"}]
+}`
+
+func TestAPI_GetAllVoters(t *testing.T) {
+	voters := testVoters()
+	var requests int
+	node, api := newFakeNode(t)
+	node.abi("eosio", votersTestAbi)
+	node.table("voters", func(req eos.GetTableRowsRequest) (interface{}, bool) {
+		requests++
+		lower, _ := strconv.Atoi(req.LowerBound)
+		end := lower + int(req.Limit)
+		if end > len(voters) {
+			end = len(voters)
+		}
+		return voters[lower:end], end < len(voters)
+	})
+
+	all, err := api.GetAllVoters(4)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(all) != len(voters) || requests != 2 {
+		t.Error("expected every voter in two pages, got", len(all), requests)
+	}
+	if all[1].OwnWeight() != 50 || !all[1].IsProxy || all[5].Owner != "erin" {
+		t.Errorf("voters were not decoded: %+v %+v", all[1], all[5])
+	}
+}
+
+func TestVoteGraph(t *testing.T) {
+	g := NewVoteGraph(testVoters())
+	if len(g.Delegates["proxy"]) != 2 || g.Delegates["proxy"][0] != "bob" {
+		t.Error("wrong delegates", g.Delegates)
+	}
+
+	for _, test := range []struct {
+		owner     eos.AccountName
+		effective float64
+		producers int
+	}{
+		{"alice", 100, 2},
+		{"proxy", 250, 2},
+		{"bob", 150, 2},
+		{"dave", 0, 0}, // proxy is not registered
+	} {
+		w, ok := g.Weight(test.owner)
+		if !ok || w.Effective != test.effective || len(w.Producers) != test.producers {
+			t.Errorf("wrong weight for %s: %+v", test.owner, w)
+		}
+	}
+	if g.Weights()[0].Owner != "proxy" {
+		t.Error("weights should be sorted by effective weight")
+	}
+
+	votes := g.ProducerVotes()
+	if votes["bp1"] != 100 || votes["bp2"] != 350 || votes["bp3"] != 250 || votes["bp4"] != 10 {
+		t.Error("wrong producer votes", votes)
+	}
+	ranks := g.Ranks()
+	if ranks[0].Producer != "bp2" || ranks[3].Producer != "bp4" || ranks[3].Rank != 4 {
+		t.Error("wrong ranks", ranks)
+	}
+
+	// bob leaves the proxy and votes for bp4: proxy's producers lose 150, bp4 moves to 2nd
+	changes, err := g.WhatIf("bob", []eos.AccountName{"bp4"}, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	found := make(map[eos.AccountName]RankChange)
+	for _, c := range changes {
+		found[c.Producer] = c
+	}
+	if c := found["bp4"]; c.OldRank != 4 || c.NewRank != 2 || c.NewVotes != 160 {
+		t.Errorf("wrong change for bp4: %+v", c)
+	}
+	if c := found["bp3"]; c.NewVotes != 100 || c.NewRank != 4 {
+		t.Errorf("wrong change for bp3: %+v", c)
+	}
+	if _, err = g.WhatIf("zed", []eos.AccountName{"bp1"}, 0); err == nil {
+		t.Error("unknown voter without a weight should fail")
+	}
+	if changes, _ = g.WhatIf("zed", []eos.AccountName{"bp5"}, 1); len(changes) != 1 || changes[0].OldRank != 0 {
+		t.Error("a new producer should have no old rank", changes)
+	}
+}
+
+func TestDiffVoteSnapshots(t *testing.T) {
+	from := &VoteSnapshot{HeadBlock: 1, Voters: testVoters()}
+	buf := bytes.NewBuffer(nil)
+	if err := from.Save(buf); err != nil {
+		t.Error(err)
+		return
+	}
+	to, err := LoadVoteSnapshot(buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if d := DiffVoteSnapshots(from, to); len(d.Voters) != 0 || len(d.Ranks) != 0 {
+		t.Error("a saved and loaded snapshot should not differ", d)
+	}
+
+	to.HeadBlock = 2
+	to.Voters[0].Producers = []eos.AccountName{"bp1"}
+	to.Voters = to.Voters[:5] // erin stops voting
+	d := DiffVoteSnapshots(from, to)
+	if len(d.Voters) != 2 || d.Voters[0].Owner != "alice" || d.Voters[1].Owner != "erin" || d.Voters[1].New != nil {
+		t.Errorf("wrong voter changes: %+v", d.Voters)
+	}
+	if len(d.Ranks) != 2 || d.Ranks[len(d.Ranks)-1].Producer != "bp4" || d.Ranks[len(d.Ranks)-1].NewRank != 0 {
+		t.Errorf("wrong rank changes: %+v", d.Ranks)
+	}
+}
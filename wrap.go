@@ -0,0 +1,234 @@
+package fio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/fioprotocol/fio-go/eos"
+	"github.com/fioprotocol/fio-go/eos/system"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ContractUpdate holds the setcode and setabi actions needed to upgrade a contract, along with the hashes of the new
+// code and ABI. After calling API.CompareContract the hashes of the currently deployed contract, and the differences
+// between the deployed and new ABI are also populated.
+type ContractUpdate struct {
+	Account       eos.AccountName
+	Actions       []*Action
+	Abi           *eos.ABI
+	CodeHash      eos.Checksum256
+	AbiHash       eos.Checksum256
+	ChainCodeHash eos.Checksum256
+	ChainAbiHash  eos.Checksum256
+	AbiDiff       *eos.ABIDiff
+}
+
+// NewContractUpdate builds the setcode and setabi actions for a contract from a directory. The directory must contain
+// exactly one .wasm and one .abi file, this is the normal layout of a compiled fio.contracts build directory.
+func NewContractUpdate(account eos.AccountName, contractDir string) (*ContractUpdate, error) {
+	wasm, err := findOne(contractDir, "*.wasm")
+	if err != nil {
+		return nil, err
+	}
+	abi, err := findOne(contractDir, "*.abi")
+	if err != nil {
+		return nil, err
+	}
+	setCode, err := system.NewSetCode(account, wasm)
+	if err != nil {
+		return nil, err
+	}
+	setAbi, err := system.NewSetABI(account, abi)
+	if err != nil {
+		return nil, err
+	}
+	code, ok := setCode.ActionData.Data.(system.SetCode)
+	if !ok {
+		return nil, errors.New("could not read setcode action data")
+	}
+	packedAbi, ok := setAbi.ActionData.Data.(system.SetABI)
+	if !ok {
+		return nil, errors.New("could not read setabi action data")
+	}
+	abiDef := &eos.ABI{}
+	if err = eos.UnmarshalBinary(packedAbi.ABI, abiDef); err != nil {
+		return nil, err
+	}
+	codeHash := sha256.Sum256(code.Code)
+	abiHash := sha256.Sum256(packedAbi.ABI)
+	return &ContractUpdate{
+		Account:  account,
+		Actions:  []*Action{actionFromEos(setCode), actionFromEos(setAbi)},
+		Abi:      abiDef,
+		CodeHash: codeHash[:],
+		AbiHash:  abiHash[:],
+	}, nil
+}
+
+// findOne expects a single file matching the pattern in a directory
+func findOne(dir string, pattern string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return "", err
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no %s file found in %s", pattern, dir)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("found %d %s files in %s, expected one", len(matches), pattern, dir)
+}
+
+// CompareContract fetches the code and ABI hashes for the currently deployed contract, and compares the deployed ABI
+// with the new one.
+func (api *API) CompareContract(update *ContractUpdate) error {
+	if update == nil {
+		return errors.New("nil contract update")
+	}
+	raw, err := api.GetRawABI(eos.GetRawABIRequest{AccountName: string(update.Account)})
+	if err != nil {
+		return err
+	}
+	update.ChainCodeHash = raw.CodeHash
+	update.ChainAbiHash = raw.ABIHash
+	deployed, err := api.GetABI(update.Account)
+	if err != nil {
+		return err
+	}
+	update.AbiDiff = eos.DiffABI(&deployed.ABI, update.Abi)
+	return nil
+}
+
+// CodeChanged is true if the wasm differs from what is on-chain, CompareContract must be called first.
+func (cu ContractUpdate) CodeChanged() bool {
+	return !bytes.Equal(cu.CodeHash, cu.ChainCodeHash)
+}
+
+// AbiChanged is true if the packed ABI differs from what is on-chain, CompareContract must be called first.
+func (cu ContractUpdate) AbiChanged() bool {
+	return !bytes.Equal(cu.AbiHash, cu.ChainAbiHash)
+}
+
+// String provides a summary of the hashes suitable for sharing with other producers before they approve.
+func (cu ContractUpdate) String() string {
+	changed := func(b bool) string {
+		if b {
+			return "changed"
+		}
+		return "unchanged"
+	}
+	s := fmt.Sprintf("contract: %s\ncode: %s -> %s (%s)\nabi:  %s -> %s (%s)\n",
+		cu.Account,
+		cu.ChainCodeHash.String(), cu.CodeHash.String(), changed(cu.CodeChanged()),
+		cu.ChainAbiHash.String(), cu.AbiHash.String(), changed(cu.AbiChanged()),
+	)
+	if cu.AbiDiff != nil && !cu.AbiDiff.Empty() {
+		s += cu.AbiDiff.String() + "\n"
+	}
+	return s
+}
+
+// GetActiveProducers returns the sorted list of accounts in the active producer schedule, which are the accounts
+// that must approve a privileged proposal.
+func (api *API) GetActiveProducers() ([]string, error) {
+	sched, err := api.GetProducerSchedule()
+	if err != nil {
+		return nil, err
+	}
+	if len(sched.Active.Producers) == 0 {
+		return nil, errors.New("active producer schedule is empty")
+	}
+	producers := make([]string, 0)
+	for _, p := range sched.Active.Producers {
+		producers = append(producers, string(p.AccountName))
+	}
+	sort.Strings(producers)
+	return producers, nil
+}
+
+// NewWrappedMsigPropose builds an eosio.msig propose action containing an eosio.wrap execute, which allows the
+// producers to approve privileged actions (setcode, setabi, setpriv, etc.) that require the eosio permission.
+// The proposer is the executer of the wrapped transaction, and is added to the list of approvers if not present.
+func NewWrappedMsigPropose(proposer eos.AccountName, proposal Name, approvers []string, actions []*Action, expires time.Duration, txOpts *TxOptions) (*Action, error) {
+	if len(actions) == 0 {
+		return nil, errors.New("no actions provided")
+	}
+	if len(approvers) == 0 {
+		return nil, errors.New("no approvers provided")
+	}
+	if txOpts == nil {
+		txOpts = &TxOptions{}
+	}
+	requested := make([]string, 0)
+	var hasProposer bool
+	for _, a := range approvers {
+		if len(a) > 12 {
+			return nil, errors.New("invalid approver in list, account name should be < 12 chars")
+		}
+		if a == string(proposer) {
+			hasProposer = true
+		}
+		requested = append(requested, a)
+	}
+	if !hasProposer {
+		requested = append(requested, string(proposer))
+	}
+
+	wrap := NewWrapExecute(proposer, proposer, NewTransaction(actions, txOpts))
+	wrap.Authorization = append(wrap.Authorization, eos.PermissionLevel{Actor: "eosio.wrap", Permission: "active"})
+	propTx := NewTransaction([]*Action{wrap}, txOpts)
+	propTx.Expiration = eos.JSONTime{Time: time.Now().UTC().Add(expires)}
+	packed, err := eos.MarshalBinary(propTx)
+	if err != nil {
+		return nil, err
+	}
+	feeBytes := uint64((len(packed) / 1000) + 1)
+
+	return NewAction("eosio.msig", "propose", proposer,
+		MsigWrappedPropose{
+			Proposer:     proposer,
+			ProposalName: proposal.ToEos(),
+			Requested:    NewPermissionLevelSlice(requested),
+			MaxFee:       Tokens(GetMaxFee(FeeMsigPropose)) * feeBytes,
+			Trx:          propTx,
+		},
+	), nil
+}
+
+// NewGovernanceProposal is a convenience wrapper for NewWrappedMsigPropose that requests approval from every producer
+// in the current active schedule.
+func (api *API) NewGovernanceProposal(proposer eos.AccountName, proposal Name, actions []*Action, expires time.Duration, txOpts *TxOptions) (*Action, error) {
+	producers, err := api.GetActiveProducers()
+	if err != nil {
+		return nil, err
+	}
+	return NewWrappedMsigPropose(proposer, proposal, producers, actions, expires, txOpts)
+}
+
+// ContractDirs is a helper for finding contract build directories, returning a map of contract names to directories.
+// Each sub-directory containing a .wasm file is considered a contract, and the directory name is assumed to be the
+// contract account name, for example "fio.address".
+func ContractDirs(buildDir string) (map[eos.AccountName]string, error) {
+	files, err := ioutil.ReadDir(buildDir)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make(map[eos.AccountName]string)
+	for _, f := range files {
+		if !f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		p := filepath.Join(buildDir, f.Name())
+		if _, err := findOne(p, "*.wasm"); err != nil {
+			continue
+		}
+		dirs[eos.AccountName(f.Name())] = p
+	}
+	return dirs, nil
+}
@@ -0,0 +1,85 @@
+package fio
+
+import (
+	"crypto/sha256"
+	"github.com/fioprotocol/fio-go/eos"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewContractUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fio-wrap")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	wasm := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	if err = ioutil.WriteFile(filepath.Join(dir, "fio.test.wasm"), wasm, 0644); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "fio.test.abi"), []byte(ObtAbiJson), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+
+	update, err := NewContractUpdate("fio.test", dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(update.Actions) != 2 || update.Actions[0].Name != "setcode" || update.Actions[1].Name != "setabi" {
+		t.Error("expected setcode and setabi actions")
+	}
+	expect := sha256.Sum256(wasm)
+	if update.CodeHash.String() != eos.Checksum256(expect[:]).String() {
+		t.Error("code hash was incorrect")
+	}
+	if !update.CodeChanged() || !update.AbiChanged() {
+		t.Error("contract should be changed before comparing to chain")
+	}
+
+	dirs, err := ContractDirs(filepath.Dir(dir))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if dirs[eos.AccountName(filepath.Base(dir))] != dir {
+		t.Error("did not find contract directory")
+	}
+
+	_, err = NewContractUpdate("fio.test", os.TempDir()+"/does-not-exist")
+	if err == nil {
+		t.Error("should not build update for missing directory")
+	}
+}
+
+func TestNewWrappedMsigPropose(t *testing.T) {
+	inner := NewAction("eosio", "setpriv", "eosio", struct {
+		Account eos.AccountName `json:"account"`
+		IsPriv  uint8           `json:"is_priv"`
+	}{"fio.test", 1})
+	propose, err := NewWrappedMsigPropose("proposer1111", "upgrade", []string{"bp2", "bp1"}, []*Action{inner}, time.Hour, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	p, ok := propose.ActionData.Data.(MsigWrappedPropose)
+	if !ok {
+		t.Error("did not get MsigWrappedPropose")
+		return
+	}
+	if len(p.Requested) != 3 || p.Requested[0].Actor != "bp1" || p.Requested[2].Actor != "proposer1111" {
+		t.Error("requested approvals were incorrect")
+	}
+	if len(p.Trx.Actions) != 1 || p.Trx.Actions[0].Account != "eosio.wrap" || len(p.Trx.Actions[0].Authorization) != 2 {
+		t.Error("proposed transaction should contain an eosio.wrap action with two authorizations")
+	}
+	if _, err = NewWrappedMsigPropose("proposer1111", "upgrade", nil, []*Action{inner}, time.Hour, nil); err == nil {
+		t.Error("should require approvers")
+	}
+}